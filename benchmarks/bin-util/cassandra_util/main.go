@@ -29,6 +29,7 @@ var createStatements = []string{
         linked_len int,
         is_deleted boolean,
         version text,
+        t_expire timestamp,
         PRIMARY KEY (key)
     ) WITH gc_grace_seconds = 172800`,
 
@@ -18,6 +18,7 @@ import (
 	"github.com/cristalhq/aconfig/aconfigyaml"
 	cfg "github.com/kkkzoz/oreo/pkg/config"
 	"github.com/kkkzoz/oreo/pkg/discovery"
+	"github.com/kkkzoz/oreo/pkg/history"
 	"github.com/kkkzoz/oreo/pkg/network"
 )
 
@@ -162,6 +163,9 @@ func main() {
 		fmt.Println("Load finished")
 	case "run":
 		wp.DoBenchmark = true
+		if wp.HistoryPath != "" {
+			benconfig.GlobalHistoryRecorder = history.NewRecorder()
+		}
 		displayBenchmarkInfo()
 		fmt.Println("Start to run benchmark")
 		measurement.EnableWarmUp(false)
@@ -493,6 +497,12 @@ func loadConfig() *workload.WorkloadParameter {
 	benconfig.TimeOracleUrl = benConfig.TimeOracleUrl
 	benconfig.ZipfianConstant = benConfig.ZipfianConstant
 	benconfig.MaxLoadBatchSize = benConfig.MaxLoadBatchSize
+	se, err := benConfig.Serializer()
+	if err != nil {
+		log.Fatalf("Error when loading benchmark configuration: %v\n", err)
+		return nil
+	}
+	cfg.Config.Serializer = se
 
 	wp := &workload.WorkloadParameter{}
 	wpLoader := aconfig.LoaderFor(wp, aconfig.Config{
@@ -34,7 +34,7 @@ var _ ycsb.TransactionDB = (*CouchDatastore)(nil)
 
 type CouchDatastore struct {
 	conn *couchdb.CouchDBConnection
-	txn  *txn.Transaction
+	txn  transaction
 }
 
 func NewCouchDatastore(conn *couchdb.CouchDBConnection) *CouchDatastore {
@@ -48,7 +48,7 @@ func (r *CouchDatastore) Start() error {
 	rds := couchdb.NewCouchDBDatastore("couch", r.conn)
 	txn.AddDatastore(rds)
 	txn.SetGlobalDatastore(rds)
-	r.txn = txn
+	r.txn = record(txn)
 	return r.txn.Start()
 }
 
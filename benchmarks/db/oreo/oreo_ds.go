@@ -30,7 +30,7 @@ var _ ycsb.TransactionDB = (*OreoDatastore)(nil)
 type OreoDatastore struct {
 	connMap             map[string]txn.Connector
 	globalDatastoreName string
-	txn                 *txn.Transaction
+	txn                 transaction
 	isRemote            bool
 }
 
@@ -80,7 +80,7 @@ func (r *OreoDatastore) Start() error {
 		}
 	}
 
-	r.txn = txn1
+	r.txn = record(txn1)
 	return r.txn.Start()
 }
 
@@ -37,7 +37,7 @@ var _ ycsb.TransactionDB = (*MongoDatastore)(nil)
 
 type MongoDatastore struct {
 	conn     *mongo.MongoConnection
-	txn      *txn.Transaction
+	txn      transaction
 	isRemote bool
 }
 
@@ -59,7 +59,7 @@ func (r *MongoDatastore) Start() error {
 	rds := mongo.NewMongoDatastore("mongo", r.conn)
 	txn1.AddDatastore(rds)
 	txn1.SetGlobalDatastore(rds)
-	r.txn = txn1
+	r.txn = record(txn1)
 	return r.txn.Start()
 }
 
@@ -36,7 +36,7 @@ var _ ycsb.TransactionDB = (*OreoRealisticDatastore)(nil)
 type OreoRealisticDatastore struct {
 	connMap             map[string]txn.Connector
 	globalDatastoreName string
-	txn                 *txn.Transaction
+	txn                 transaction
 	isRemote            bool
 	mode                string
 }
@@ -121,7 +121,7 @@ func (r *OreoRealisticDatastore) Start() error {
 		}
 	}
 
-	r.txn = txn1
+	r.txn = record(txn1)
 	return r.txn.Start()
 }
 
@@ -37,7 +37,7 @@ var _ ycsb.TransactionDB = (*RedisDatastore)(nil)
 
 type RedisDatastore struct {
 	conn     *redis.RedisConnection
-	txn      *txn.Transaction
+	txn      transaction
 	isRemote bool
 }
 
@@ -59,7 +59,7 @@ func (r *RedisDatastore) Start() error {
 	rds := redis.NewRedisDatastore("redis1", r.conn)
 	txn1.AddDatastore(rds)
 	txn1.SetGlobalDatastore(rds)
-	r.txn = txn1
+	r.txn = record(txn1)
 	return r.txn.Start()
 }
 
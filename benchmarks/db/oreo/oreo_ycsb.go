@@ -34,7 +34,7 @@ var _ ycsb.TransactionDB = (*OreoYCSBDatastore)(nil)
 type OreoYCSBDatastore struct {
 	connMap             map[string]txn.Connector
 	globalDatastoreName string
-	txn                 *txn.Transaction
+	txn                 transaction
 	mode                string
 }
 
@@ -124,7 +124,7 @@ func (r *OreoYCSBDatastore) Start() error {
 		}
 	}
 
-	r.txn = txn1
+	r.txn = record(txn1)
 	return r.txn.Start()
 }
 
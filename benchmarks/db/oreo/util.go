@@ -1,5 +1,28 @@
 package oreo
 
+import (
+	"benchmark/pkg/benconfig"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+// transaction is the part of txn.Transaction used by the DBs.
+type transaction interface {
+	Start() error
+	Read(dsName string, key string, value any) error
+	Write(dsName string, key string, value any) error
+	Delete(dsName string, key string) error
+	Commit() error
+	Abort() error
+}
+
+// record wraps t to be recorded by benconfig.GlobalHistoryRecorder, if set.
+func record(t *txn.Transaction) transaction {
+	if benconfig.GlobalHistoryRecorder == nil {
+		return t
+	}
+	return benconfig.GlobalHistoryRecorder.Wrap(t)
+}
+
 func getKeyName(table string, key string) string {
 	return table + "/" + key
 }
@@ -4,7 +4,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kkkzoz/oreo/pkg/history"
 	"github.com/kkkzoz/oreo/pkg/network"
+	"github.com/kkkzoz/oreo/pkg/serializer"
 )
 
 var (
@@ -20,6 +22,9 @@ var (
 
 var GlobalClient *network.Client
 
+// GlobalHistoryRecorder records the Oreo transactions of the run if set.
+var GlobalHistoryRecorder *history.Recorder
+
 type BenchmarkConfig struct {
 	RegistryAddr       string              `yaml:"registry_addr"`
 	RegistryAddrs      []string            `yaml:"registry_addrs"`
@@ -36,6 +41,11 @@ type BenchmarkConfig struct {
 
 	RedisAddr     string `yaml:"redis_addr"`
 	RedisPassword string `yaml:"redis_password"`
+	// RedisAddrs are the seed nodes of a Redis Cluster,
+	// or the sentinels if RedisMasterName is set.
+	RedisAddrs      []string `yaml:"redis_addrs"`
+	RedisMasterName string   `yaml:"redis_master_name"`
+	RedisCluster    bool     `yaml:"redis_cluster"`
 
 	MongoDBAddr1    string `yaml:"mongodb_addr1"`
 	MongoDBAddr2    string `yaml:"mongodb_addr2"`
@@ -54,6 +64,14 @@ type BenchmarkConfig struct {
 	CassandraPassword string   `yaml:"cassandra_password"`
 	DynamoDBAddr      string   `yaml:"dynamodb_addr"`
 	TiKVAddr          []string `yaml:"tikv_addr"`
+	BoltPath          string   `yaml:"bolt_path"`
+	PostgresAddr      string   `yaml:"postgres_addr"`
+	EtcdAddr          []string `yaml:"etcd_addr"`
+
+	// Compression is the codec compressing the values, "zstd" or "snappy".
+	// Empty leaves the values uncompressed.
+	Compression          string `yaml:"compression"`
+	CompressionThreshold int    `yaml:"compression_threshold"`
 
 	// DBCombination []string `yaml:"db_combination"`
 }
@@ -88,3 +106,16 @@ func (cfg *BenchmarkConfig) ResolveHTTPRegistryAddrs() []string {
 
 	return out
 }
+
+// Serializer returns the serializer of the records, which compresses the
+// values with the codec named by Compression. The compressed values are
+// decoded whatever the codec, so an executor reads the records of any client.
+func (cfg *BenchmarkConfig) Serializer() (serializer.Serializer, error) {
+	codec, err := serializer.ParseCodec(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+	return serializer.NewCompressedSerializer(
+		serializer.NewJSON2Serializer(), codec, cfg.CompressionThreshold,
+	), nil
+}
@@ -14,6 +14,7 @@ import (
 	"benchmark/pkg/workload"
 	"benchmark/ycsb"
 	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/history"
 )
 
 type Client struct {
@@ -125,6 +126,7 @@ func (c *Client) RunBenchmark() {
 	// 	c.wl.DisplayCheckResult()
 	// }
 	c.getCacheState()
+	c.checkHistory()
 
 	if !c.wl.NeedPostCheck() {
 		return
@@ -198,6 +200,33 @@ func (c *Client) RunBenchmark() {
 	// }
 }
 
+// checkHistory writes the history recorded during the run and checks it
+// for the anomalies prohibited by the isolation level.
+func (c *Client) checkHistory() {
+	recorder := benconfig.GlobalHistoryRecorder
+	if recorder == nil {
+		return
+	}
+	// the transactions of the post-check are not recorded
+	benconfig.GlobalHistoryRecorder = nil
+
+	fmt.Println("----------------------------------")
+	h := recorder.History()
+	if err := history.WriteFile(c.wp.HistoryPath, h); err != nil {
+		fmt.Printf("Failed to write the history to %s: %v\n", c.wp.HistoryPath, err)
+	}
+	report := history.Check(h, config.Config.IsolationLevel)
+	fmt.Printf("History: %d transactions, %d checked\n", len(h), report.Txns)
+	for _, a := range report.Anomalies {
+		fmt.Printf("[%s] %s (prohibited: %v)\n", a.Type, a.Description, a.Type.ProhibitedBy(report.Level))
+	}
+	if report.Valid() {
+		fmt.Printf("History check passed under %v\n", report.Level)
+	} else {
+		fmt.Printf("History check failed under %v: %d anomalies\n", report.Level, len(report.Violations()))
+	}
+}
+
 func (c *Client) getCacheState() {
 	fmt.Println("----------------------------------")
 
@@ -28,6 +28,10 @@ type WorkloadParameter struct {
 	TransferAmountPerTxn  int `yaml:"transferamountpertxn"`
 	TotalAmount           int `yaml:"totalamount"`
 	PostCheckWorkerThread int `yaml:"postcheckworkerthread"`
+	// HistoryPath is the file the history of the Oreo transactions is written
+	// to after the run, to be checked for the isolation anomalies.
+	// Empty disables the recording.
+	HistoryPath string `yaml:"historypath"`
 
 	// These parameters are for the data distribution test
 	InvolvedDBNum       int     `yaml:"involveddbnum"`
@@ -7,10 +7,12 @@ import (
 	"math/rand"
 	"time"
 
+	oreoconfig "github.com/kkkzoz/oreo/pkg/config"
 	"github.com/kkkzoz/oreo/pkg/datastore/mongo"
 	"github.com/kkkzoz/oreo/pkg/datastore/redis"
 	"github.com/kkkzoz/oreo/pkg/discovery"
 	"github.com/kkkzoz/oreo/pkg/network"
+	"github.com/kkkzoz/oreo/pkg/serializer"
 	"github.com/kkkzoz/oreo/pkg/timesource"
 	"github.com/kkkzoz/oreo/pkg/txn"
 	redisd "github.com/redis/go-redis/v9"
@@ -318,8 +320,17 @@ func main() {
 	mode := flag.String("mode", "oreo", "Mode: oreo or native")
 	numRecords := flag.Int("records", 1000, "Number of records to insert")
 	batchSize := flag.Int("batch", 10, "Number of records per transaction (oreo mode only)")
+	compression := flag.String("compression", "", "Codec compressing the values: zstd or snappy (oreo mode only)")
 	flag.Parse()
 
+	codec, err := serializer.ParseCodec(*compression)
+	if err != nil {
+		panic(err)
+	}
+	oreoconfig.Config.Serializer = serializer.NewCompressedSerializer(
+		serializer.NewJSON2Serializer(), codec, serializer.DefaultCompressionThreshold,
+	)
+
 	rand.Seed(time.Now().UnixNano())
 
 	if *mode == "oreo" {
@@ -19,15 +19,17 @@ import (
 	"github.com/cristalhq/aconfig/aconfigyaml"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
 	"github.com/kkkzoz/oreo/pkg/datastore/cassandra"
 	"github.com/kkkzoz/oreo/pkg/datastore/couchdb"
 	"github.com/kkkzoz/oreo/pkg/datastore/dynamodb"
+	"github.com/kkkzoz/oreo/pkg/datastore/etcd"
 	"github.com/kkkzoz/oreo/pkg/datastore/mongo"
+	"github.com/kkkzoz/oreo/pkg/datastore/postgres"
 	"github.com/kkkzoz/oreo/pkg/datastore/redis"
 	"github.com/kkkzoz/oreo/pkg/datastore/tikv"
 	"github.com/kkkzoz/oreo/pkg/logger"
 	"github.com/kkkzoz/oreo/pkg/network"
-	"github.com/kkkzoz/oreo/pkg/serializer"
 	"github.com/kkkzoz/oreo/pkg/timesource"
 	"github.com/kkkzoz/oreo/pkg/txn"
 	"github.com/valyala/fasthttp"
@@ -44,9 +46,10 @@ var Banner = `
 `
 
 type Server struct {
-	port      int
-	reader    network.Reader
-	committer network.Committer
+	port       int
+	reader     network.Reader
+	committer  network.Committer
+	namespaces *network.Namespaces
 }
 
 func NewServer(
@@ -55,12 +58,15 @@ func NewServer(
 	factory txn.DataItemFactory,
 	timeSource timesource.TimeSourcer,
 ) *Server {
-	reader := *network.NewReader(connMap, factory, serializer.NewJSON2Serializer(), network.NewCacher())
-	return &Server{
+	reader := *network.NewReader(connMap, factory, config.Config.Serializer, network.NewCacher())
+	s := &Server{
 		port:      port,
 		reader:    reader,
-		committer: *network.NewCommitter(connMap, reader, serializer.NewJSON2Serializer(), factory, timeSource),
+		committer: *network.NewCommitter(connMap, reader, config.Config.Serializer, factory, timeSource),
 	}
+	s.namespaces = network.NewNamespaces(connMap, &s.reader, &s.committer,
+		config.Config.Serializer, factory, timeSource)
+	return s
 }
 
 func (s *Server) Run() {
@@ -74,6 +80,10 @@ func (s *Server) Run() {
 			s.prepareHandler(ctx)
 		case "/commit":
 			s.commitHandler(ctx)
+		case "/renew":
+			s.renewHandler(ctx)
+		case "/status":
+			s.statusHandler(ctx)
 		case "/abort":
 			s.abortHandler(ctx)
 		case "/cache":
@@ -140,7 +150,13 @@ func (s *Server) readHandler(ctx *fasthttp.RequestCtx) {
 		req.Config,
 	)
 
-	item, dataType, gk, err := s.reader.Read(req.DsName, req.Key, req.StartTime, req.Config, true)
+	var item txn.DataItem
+	var dataType txn.RemoteDataStrategy
+	var gk string
+	reader, _, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		item, dataType, gk, err = reader.Read(req.DsName, req.Key, req.StartTime, req.Config, true)
+	}
 
 	var response network.ReadResponse
 	if err != nil {
@@ -210,19 +226,26 @@ func (s *Server) prepareHandler(ctx *fasthttp.RequestCtx) {
 		req.ValidationMap,
 	)
 
-	verMap, tCommit, err := s.committer.Prepare(req.DsName, req.ItemList,
-		req.StartTime, req.Config, req.ValidationMap)
+	var verMap map[string]string
+	var tCommit int64
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		verMap, tCommit, err = committer.Prepare(req.DsName, req.ItemList,
+			req.StartTime, req.Config, req.ValidationMap, req.ReadSet, req.Conditions, req.Merges)
+	}
 	var resp network.PrepareResponse
 	if err != nil {
 		resp = network.PrepareResponse{
-			Status: "Error",
-			ErrMsg: err.Error(),
+			Status:   "Error",
+			ErrMsg:   err.Error(),
+			LeaseNow: config.Config.LeaseClock.Now().UnixMicro(),
 		}
 	} else {
 		resp = network.PrepareResponse{
-			Status:  "OK",
-			VerMap:  verMap,
-			TCommit: tCommit,
+			Status:   "OK",
+			VerMap:   verMap,
+			TCommit:  tCommit,
+			LeaseNow: config.Config.LeaseClock.Now().UnixMicro(),
 		}
 	}
 	respBytes, _ := json2.Marshal(resp)
@@ -242,7 +265,10 @@ func (s *Server) commitHandler(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	err := s.committer.Commit(req.DsName, req.List, req.TCommit)
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		err = committer.Commit(req.DsName, req.List, req.TCommit)
+	}
 	var resp network.Response[string]
 	if err != nil {
 		resp = network.Response[string]{
@@ -259,6 +285,74 @@ func (s *Server) commitHandler(ctx *fasthttp.RequestCtx) {
 	logger.CheckAndLogError("Failed to write response", err)
 }
 
+func (s *Server) renewHandler(ctx *fasthttp.RequestCtx) {
+	startTime := time.Now()
+	defer func() {
+		logger.Debugw("Renew request", "latency", time.Since(startTime))
+	}()
+
+	var req network.RenewRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		ctx.Error("Invalid renew request body.", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var verMap map[string]string
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		verMap, err = committer.Renew(req.DsName, req.List, req.TLease)
+	}
+	var resp network.Response[map[string]string]
+	if err != nil {
+		resp = network.Response[map[string]string]{
+			Status: "Error",
+			ErrMsg: err.Error(),
+		}
+	} else {
+		resp = network.Response[map[string]string]{
+			Status: "OK",
+			Data:   verMap,
+		}
+	}
+	respBytes, _ := json.Marshal(resp)
+	_, err = ctx.Write(respBytes)
+	logger.CheckAndLogError("Failed to write response", err)
+}
+
+func (s *Server) statusHandler(ctx *fasthttp.RequestCtx) {
+	startTime := time.Now()
+	defer func() {
+		logger.Debugw("Status request", "latency", time.Since(startTime))
+	}()
+
+	var req network.StatusRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		ctx.Error("Invalid status request body.", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var outcome txn.TxnOutcome
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		outcome, err = committer.QueryOutcome(req.TxnId, req.DsNames, req.Keys)
+	}
+	var resp network.Response[txn.TxnOutcome]
+	if err != nil {
+		resp = network.Response[txn.TxnOutcome]{
+			Status: "Error",
+			ErrMsg: err.Error(),
+		}
+	} else {
+		resp = network.Response[txn.TxnOutcome]{
+			Status: "OK",
+			Data:   outcome,
+		}
+	}
+	respBytes, _ := json.Marshal(resp)
+	_, err = ctx.Write(respBytes)
+	logger.CheckAndLogError("Failed to write response", err)
+}
+
 func (s *Server) abortHandler(ctx *fasthttp.RequestCtx) {
 	startTime := time.Now()
 	defer func() {
@@ -271,7 +365,10 @@ func (s *Server) abortHandler(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	err := s.committer.Abort(req.DsName, req.KeyList, req.GroupKeyList)
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		err = committer.Abort(req.DsName, req.KeyList, req.GroupKeyList)
+	}
 	var resp network.Response[string]
 	if err != nil {
 		resp = network.Response[string]{
@@ -305,6 +402,9 @@ var (
 	db_combination = ""
 	benConfigPath  = ""
 	cg             = false
+	oracleFree     = false
+	uncertainty    = 10 * time.Millisecond
+	oracleClock    = false
 )
 
 var benConfig = benconfig.BenchmarkConfig{}
@@ -367,7 +467,19 @@ func main() {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
-	oracle := timesource.NewGlobalTimeSource(benConfig.TimeOracleUrl)
+	var oracle timesource.TimeSourcer = timesource.NewGlobalTimeSource(benConfig.TimeOracleUrl)
+	if oracleFree {
+		fmt.Printf("Running without time oracle, clock uncertainty: %v\n", uncertainty)
+		config.Config.MaxClockUncertainty = uncertainty
+		oracle = timesource.NewSimpleTimeSource()
+	} else if oracleClock {
+		clock, err := timesource.NewOracleClock(benConfig.TimeOracleUrl, time.Second)
+		if err != nil {
+			log.Fatalf("Failed to read the oracle clock: %v", err)
+		}
+		fmt.Printf("Using the oracle clock for leases, offset: %v\n", clock.Offset())
+		config.Config.LeaseClock = clock
+	}
 	server := NewServer(port, connMap, &redis.RedisItemFactory{}, oracle)
 	go server.Run()
 
@@ -393,9 +505,14 @@ func loadConfig() error {
 		log.Fatalf("Error when loading benchmark configuration: %v\n", err)
 	}
 
-	if benConfig.TimeOracleUrl == "" {
+	if benConfig.TimeOracleUrl == "" && !oracleFree {
 		logger.Fatal("Time Oracle URL must be specified")
 	}
+	se, err := benConfig.Serializer()
+	if err != nil {
+		return err
+	}
+	config.Config.Serializer = se
 	return nil
 }
 
@@ -408,6 +525,9 @@ func parseFlag() {
 	flag.StringVar(&db_combination, "db", "", "Database Combination")
 	flag.BoolVar(&cg, "cg", false, "Enable Cherry Garcia Mode")
 	flag.StringVar(&benConfigPath, "bc", "", "Benchmark Configuration Path")
+	flag.BoolVar(&oracleFree, "oracle-free", false, "Use local clocks instead of the time oracle")
+	flag.DurationVar(&uncertainty, "uncertainty", 10*time.Millisecond, "Maximum clock uncertainty in oracle-free mode")
+	flag.BoolVar(&oracleClock, "oracle-clock", false, "Use the clock of the time oracle for leases")
 	flag.Parse()
 
 	if benConfigPath == "" {
@@ -484,6 +604,15 @@ func getConnMap() map[string]txn.Connector {
 			case "TiKV":
 				tikvConn := getTiKVConn()
 				connMap["TiKV"] = tikvConn
+			case "Bolt":
+				boltConn := getBoltConn()
+				connMap["Bolt"] = boltConn
+			case "Postgres":
+				postgresConn := getPostgresConn()
+				connMap["Postgres"] = postgresConn
+			case "Etcd":
+				etcdConn := getEtcdConn()
+				connMap["Etcd"] = etcdConn
 			default:
 				logger.Fatal("Invalid database combination")
 			}
@@ -556,9 +685,12 @@ func getRedisConn(id int) *redis.RedisConnection {
 	}
 
 	redisConn := redis.NewRedisConnection(&redis.ConnectionOptions{
-		Address:  address,
-		Password: benConfig.RedisPassword,
-		PoolSize: poolSize,
+		Address:    address,
+		Addresses:  benConfig.RedisAddrs,
+		MasterName: benConfig.RedisMasterName,
+		Cluster:    benConfig.RedisCluster,
+		Password:   benConfig.RedisPassword,
+		PoolSize:   poolSize,
 	})
 	err := redisConn.Connect()
 	if err != nil {
@@ -601,3 +733,39 @@ func getTiKVConn() *tikv.TiKVConnection {
 	}
 	return tikvConn
 }
+
+func getBoltConn() *bolt.BoltConnection {
+	boltConn := bolt.NewBoltConnection(&bolt.ConnectionOptions{
+		Path:    benConfig.BoltPath,
+		Timeout: time.Second,
+	})
+	err := boltConn.Connect()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return boltConn
+}
+
+func getPostgresConn() *postgres.PostgresConnection {
+	postgresConn := postgres.NewPostgresConnection(&postgres.ConnectionOptions{
+		Address:   benConfig.PostgresAddr,
+		TableName: "records",
+		PoolSize:  int32(poolSize),
+	})
+	err := postgresConn.Connect()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return postgresConn
+}
+
+func getEtcdConn() *etcd.EtcdConnection {
+	etcdConn := etcd.NewEtcdConnection(&etcd.ConnectionOptions{
+		Endpoints: benConfig.EtcdAddr,
+	})
+	err := etcdConn.Connect()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return etcdConn
+}
@@ -23,7 +23,6 @@ import (
 	"github.com/kkkzoz/oreo/pkg/discovery"
 	"github.com/kkkzoz/oreo/pkg/logger"
 	"github.com/kkkzoz/oreo/pkg/network"
-	"github.com/kkkzoz/oreo/pkg/serializer"
 	"github.com/kkkzoz/oreo/pkg/timesource"
 	"github.com/kkkzoz/oreo/pkg/txn"
 	"github.com/valyala/fasthttp"
@@ -47,6 +46,7 @@ type Server struct {
 	handledDsNames []string
 	reader         network.Reader
 	committer      network.Committer
+	namespaces     *network.Namespaces
 	fasthttpServer *fasthttp.Server // Keep track for shutdown
 
 	// Service registry interface
@@ -64,7 +64,7 @@ func NewServer(
 	timeSource timesource.TimeSourcer,
 	registryType string,
 ) *Server {
-	reader := *network.NewReader(connMap, factory, serializer.NewJSON2Serializer(), network.NewCacher())
+	reader := *network.NewReader(connMap, factory, config.Config.Serializer, network.NewCacher())
 
 	// Extract database connection addresses from connMap
 	dbConnections := make(map[string]string)
@@ -106,15 +106,18 @@ func NewServer(
 		panic("unknown registry type")
 	}
 
-	return &Server{
+	s := &Server{
 		port:           port,
 		advertiseAddr:  advertiseAddr,
 		registryAddrs:  registryAddrs,
 		handledDsNames: handledDsNames,
 		reader:         reader,
-		committer:      *network.NewCommitter(connMap, reader, serializer.NewJSON2Serializer(), factory, timeSource),
+		committer:      *network.NewCommitter(connMap, reader, config.Config.Serializer, factory, timeSource),
 		registry:       registry,
 	}
+	s.namespaces = network.NewNamespaces(connMap, &s.reader, &s.committer,
+		config.Config.Serializer, factory, timeSource)
+	return s
 }
 
 // --- Registry Interaction ---
@@ -168,6 +171,10 @@ func (s *Server) RunAndBlock() {
 			s.prepareHandler(ctx)
 		case "/commit":
 			s.commitHandler(ctx)
+		case "/renew":
+			s.renewHandler(ctx)
+		case "/status":
+			s.statusHandler(ctx)
 		case "/abort":
 			s.abortHandler(ctx)
 		case "/cache":
@@ -296,7 +303,13 @@ func (s *Server) readHandler(ctx *fasthttp.RequestCtx) {
 		req.Config,
 	)
 
-	item, dataType, gk, err := s.reader.Read(req.DsName, req.Key, req.StartTime, req.Config, true)
+	var item txn.DataItem
+	var dataType txn.RemoteDataStrategy
+	var gk string
+	reader, _, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		item, dataType, gk, err = reader.Read(req.DsName, req.Key, req.StartTime, req.Config, true)
+	}
 
 	var response network.ReadResponse
 	if err != nil {
@@ -367,8 +380,13 @@ func (s *Server) prepareHandler(ctx *fasthttp.RequestCtx) {
 		getMapKeys(req.ValidationMap),
 	)
 
-	verMap, tCommit, err := s.committer.Prepare(req.DsName, req.ItemList,
-		req.StartTime, req.Config, req.ValidationMap)
+	var verMap map[string]string
+	var tCommit int64
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		verMap, tCommit, err = committer.Prepare(req.DsName, req.ItemList,
+			req.StartTime, req.Config, req.ValidationMap, req.ReadSet, req.Conditions, req.Merges)
+	}
 	var resp network.PrepareResponse
 	if err != nil {
 		logger.Warnw(
@@ -381,17 +399,19 @@ func (s *Server) prepareHandler(ctx *fasthttp.RequestCtx) {
 			err,
 		)
 		resp = network.PrepareResponse{
-			Status: "Error",
-			ErrMsg: err.Error(),
+			Status:   "Error",
+			ErrMsg:   err.Error(),
+			LeaseNow: config.Config.LeaseClock.Now().UnixMicro(),
 		}
 		ctx.SetStatusCode(
 			fasthttp.StatusInternalServerError,
 		) // Or map specific errors (e.g., Conflict)
 	} else {
 		resp = network.PrepareResponse{
-			Status:  "OK",
-			VerMap:  verMap,
-			TCommit: tCommit,
+			Status:   "OK",
+			VerMap:   verMap,
+			TCommit:  tCommit,
+			LeaseNow: config.Config.LeaseClock.Now().UnixMicro(),
 		}
 		ctx.SetStatusCode(fasthttp.StatusOK)
 	}
@@ -439,7 +459,10 @@ func (s *Server) commitHandler(ctx *fasthttp.RequestCtx) {
 		req.TCommit,
 	)
 
-	err := s.committer.Commit(req.DsName, req.List, req.TCommit)
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		err = committer.Commit(req.DsName, req.List, req.TCommit)
+	}
 	var resp network.Response[string] // Generic response type
 	if err != nil {
 		// logger.Warnw("Commit operation failed", "dsName", req.DsName, "tCommit", req.TCommit, "error", err)
@@ -469,6 +492,130 @@ func (s *Server) commitHandler(ctx *fasthttp.RequestCtx) {
 	}
 }
 
+func (s *Server) renewHandler(ctx *fasthttp.RequestCtx) {
+	startTime := time.Now()
+	defer func() {
+		logger.Debugw(
+			"Renew request processing finished",
+			"latency_ms",
+			time.Since(startTime).Milliseconds(),
+		)
+	}()
+
+	var req network.RenewRequest
+	// Use jsoniter for application data
+	if err := json2.Unmarshal(ctx.PostBody(), &req); err != nil {
+		errMsg := fmt.Sprintf("Invalid renew request body: %s", err.Error())
+		logger.Errorw(errMsg, "body", string(ctx.PostBody()))
+		ctx.Error(errMsg, fasthttp.StatusBadRequest)
+		return
+	}
+
+	logger.Infow(
+		"Renew request received",
+		"dsName",
+		req.DsName,
+		"renewInfoCount",
+		len(req.List),
+		"tLease",
+		req.TLease,
+	)
+
+	var verMap map[string]string
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		verMap, err = committer.Renew(req.DsName, req.List, req.TLease)
+	}
+	var resp network.Response[map[string]string]
+	if err != nil {
+		resp = network.Response[map[string]string]{
+			Status: "Error",
+			ErrMsg: err.Error(),
+		}
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+	} else {
+		resp = network.Response[map[string]string]{
+			Status: "OK",
+			Data:   verMap,
+		}
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	}
+
+	// Use jsoniter for response
+	respBytes, marshalErr := json2.Marshal(resp)
+	if marshalErr != nil {
+		logger.Errorw("Failed to marshal renew response", "error", marshalErr)
+		ctx.Error("Internal Server Error", fasthttp.StatusInternalServerError)
+		return
+	}
+	ctx.SetContentType("application/json")
+	_, err = ctx.Write(respBytes)
+	if err != nil {
+		logger.Errorw("Failed to write response", "error", err)
+	}
+}
+
+func (s *Server) statusHandler(ctx *fasthttp.RequestCtx) {
+	startTime := time.Now()
+	defer func() {
+		logger.Debugw(
+			"Status request processing finished",
+			"latency_ms",
+			time.Since(startTime).Milliseconds(),
+		)
+	}()
+
+	var req network.StatusRequest
+	// Use jsoniter for application data
+	if err := json2.Unmarshal(ctx.PostBody(), &req); err != nil {
+		errMsg := fmt.Sprintf("Invalid status request body: %s", err.Error())
+		logger.Errorw(errMsg, "body", string(ctx.PostBody()))
+		ctx.Error(errMsg, fasthttp.StatusBadRequest)
+		return
+	}
+
+	logger.Infow(
+		"Status request received",
+		"txnId",
+		req.TxnId,
+		"dsNames",
+		req.DsNames,
+	)
+
+	var outcome txn.TxnOutcome
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		outcome, err = committer.QueryOutcome(req.TxnId, req.DsNames, req.Keys)
+	}
+	var resp network.Response[txn.TxnOutcome]
+	if err != nil {
+		resp = network.Response[txn.TxnOutcome]{
+			Status: "Error",
+			ErrMsg: err.Error(),
+		}
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+	} else {
+		resp = network.Response[txn.TxnOutcome]{
+			Status: "OK",
+			Data:   outcome,
+		}
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	}
+
+	// Use jsoniter for response
+	respBytes, marshalErr := json2.Marshal(resp)
+	if marshalErr != nil {
+		logger.Errorw("Failed to marshal status response", "error", marshalErr)
+		ctx.Error("Internal Server Error", fasthttp.StatusInternalServerError)
+		return
+	}
+	ctx.SetContentType("application/json")
+	_, err = ctx.Write(respBytes)
+	if err != nil {
+		logger.Errorw("Failed to write response", "error", err)
+	}
+}
+
 func (s *Server) abortHandler(ctx *fasthttp.RequestCtx) {
 	startTime := time.Now()
 	defer func() {
@@ -498,7 +645,10 @@ func (s *Server) abortHandler(ctx *fasthttp.RequestCtx) {
 		req.GroupKeyList,
 	)
 
-	err := s.committer.Abort(req.DsName, req.KeyList, req.GroupKeyList)
+	_, committer, err := s.namespaces.Get(req.Namespace)
+	if err == nil {
+		err = committer.Abort(req.DsName, req.KeyList, req.GroupKeyList)
+	}
 	var resp network.Response[string] // Generic response type
 	if err != nil {
 		// Abort failing is usually just a warning unless it leaks resources
@@ -581,6 +731,21 @@ var (
 		"http",
 		"Type of registry to use: 'http' or 'etcd'",
 	)
+	oracleFree = flag.Bool(
+		"oracle-free",
+		false,
+		"Use local clocks with bounded uncertainty instead of the time oracle",
+	)
+	uncertainty = flag.Duration(
+		"uncertainty",
+		10*time.Millisecond,
+		"Maximum clock uncertainty in oracle-free mode (config.Config.MaxClockUncertainty)",
+	)
+	oracleClock = flag.Bool(
+		"oracle-clock",
+		false,
+		"Use the clock of the time oracle for leases (config.Config.LeaseClock)",
+	)
 )
 
 // Global benchmark config loaded from YAML
@@ -648,7 +813,19 @@ func main() {
 	logger.Infow("Executor configured to handle datastores", "dsNames", handledDsNames)
 
 	// Create the time source (Oracle)
-	oracle := timesource.NewGlobalTimeSource(benConfig.TimeOracleUrl)
+	var oracle timesource.TimeSourcer = timesource.NewGlobalTimeSource(benConfig.TimeOracleUrl)
+	if *oracleFree {
+		logger.Infow("Running without time oracle", "uncertainty", *uncertainty)
+		config.Config.MaxClockUncertainty = *uncertainty
+		oracle = timesource.NewSimpleTimeSource()
+	} else if *oracleClock {
+		clock, err := timesource.NewOracleClock(benConfig.TimeOracleUrl, time.Second)
+		if err != nil {
+			logger.Fatalw("Failed to read the oracle clock", "error", err)
+		}
+		logger.Infow("Using the oracle clock for leases", "offset", clock.Offset())
+		config.Config.LeaseClock = clock
+	}
 
 	// Create the main Server instance with registry type
 	server := NewServer(
@@ -714,9 +891,14 @@ func loadConfig(configPath string) error {
 	}
 
 	// Validate essential config values
-	if benConfig.TimeOracleUrl == "" {
+	if benConfig.TimeOracleUrl == "" && !*oracleFree {
 		return fmt.Errorf("timeOracleUrl must be specified in the benchmark configuration")
 	}
+	se, err := benConfig.Serializer()
+	if err != nil {
+		return fmt.Errorf("invalid compression in the benchmark configuration: %w", err)
+	}
+	config.Config.Serializer = se
 	logger.Infow(
 		"Benchmark configuration loaded successfully",
 		"timeOracleUrl",
@@ -2,11 +2,15 @@ package main
 
 import (
 	"strings"
+	"time"
 
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
 	"github.com/kkkzoz/oreo/pkg/datastore/cassandra"
 	"github.com/kkkzoz/oreo/pkg/datastore/couchdb"
 	"github.com/kkkzoz/oreo/pkg/datastore/dynamodb"
+	"github.com/kkkzoz/oreo/pkg/datastore/etcd"
 	"github.com/kkkzoz/oreo/pkg/datastore/mongo"
+	"github.com/kkkzoz/oreo/pkg/datastore/postgres"
 	"github.com/kkkzoz/oreo/pkg/datastore/redis"
 	"github.com/kkkzoz/oreo/pkg/datastore/tikv"
 	"github.com/kkkzoz/oreo/pkg/logger"
@@ -123,6 +127,24 @@ func getConnMap(wType string, dbComb string) map[string]txn.Connector {
 				} else {
 					logger.Warn("TiKVAddr not configured despite being requested in --db")
 				}
+			case "Bolt":
+				if benConfig.BoltPath != "" {
+					connMap["Bolt"] = getBoltConn()
+				} else {
+					logger.Warn("BoltPath not configured despite being requested in --db")
+				}
+			case "Postgres":
+				if benConfig.PostgresAddr != "" {
+					connMap["Postgres"] = getPostgresConn()
+				} else {
+					logger.Warn("PostgresAddr not configured despite being requested in --db")
+				}
+			case "Etcd":
+				if len(benConfig.EtcdAddr) > 0 {
+					connMap["Etcd"] = getEtcdConn()
+				} else {
+					logger.Warn("EtcdAddr not configured despite being requested in --db")
+				}
 			default:
 				logger.Errorf("Invalid database name '%s' in --db combination", db)
 			}
@@ -248,9 +270,12 @@ func getRedisConn(id int) *redis.RedisConnection {
 	}
 	logger.Infow("Connecting to Redis", "id", id, "address", address)
 	redisConn := redis.NewRedisConnection(&redis.ConnectionOptions{
-		Address:  address,
-		Password: benConfig.RedisPassword,
-		PoolSize: *poolSize,
+		Address:    address,
+		Addresses:  benConfig.RedisAddrs,
+		MasterName: benConfig.RedisMasterName,
+		Cluster:    benConfig.RedisCluster,
+		Password:   benConfig.RedisPassword,
+		PoolSize:   *poolSize,
 	})
 	err := redisConn.Connect() // Connect attempts to ping
 	if err != nil {
@@ -318,6 +343,47 @@ func getTiKVConn() *tikv.TiKVConnection {
 	return tikvConn
 }
 
+func getBoltConn() *bolt.BoltConnection {
+	logger.Infow("Opening Bolt", "path", benConfig.BoltPath)
+	boltConn := bolt.NewBoltConnection(&bolt.ConnectionOptions{
+		Path:    benConfig.BoltPath,
+		Timeout: time.Second,
+	})
+	err := boltConn.Connect() // Connect opens the file and takes its lock
+	if err != nil {
+		logger.Fatalw("Failed to open Bolt", "path", benConfig.BoltPath, "error", err)
+	}
+	logger.Info("Opened Bolt successfully.")
+	return boltConn
+}
+
+func getPostgresConn() *postgres.PostgresConnection {
+	logger.Infow("Connecting to Postgres", "address", benConfig.PostgresAddr)
+	postgresConn := postgres.NewPostgresConnection(&postgres.ConnectionOptions{
+		Address:   benConfig.PostgresAddr,
+		TableName: "records",
+	})
+	err := postgresConn.Connect() // Connect also creates the tables
+	if err != nil {
+		logger.Fatalw("Failed to connect to Postgres", "address", benConfig.PostgresAddr, "error", err)
+	}
+	logger.Info("Connected to Postgres successfully.")
+	return postgresConn
+}
+
+func getEtcdConn() *etcd.EtcdConnection {
+	logger.Infow("Connecting to Etcd", "endpoints", benConfig.EtcdAddr)
+	etcdConn := etcd.NewEtcdConnection(&etcd.ConnectionOptions{
+		Endpoints: benConfig.EtcdAddr,
+	})
+	err := etcdConn.Connect()
+	if err != nil {
+		logger.Fatalw("Failed to connect to Etcd", "endpoints", benConfig.EtcdAddr, "error", err)
+	}
+	logger.Info("Connected to Etcd successfully.")
+	return etcdConn
+}
+
 // getMapKeys is a small helper for logging map keys without values
 func getMapKeys(m map[string]txn.PredicateInfo) []string {
 	if m == nil {
@@ -78,6 +78,16 @@ func handleTimestamp(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleClock serves the wall clock of the node in microseconds,
+// which clients follow to stamp and check leases on a common timeline.
+func handleClock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	_, writeErr := fmt.Fprintf(w, "%d", time.Now().UnixMicro())
+	if writeErr != nil {
+		Log.Errorw("Failed to write clock response", "error", writeErr)
+	}
+}
+
 // handleHealth reports status based on the isActive flag. Crucial for HAProxy.
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	activeMutex.RLock()
@@ -201,6 +211,7 @@ func main() {
 	// --- Role-Specific Logic ---
 	mux := http.NewServeMux() // Use a mux for clarity
 	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/clock", handleClock)
 
 	switch role {
 	case "primary":
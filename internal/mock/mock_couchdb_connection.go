@@ -70,6 +70,12 @@ func (m *MockCouchDBConnection) ConditionalUpdate(
 	return m.CouchDBConnection.ConditionalUpdate(key, value, doCreate)
 }
 
+// ConditionalUpdateBatch issues the updates one by one,
+// so that each of them goes through the injected failures.
+func (m *MockCouchDBConnection) ConditionalUpdateBatch(ops []txn.UpdateOp) []txn.BatchResult {
+	return txn.ConditionalUpdateEach(m, ops)
+}
+
 func (m *MockCouchDBConnection) PutItem(key string, value txn.DataItem) (string, error) {
 	time.Sleep(m.networkDelay)
 	defer func() { m.debugCounter--; m.PutTimes++ }()
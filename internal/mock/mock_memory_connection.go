@@ -0,0 +1,164 @@
+package mock
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+var (
+	_ txn.Connector  = (*MockMemoryConnection)(nil)
+	_ txn.KeyScanner = (*MockMemoryConnection)(nil)
+)
+
+// MockMemoryConnection is a Connector keeping the records in memory,
+// for the tests that do not need a real datastore.
+// The records are BoltItems, so the datastores are created with
+// bolt.NewBoltDatastore or a bolt.BoltItemFactory.
+type MockMemoryConnection struct {
+	mu    sync.Mutex
+	items map[string]bolt.BoltItem
+	kvs   map[string]string
+}
+
+func NewMockMemoryConnection() *MockMemoryConnection {
+	return &MockMemoryConnection{
+		items: make(map[string]bolt.BoltItem),
+		kvs:   make(map[string]string),
+	}
+}
+
+// toItem returns a copy of value, so the records are never shared.
+func toItem(value txn.DataItem) bolt.BoltItem {
+	item := bolt.BoltItem{
+		BKey:          value.Key(),
+		BValue:        value.Value(),
+		BGroupKeyList: value.GroupKeyList(),
+		BTxnState:     value.TxnState(),
+		BTValid:       value.TValid(),
+		BTLease:       value.TLease(),
+		BPrev:         value.Prev(),
+		BLinkedLen:    value.LinkedLen(),
+		BIsDeleted:    value.IsDeleted(),
+		BVersion:      value.Version(),
+	}
+	if it, ok := value.(txn.ExpiringItem); ok {
+		item.BTExpire = it.TExpire()
+	}
+	return item
+}
+
+func (c *MockMemoryConnection) Connect() error {
+	return nil
+}
+
+func (c *MockMemoryConnection) GetItem(key string) (txn.DataItem, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if !ok {
+		return &bolt.BoltItem{}, errors.New(txn.KeyNotFound)
+	}
+	return &item, nil
+}
+
+func (c *MockMemoryConnection) PutItem(key string, value txn.DataItem) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = toItem(value)
+	return value.Version(), nil
+}
+
+// ConditionalUpdate writes value if the version of the stored item matches
+// the version of value, or if doCreate is set and the item does not exist.
+// It returns the new version, which is the old one plus one.
+func (c *MockMemoryConnection) ConditionalUpdate(key string, value txn.DataItem, doCreate bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	old, ok := c.items[key]
+	if doCreate && ok || !doCreate && (!ok || old.BVersion != value.Version()) {
+		return "", errors.New(txn.VersionMismatch)
+	}
+	item := toItem(value)
+	item.BVersion = util.AddToString(value.Version(), 1)
+	c.items[key] = item
+	return item.BVersion, nil
+}
+
+// ConditionalCommit marks the item as COMMITTED at tCommit
+// if its version matches version, and returns the new version.
+func (c *MockMemoryConnection) ConditionalCommit(key string, version string, tCommit int64) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if !ok {
+		return "", errors.New(txn.KeyNotFound)
+	}
+	if item.BVersion != version {
+		return "", errors.New(txn.VersionMismatch)
+	}
+	item.BTxnState = config.COMMITTED
+	item.BTValid = tCommit
+	item.BVersion = util.AddToString(version, 1)
+	c.items[key] = item
+	return item.BVersion, nil
+}
+
+func (c *MockMemoryConnection) AtomicCreate(name string, value any) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.kvs[name]; ok {
+		return old, errors.New(txn.KeyExists)
+	}
+	c.kvs[name] = util.ToString(value)
+	return "", nil
+}
+
+func (c *MockMemoryConnection) Get(name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.kvs[name]
+	if !ok {
+		return "", errors.New(txn.KeyNotFound)
+	}
+	return value, nil
+}
+
+func (c *MockMemoryConnection) Put(name string, value any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kvs[name] = util.ToString(value)
+	return nil
+}
+
+// Delete removes name from both the items and the plain values.
+func (c *MockMemoryConnection) Delete(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, name)
+	delete(c.kvs, name)
+	return nil
+}
+
+// ScanKeys calls fn with the key of every item, in order. The keys are
+// collected first, so fn may use the connection.
+func (c *MockMemoryConnection) ScanKeys(fn func(key string) error) error {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.items))
+	for key := range c.items {
+		keys = append(keys, key)
+	}
+	c.mu.Unlock()
+	slices.Sort(keys)
+	for _, key := range keys {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package mock
+
+import (
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/kkkzoz/oreo/pkg/txn/testsuite"
+)
+
+type memoryTestSuiteHelper struct{}
+
+func (h *memoryTestSuiteHelper) MakeItem(ops txn.ItemOptions) txn.DataItem {
+	return &bolt.BoltItem{
+		BKey:          ops.Key,
+		BValue:        util.ToJSONString(ops.Value),
+		BGroupKeyList: ops.GroupKeyList,
+		BTxnState:     ops.TxnState,
+		BTValid:       ops.TValid,
+		BTLease:       ops.TLease,
+		BPrev:         ops.Prev,
+		BIsDeleted:    ops.IsDeleted,
+		BVersion:      ops.Version,
+	}
+}
+
+func (h *memoryTestSuiteHelper) NewInstance() txn.DataItem {
+	return &bolt.BoltItem{}
+}
+
+func TestMockMemoryConnector_InterfaceSuite(t *testing.T) {
+	testsuite.TestConnectorSuite(t, NewMockMemoryConnection(), &memoryTestSuiteHelper{})
+}
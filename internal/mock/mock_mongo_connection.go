@@ -73,6 +73,12 @@ func (m *MockMongoConnection) ConditionalUpdate(
 	return m.MongoConnection.ConditionalUpdate(key, value, doCreate)
 }
 
+// ConditionalUpdateBatch issues the updates one by one,
+// so that each of them goes through the injected failures.
+func (m *MockMongoConnection) ConditionalUpdateBatch(ops []txn.UpdateOp) []txn.BatchResult {
+	return txn.ConditionalUpdateEach(m, ops)
+}
+
 func (m *MockMongoConnection) PutItem(key string, value txn.DataItem) (string, error) {
 	time.Sleep(m.networkDelay)
 	defer func() { m.debugCounter--; m.PutTimes++ }()
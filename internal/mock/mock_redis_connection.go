@@ -72,6 +72,12 @@ func (m *MockRedisConnection) ConditionalUpdate(
 	return m.RedisConnection.ConditionalUpdate(key, value, doCreate)
 }
 
+// ConditionalUpdateBatch issues the updates one by one,
+// so that each of them goes through the injected failures.
+func (m *MockRedisConnection) ConditionalUpdateBatch(ops []txn.UpdateOp) []txn.BatchResult {
+	return txn.ConditionalUpdateEach(m, ops)
+}
+
 func (m *MockRedisConnection) PutItem(key string, value txn.DataItem) (string, error) {
 	time.Sleep(m.networkDelay)
 	defer func() { m.debugCounter--; m.PutTimes++ }()
@@ -0,0 +1,90 @@
+package simulation
+
+import (
+	"sort"
+)
+
+// check recovers the records once every lease has expired, and checks that
+//   - the records of every key are the same in all the datastores,
+//     so the transactions are atomic across the datastores,
+//   - the committed transactions read the same record in all the datastores,
+//   - the committed writes of every key count 1, 2, ... without gaps nor
+//     duplicates, so none of them is lost and none read an aborted write,
+//   - the records hold the last committed write of their keys.
+func (s *simulator) check() error {
+	s.clock.advance(2 * s.opts.LeaseTime)
+	s.clock.schedule("checker")
+	for e := range s.downUntil {
+		delete(s.downUntil, e)
+		e.restart()
+	}
+	s.tracef("checking the records")
+
+	faults := newTxnFaults()
+	executors := s.executors
+	// the records are recovered by the checker itself
+	s.executors = nil
+	tx := s.newTransaction(faults)
+	s.executors = executors
+	if err := tx.Start(); err != nil {
+		return s.fail("failed to start the checker: %v", err)
+	}
+	final := make(map[string]cell, len(s.keys))
+	for _, key := range s.keys {
+		for i, dsName := range s.dsNames {
+			var value cell
+			if err := tx.Read(dsName, key, &value); err != nil {
+				return s.fail("%s of %s cannot be read after recovery: %v", key, dsName, firstLine(err))
+			}
+			if i == 0 {
+				final[key] = value
+			} else if value != final[key] {
+				return s.fail("%s is %v in %s but %v in %s",
+					key, final[key], s.dsNames[0], value, dsName)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return s.fail("failed to commit the checker: %v", err)
+	}
+
+	// writers maps the keys to the transactions that committed each count
+	writers := make(map[string]map[int]string, len(s.keys))
+	for _, o := range s.outcomes {
+		if !o.committed {
+			continue
+		}
+		if o.fractured != "" {
+			return s.fail("%s committed after a fractured read: %s", o.id, o.fractured)
+		}
+		for key, count := range o.writes {
+			if writers[key] == nil {
+				writers[key] = make(map[int]string)
+			}
+			if other, ok := writers[key][count]; ok {
+				return s.fail("lost update: %s and %s both committed count %d of %s",
+					other, o.id, count, key)
+			}
+			writers[key][count] = o.id
+		}
+	}
+	for _, key := range s.keys {
+		counts := make([]int, 0, len(writers[key]))
+		for count := range writers[key] {
+			counts = append(counts, count)
+		}
+		sort.Ints(counts)
+		for i, count := range counts {
+			if count != i+1 {
+				return s.fail("%s committed count %d of %s, which follows no committed write",
+					writers[key][count], count, key)
+			}
+		}
+		want := cell{Count: len(counts), Writer: writers[key][len(counts)]}
+		if final[key] != want {
+			return s.fail("%s is %v after recovery, but the last committed write is %v",
+				key, final[key], want)
+		}
+	}
+	return nil
+}
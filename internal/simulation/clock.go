@@ -0,0 +1,75 @@
+package simulation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/timesource"
+)
+
+var (
+	_ config.Clock           = (*Clock)(nil)
+	_ timesource.TimeSourcer = (*Clock)(nil)
+)
+
+// epoch is the virtual time a simulation starts at.
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock is the virtual clock of a simulation. It is both the lease clock
+// and the time source of the nodes, and it reads the virtual time plus
+// the skew of the node that is scheduled.
+//
+// The virtual time only moves when the scheduler advances it, and by
+// a microsecond whenever a timestamp is taken, so that two timestamps
+// taken by the same node are never equal.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	skews   map[string]time.Duration
+	current string
+}
+
+func newClock() *Clock {
+	return &Clock{
+		now:   epoch,
+		skews: make(map[string]time.Duration),
+	}
+}
+
+// Now returns the virtual time as seen by the scheduled node.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now.Add(c.skews[c.current])
+}
+
+// GetTime returns the virtual time as seen by the scheduled node
+// in microseconds, like timesource.SimpleTimeSource.
+func (c *Clock) GetTime(mode string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(time.Microsecond)
+	return c.now.Add(c.skews[c.current]).UnixMicro(), nil
+}
+
+// advance moves the virtual time forward by d.
+func (c *Clock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// schedule makes node the one whose clock is read.
+func (c *Clock) schedule(node string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = node
+}
+
+// setSkew sets the offset of the clock of node from the virtual time.
+func (c *Clock) setSkew(node string, skew time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skews[node] = skew
+}
@@ -0,0 +1,161 @@
+package simulation
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/network"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+var _ txn.RemoteClient = (*remoteClient)(nil)
+
+// executor is an in-process executor serving the stores.
+// While it is down, its requests fail and its operations are dropped.
+type executor struct {
+	name   string
+	stores map[string]*store
+	clock  *Clock
+
+	mu        sync.Mutex
+	down      bool
+	reader    *network.Reader
+	committer *network.Committer
+}
+
+func newExecutor(name string, stores map[string]*store, clock *Clock) *executor {
+	e := &executor{name: name, stores: stores, clock: clock}
+	e.restart()
+	return e
+}
+
+// restart brings the executor up with empty caches.
+func (e *executor) restart() {
+	connMap := make(map[string]txn.Connector, len(e.stores))
+	for dsName, st := range e.stores {
+		connMap[dsName] = &conn{st: st, ds: dsName, txnId: noTxn, gate: e}
+	}
+	factory := &bolt.BoltItemFactory{}
+	reader := network.NewReader(connMap, factory, config.Config.Serializer, network.NewCacher())
+	committer := network.NewCommitter(connMap, *reader, config.Config.Serializer, factory, e.clock)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.down = false
+	e.reader, e.committer = reader, committer
+}
+
+func (e *executor) crash() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.down = true
+}
+
+func (e *executor) isDown() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.down
+}
+
+func (e *executor) pass(phase, string) error {
+	if e.isDown() {
+		return fmt.Errorf("executor %s is down", e.name)
+	}
+	return nil
+}
+
+// serve returns the reader and the committer of the executor if it is up.
+func (e *executor) serve() (*network.Reader, *network.Committer, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.down {
+		return nil, nil, fmt.Errorf("executor %s is down", e.name)
+	}
+	return e.reader, e.committer, nil
+}
+
+// remoteClient sends the requests of a transaction to the executors,
+// through the gate of its coordinator. The items are copied both ways,
+// as they would be serialized over the network.
+type remoteClient struct {
+	faults *txnFaults
+	// executors maps the datastores to the executors serving them
+	executors map[string]*executor
+}
+
+func (rc *remoteClient) executor(dsName string, p phase) (*network.Reader, *network.Committer, error) {
+	if err := rc.faults.pass(p, dsName); err != nil {
+		return nil, nil, err
+	}
+	e, ok := rc.executors[dsName]
+	if !ok {
+		return nil, nil, errors.New("no executor serves " + dsName)
+	}
+	return e.serve()
+}
+
+func (rc *remoteClient) Read(
+	dsName string,
+	key string,
+	ts int64,
+	cfg txn.RecordConfig,
+) (txn.DataItem, txn.RemoteDataStrategy, string, error) {
+	reader, _, err := rc.executor(dsName, phaseRead)
+	if err != nil {
+		return nil, txn.Normal, "", err
+	}
+	item, strategy, groupKeyList, err := reader.Read(dsName, key, ts, cfg, true)
+	if item != nil {
+		it := toItem(item)
+		item = &it
+	}
+	return item, strategy, groupKeyList, err
+}
+
+func (rc *remoteClient) Prepare(dsName string, itemList []txn.DataItem,
+	startTime int64,
+	cfg txn.RecordConfig, validationMap map[string]txn.PredicateInfo,
+	readSet map[string]txn.ReadVersion,
+	conds map[string]txn.WriteCondition,
+	merges map[string][]txn.MergeDelta,
+) (map[string]string, int64, error) {
+	_, committer, err := rc.executor(dsName, phasePrepare)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := make([]txn.DataItem, len(itemList))
+	for i, item := range itemList {
+		it := toItem(item)
+		items[i] = &it
+	}
+	return committer.Prepare(dsName, items, startTime, cfg,
+		validationMap, readSet, conds, merges)
+}
+
+func (rc *remoteClient) Commit(dsName string, infoList []txn.CommitInfo, tCommit int64) error {
+	_, committer, err := rc.executor(dsName, phaseCommit)
+	if err != nil {
+		return err
+	}
+	return committer.Commit(dsName, infoList, tCommit)
+}
+
+func (rc *remoteClient) Renew(dsName string, infoList []txn.CommitInfo, tLease time.Time) (map[string]string, error) {
+	_, committer, err := rc.executor(dsName, phaseOther)
+	if err != nil {
+		return nil, err
+	}
+	return committer.Renew(dsName, infoList, tLease)
+}
+
+func (rc *remoteClient) Abort(dsName string, keyList []string, groupKeyList string) error {
+	_, committer, err := rc.executor(dsName, phaseAbort)
+	if err != nil {
+		return err
+	}
+	return committer.Abort(dsName, keyList, groupKeyList)
+}
@@ -0,0 +1,146 @@
+package simulation
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+)
+
+// errCrashed is returned by the operations of a crashed coordinator.
+var errCrashed = errors.New("simulated crash")
+
+// CrashPoint is where a coordinator dies while committing a transaction.
+type CrashPoint int
+
+const (
+	NoCrash CrashPoint = iota
+	// CrashDuringPrepare kills the coordinator after it has prepared
+	// the records of some of the datastores.
+	CrashDuringPrepare
+	// CrashBeforeGroupKey kills the coordinator after it has prepared
+	// all the records, before it creates the group key.
+	CrashBeforeGroupKey
+	// CrashMidCommit kills the coordinator after it has created the group
+	// key, once it has committed the records of some of the datastores.
+	CrashMidCommit
+)
+
+func (c CrashPoint) String() string {
+	return [...]string{"none", "during prepare", "before group key", "mid-commit"}[c]
+}
+
+// phase returns the phase the coordinator dies in.
+func (c CrashPoint) phase() phase {
+	return [...]phase{phaseNone, phasePrepare, phaseGroupKey, phaseCommit}[c]
+}
+
+// txnFaults is the gate of a coordinator committing a transaction.
+//
+// The faults only depend on the phases of the operations and on their
+// datastores, not on the order in which the goroutines of the coordinator
+// issue them, so they are reproducible.
+type txnFaults struct {
+	txnId string
+
+	crash CrashPoint
+	// survivors are the datastores whose operations of the crash phase
+	// are delivered before the crash. The group key is always dropped, as
+	// the datastore holding it depends on the order of a map iteration.
+	survivors map[string]bool
+
+	// pause is the phase at whose first operation the coordinator
+	// stops until the scheduler releases it. The pauses before the
+	// prepare phase are left to the scheduler.
+	pause   phase
+	reached chan struct{}
+	release chan struct{}
+
+	mu      sync.Mutex
+	paused  bool
+	crashed bool
+	// ended is set once the transaction is over, so the work it left in
+	// the background is dropped, as if the coordinator died right away
+	ended bool
+}
+
+func newTxnFaults() *txnFaults {
+	return &txnFaults{
+		reached: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (f *txnFaults) id() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.txnId
+}
+
+func (f *txnFaults) pass(p phase, ds string) error {
+	f.mu.Lock()
+	if f.ended {
+		f.mu.Unlock()
+		return errCrashed
+	}
+	// the paused coordinator holds all its operations,
+	// so none of them runs during the pause
+	if f.pause != phaseNone && (p == f.pause || f.paused) {
+		if !f.paused {
+			f.paused = true
+			close(f.reached)
+		}
+		f.mu.Unlock()
+		<-f.release
+		f.mu.Lock()
+	}
+	defer f.mu.Unlock()
+
+	switch p {
+	case phaseRead:
+		return nil
+	case phaseAbort:
+		// a failed commit aborts in the background, racing with the
+		// next steps, so the coordinator never gets to abort and its
+		// records are recovered by the others once its lease expires
+		return errCrashed
+	}
+	if f.crash == NoCrash {
+		return nil
+	}
+	at := f.crash.phase()
+	switch {
+	case p == at && p != phaseGroupKey && f.survivors[ds]:
+		return nil
+	case p == at, p > at && p != phaseOther:
+		f.crashed = true
+		return errCrashed
+	case f.crashed:
+		return errCrashed
+	}
+	return nil
+}
+
+// waitPaused waits until the coordinator reaches its pause or commitDone
+// is closed, and reports whether it is paused.
+func (f *txnFaults) waitPaused(commitDone <-chan struct{}) bool {
+	select {
+	case <-f.reached:
+		return true
+	case <-commitDone:
+		return false
+	}
+}
+
+// resume releases the paused coordinator.
+func (f *txnFaults) resume() {
+	close(f.release)
+}
+
+// end drops the later operations of the transaction,
+// and reports whether the coordinator crashed.
+func (f *txnFaults) end() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ended = true
+	return f.crashed
+}
@@ -0,0 +1,508 @@
+// Package simulation runs the commit protocol deterministically.
+//
+// A simulation runs many coordinators, and optionally executors, against
+// in-memory datastores. A scheduler seeded by Options.Seed interleaves the
+// steps of their transactions one at a time, moves a virtual clock forward,
+// skews the clocks of the coordinators, pauses them between the phases of
+// their commits, crashes them and takes the executors down. At the end, the
+// records are recovered and the invariants of the protocol are checked.
+//
+// The same seed always produces the same run, so a failure is reproduced
+// from the seed it reports.
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+// Options configures a simulation. The zero counts and durations
+// are replaced by their defaults, and the zero rates disable the faults.
+type Options struct {
+	Seed int64
+	// Coordinators is the number of coordinators, 4 by default.
+	Coordinators int
+	// Executors is the number of executors. Zero runs the coordinators
+	// in the local mode.
+	Executors int
+	// Datastores is the number of datastores, 2 by default.
+	// Every transaction writes its keys in all of them.
+	Datastores int
+	// Keys is the number of keys, 4 by default.
+	Keys int
+	// Transactions is the number of transactions run, 100 by default.
+	Transactions int
+
+	// CrashRate is the probability that a coordinator crashes while committing.
+	CrashRate float64
+	// PauseRate is the probability that a coordinator is paused between
+	// two phases of its commit, for up to MaxPause steps of the others.
+	PauseRate float64
+	// MaxPause is 5 steps by default.
+	MaxPause int
+	// ExecutorFailureRate is the probability that an executor goes down
+	// at each step, for up to 5 steps.
+	ExecutorFailureRate float64
+	// MaxClockSkew bounds the offsets of the clocks of the coordinators.
+	MaxClockSkew time.Duration
+	// MaxStep bounds how far the virtual time moves at each step,
+	// 10 minutes by default.
+	MaxStep time.Duration
+	// LeaseTime is the lease of the prepared records, an hour by default.
+	// The leases are renewed on the wall clock, every third of it, so it has
+	// to be long enough for the renewals not to happen during a run.
+	LeaseTime time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.Coordinators == 0 {
+		o.Coordinators = 4
+	}
+	if o.Datastores == 0 {
+		o.Datastores = 2
+	}
+	if o.Keys == 0 {
+		o.Keys = 4
+	}
+	if o.Transactions == 0 {
+		o.Transactions = 100
+	}
+	if o.MaxPause == 0 {
+		o.MaxPause = 5
+	}
+	if o.MaxStep == 0 {
+		o.MaxStep = 10 * time.Minute
+	}
+	if o.LeaseTime == 0 {
+		o.LeaseTime = time.Hour
+	}
+}
+
+// Result sums up a successful simulation.
+type Result struct {
+	Seed      int64
+	Committed int
+	Aborted   int
+	// Crashed counts the transactions whose coordinator crashed.
+	Crashed int
+	// Trace lists the steps of the simulation.
+	Trace []string
+}
+
+// Failure is returned when an invariant is violated.
+type Failure struct {
+	Seed   int64
+	Reason string
+	Trace  []string
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("simulation with seed %d failed: %s", f.Seed, f.Reason)
+}
+
+// cell is the value of the records. Each transaction increments
+// the count of its keys, in every datastore.
+type cell struct {
+	Count  int
+	Writer string
+}
+
+// txnRun is a transaction being run by a coordinator.
+type txnRun struct {
+	tx     *txn.Transaction
+	faults *txnFaults
+	keys   []string
+	// next is the next step, 0 to start, then one per key, then commit
+	next int
+	// writes maps the keys to the counts written
+	writes    map[string]int
+	fractured string
+	// held is set while the coordinator is paused before it commits
+	held bool
+	done chan struct{}
+	err  error
+}
+
+type coordinator struct {
+	name string
+	run  *txnRun
+	// pause is the number of steps left before the paused commit resumes
+	pause int
+}
+
+// outcome is the outcome of a transaction that reached its commit.
+type outcome struct {
+	id        string
+	committed bool
+	writes    map[string]int
+	fractured string
+}
+
+// simulator is the state of a simulation.
+type simulator struct {
+	opts  Options
+	rng   *rand.Rand
+	clock *Clock
+	ids   *idGenerator
+
+	dsNames   []string
+	keys      []string
+	stores    map[string]*store
+	executors []*executor
+	// downUntil maps the executors that are down to the step they recover at
+	downUntil map[*executor]int
+
+	coordinators []*coordinator
+	started      int
+	step         int
+	outcomes     []outcome
+	res          Result
+}
+
+// Run runs a simulation and checks its invariants. It returns a *Failure
+// if they are violated. It sets config.Config for the duration of the run,
+// so no other transaction may run in the process meanwhile.
+func Run(opts Options) (*Result, error) {
+	opts.setDefaults()
+	s := &simulator{
+		opts:      opts,
+		rng:       rand.New(rand.NewSource(opts.Seed)),
+		clock:     newClock(),
+		ids:       &idGenerator{},
+		stores:    make(map[string]*store),
+		downUntil: make(map[*executor]int),
+		res:       Result{Seed: opts.Seed},
+	}
+	defer s.configure()()
+
+	for i := 0; i < opts.Datastores; i++ {
+		name := fmt.Sprintf("ds%d", i)
+		s.dsNames = append(s.dsNames, name)
+		s.stores[name] = newStore()
+	}
+	for i := 0; i < opts.Keys; i++ {
+		s.keys = append(s.keys, fmt.Sprintf("k%d", i))
+	}
+	for i := 0; i < opts.Executors; i++ {
+		s.executors = append(s.executors, newExecutor(fmt.Sprintf("e%d", i), s.stores, s.clock))
+	}
+	for i := 0; i < opts.Coordinators; i++ {
+		c := &coordinator{name: fmt.Sprintf("c%d", i)}
+		if opts.MaxClockSkew > 0 {
+			skew := time.Duration(s.rng.Int63n(2*int64(opts.MaxClockSkew)+1)) - opts.MaxClockSkew
+			s.clock.setSkew(c.name, skew)
+			s.tracef("%s has a clock skew of %v", c.name, skew)
+		}
+		s.coordinators = append(s.coordinators, c)
+	}
+
+	if err := s.load(); err != nil {
+		return nil, s.fail("failed to load the records: %v", err)
+	}
+	for s.busy() {
+		s.schedule()
+	}
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	return &s.res, nil
+}
+
+// configure sets config.Config for the simulation,
+// and returns the function restoring it.
+func (s *simulator) configure() func() {
+	saved := config.Config
+	config.Config.LeaseClock = s.clock
+	config.Config.LeaseTime = s.opts.LeaseTime
+	config.Config.IdGenerator = s.ids
+	// the coordinators create the group keys, and take the commit
+	// timestamps from their clocks in the local mode too
+	config.Config.AblationLevel = 2
+	config.Config.MaxClockUncertainty = 0
+	return func() { config.Config = saved }
+}
+
+// idGenerator generates the transaction ids in order. They have the same
+// length, so none of them is a part of another.
+type idGenerator struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (g *idGenerator) GenerateId() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	return fmt.Sprintf("sim-%06d", g.next)
+}
+
+func (s *simulator) tracef(format string, args ...any) {
+	s.res.Trace = append(s.res.Trace, fmt.Sprintf("%d: ", s.step)+fmt.Sprintf(format, args...))
+}
+
+func (s *simulator) fail(format string, args ...any) *Failure {
+	return &Failure{Seed: s.opts.Seed, Reason: fmt.Sprintf(format, args...), Trace: s.res.Trace}
+}
+
+// newTransaction returns a transaction of the node gated by faults.
+func (s *simulator) newTransaction(faults *txnFaults) *txn.Transaction {
+	var tx *txn.Transaction
+	if len(s.executors) == 0 {
+		tx = txn.NewTransactionWithOracle(s.clock)
+	} else {
+		client := &remoteClient{faults: faults, executors: make(map[string]*executor)}
+		for _, dsName := range s.dsNames {
+			client.executors[dsName] = s.executors[s.rng.Intn(len(s.executors))]
+		}
+		tx = txn.NewTransactionWithRemote(client, s.clock)
+	}
+	for _, dsName := range s.dsNames {
+		tx.AddDatastore(bolt.NewBoltDatastore(dsName, &conn{
+			st:    s.stores[dsName],
+			ds:    dsName,
+			txnId: faults.id,
+			gate:  faults,
+		}))
+	}
+	return tx
+}
+
+// load writes the initial records without faults.
+func (s *simulator) load() error {
+	s.clock.schedule("loader")
+	tx := s.newTransaction(newTxnFaults())
+	if err := tx.Start(); err != nil {
+		return err
+	}
+	for _, key := range s.keys {
+		for _, dsName := range s.dsNames {
+			if err := tx.Write(dsName, key, cell{}); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// busy reports whether transactions are left to run or to finish.
+func (s *simulator) busy() bool {
+	if s.started < s.opts.Transactions {
+		return true
+	}
+	return slices.ContainsFunc(s.coordinators, func(c *coordinator) bool { return c.run != nil })
+}
+
+// schedule runs a step of a coordinator picked at random,
+// then moves the virtual time and the executors forward.
+func (s *simulator) schedule() {
+	var runnable []*coordinator
+	for _, c := range s.coordinators {
+		if c.pause > 0 || c.run == nil && s.started >= s.opts.Transactions {
+			continue
+		}
+		runnable = append(runnable, c)
+	}
+	if len(runnable) > 0 {
+		c := runnable[s.rng.Intn(len(runnable))]
+		s.clock.schedule(c.name)
+		s.stepCoordinator(c)
+	}
+
+	s.step++
+	s.clock.advance(time.Microsecond + time.Duration(s.rng.Int63n(int64(s.opts.MaxStep))))
+	for _, c := range s.coordinators {
+		if c.pause > 0 {
+			c.pause--
+		}
+	}
+	for _, e := range s.executors {
+		if until, ok := s.downUntil[e]; ok && until <= s.step {
+			delete(s.downUntil, e)
+			e.restart()
+			s.tracef("%s restarts", e.name)
+		}
+	}
+	if len(s.executors) > 0 && s.rng.Float64() < s.opts.ExecutorFailureRate {
+		e := s.executors[s.rng.Intn(len(s.executors))]
+		if _, ok := s.downUntil[e]; !ok {
+			s.downUntil[e] = s.step + 1 + s.rng.Intn(5)
+			e.crash()
+			s.tracef("%s goes down", e.name)
+		}
+	}
+}
+
+// stepCoordinator runs the next step of the transaction of c,
+// starting a new one if it has none.
+func (s *simulator) stepCoordinator(c *coordinator) {
+	if c.run == nil {
+		s.begin(c)
+		return
+	}
+	run := c.run
+	switch {
+	case run.held:
+		s.tracef("%s resumes %s", c.name, run.tx.TxnId)
+		run.held = false
+		s.runCommit(c)
+	case run.done != nil:
+		s.tracef("%s resumes %s", c.name, run.tx.TxnId)
+		run.faults.resume()
+		<-run.done
+		s.finish(c)
+	case run.next <= len(run.keys):
+		s.readWrite(c, run.keys[run.next-1])
+		run.next++
+	default:
+		s.commit(c)
+	}
+}
+
+func (s *simulator) begin(c *coordinator) {
+	s.started++
+	faults := newTxnFaults()
+	run := &txnRun{
+		tx:     s.newTransaction(faults),
+		faults: faults,
+		writes: make(map[string]int),
+		next:   1,
+	}
+	n := 1 + s.rng.Intn(min(2, len(s.keys)))
+	for _, i := range s.rng.Perm(len(s.keys))[:n] {
+		run.keys = append(run.keys, s.keys[i])
+	}
+	c.run = run
+	if err := run.tx.Start(); err != nil {
+		s.abort(c, "start", err)
+		return
+	}
+	faults.mu.Lock()
+	faults.txnId = run.tx.TxnId
+	faults.mu.Unlock()
+	s.tracef("%s starts %s on %v", c.name, run.tx.TxnId, run.keys)
+}
+
+// readWrite increments key in every datastore.
+func (s *simulator) readWrite(c *coordinator, key string) {
+	run := c.run
+	var first cell
+	for i, dsName := range s.dsNames {
+		var value cell
+		if err := run.tx.Read(dsName, key, &value); err != nil {
+			s.abort(c, "read "+key, err)
+			return
+		}
+		if i == 0 {
+			first = value
+		} else if value != first && run.fractured == "" {
+			run.fractured = fmt.Sprintf("%s read %v in %s but %v in %s",
+				key, first, s.dsNames[0], value, dsName)
+		}
+	}
+	next := cell{Count: first.Count + 1, Writer: run.tx.TxnId}
+	for _, dsName := range s.dsNames {
+		if err := run.tx.Write(dsName, key, next); err != nil {
+			s.abort(c, "write "+key, err)
+			return
+		}
+	}
+	run.writes[key] = next.Count
+	s.tracef("%s: %s reads %v and writes %v", c.name, run.tx.TxnId, first, next)
+}
+
+// commit commits the transaction of c with the faults picked at random.
+func (s *simulator) commit(c *coordinator) {
+	run := c.run
+	faults := run.faults
+	if s.rng.Float64() < s.opts.CrashRate {
+		faults.crash = CrashPoint(1 + s.rng.Intn(3))
+		faults.survivors = make(map[string]bool)
+		for _, dsName := range s.dsNames {
+			faults.survivors[dsName] = s.rng.Intn(2) == 0
+		}
+		s.tracef("%s will crash %v committing %s, survivors %v",
+			c.name, faults.crash, run.tx.TxnId, faults.survivors)
+	}
+	if s.rng.Float64() < s.opts.PauseRate {
+		faults.pause = phasePrepare + phase(s.rng.Intn(3))
+	}
+	if faults.pause == phasePrepare {
+		// the prepare reads the clock before it reaches the datastores,
+		// so the coordinator is held before it commits instead
+		faults.pause = phaseNone
+		run.held = true
+		s.pauseCommit(c, phasePrepare)
+		return
+	}
+	s.runCommit(c)
+}
+
+// runCommit runs the commit of c until it completes or reaches its pause.
+func (s *simulator) runCommit(c *coordinator) {
+	run := c.run
+	run.done = make(chan struct{})
+	go func() {
+		defer close(run.done)
+		run.err = run.tx.Commit()
+	}()
+	if run.faults.waitPaused(run.done) {
+		s.pauseCommit(c, run.faults.pause)
+		return
+	}
+	s.finish(c)
+}
+
+func (s *simulator) pauseCommit(c *coordinator, p phase) {
+	c.pause = 1 + s.rng.Intn(s.opts.MaxPause)
+	s.tracef("%s pauses %s before its %v phase for %d steps",
+		c.name, c.run.tx.TxnId, p, c.pause)
+}
+
+// finish records the outcome of the committed transaction of c.
+func (s *simulator) finish(c *coordinator) {
+	run := c.run
+	c.run = nil
+	crashed := run.faults.end()
+	if crashed {
+		s.res.Crashed++
+	}
+	o := outcome{
+		id:        run.tx.TxnId,
+		committed: run.err == nil,
+		writes:    run.writes,
+		fractured: run.fractured,
+	}
+	s.outcomes = append(s.outcomes, o)
+	if o.committed {
+		s.res.Committed++
+		s.tracef("%s commits %s (crashed: %v)", c.name, o.id, crashed)
+	} else {
+		s.res.Aborted++
+		// the error is left out, as the datastores prepare concurrently
+		// and the first of them to fail gives it
+		s.tracef("%s fails to commit %s (crashed: %v)", c.name, o.id, crashed)
+	}
+}
+
+// abort aborts the transaction of c after it failed to step.
+func (s *simulator) abort(c *coordinator, step string, err error) {
+	run := c.run
+	c.run = nil
+	_ = run.tx.Abort()
+	run.faults.end()
+	s.res.Aborted++
+	s.tracef("%s aborts %s after failing to %s: %v", c.name, run.tx.TxnId, step, firstLine(err))
+}
+
+func firstLine(err error) string {
+	msg, _, _ := strings.Cut(err.Error(), "\n")
+	return msg
+}
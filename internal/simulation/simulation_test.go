@@ -0,0 +1,79 @@
+package simulation
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	seed = flag.Int64("sim.seed", 0, "runs the simulations with this seed only")
+	runs = flag.Int("sim.runs", 20, "number of seeds simulated per configuration")
+)
+
+// runSeeds runs the simulation with opts for every seed,
+// and reports the seed and the trace of the failures.
+func runSeeds(t *testing.T, opts Options) {
+	seeds := make([]int64, 0, *runs)
+	if *seed != 0 {
+		seeds = append(seeds, *seed)
+	} else {
+		for i := 1; i <= *runs; i++ {
+			seeds = append(seeds, int64(i))
+		}
+	}
+	for _, s := range seeds {
+		opts.Seed = s
+		res, err := Run(opts)
+		var failure *Failure
+		if errors.As(err, &failure) {
+			t.Fatalf("%v\ntrace:\n%s\nreproduce with: go test ./internal/simulation -run '%s' -sim.seed=%d",
+				failure, strings.Join(failure.Trace, "\n"), t.Name(), s)
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, opts.Transactions, res.Committed+res.Aborted)
+	}
+}
+
+func TestSimulationLocal(t *testing.T) {
+	runSeeds(t, Options{
+		Transactions: 100,
+		CrashRate:    0.2,
+		PauseRate:    0.2,
+		MaxClockSkew: time.Minute,
+	})
+}
+
+func TestSimulationRemote(t *testing.T) {
+	runSeeds(t, Options{
+		Executors:           3,
+		Transactions:        100,
+		CrashRate:           0.2,
+		PauseRate:           0.2,
+		ExecutorFailureRate: 0.05,
+		MaxClockSkew:        time.Minute,
+	})
+}
+
+func TestSimulationIsDeterministic(t *testing.T) {
+	for _, executors := range []int{0, 2} {
+		opts := Options{
+			Seed:                42,
+			Executors:           executors,
+			CrashRate:           0.3,
+			PauseRate:           0.3,
+			ExecutorFailureRate: 0.1,
+			MaxClockSkew:        time.Minute,
+		}
+		first, err := Run(opts)
+		assert.NoError(t, err)
+		second, err := Run(opts)
+		assert.NoError(t, err)
+		assert.Equal(t, first.Trace, second.Trace)
+		assert.NotZero(t, first.Crashed)
+	}
+}
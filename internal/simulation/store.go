@@ -0,0 +1,279 @@
+package simulation
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+var _ txn.BatchConnector = (*conn)(nil)
+
+// store is the in-memory state of a datastore, shared by the nodes.
+type store struct {
+	mu    sync.Mutex
+	items map[string]bolt.BoltItem
+	kvs   map[string]string
+}
+
+func newStore() *store {
+	return &store{
+		items: make(map[string]bolt.BoltItem),
+		kvs:   make(map[string]string),
+	}
+}
+
+// toItem returns a copy of item, so the nodes never share the records.
+func toItem(item txn.DataItem) bolt.BoltItem {
+	it := bolt.BoltItem{
+		BKey:          item.Key(),
+		BValue:        item.Value(),
+		BGroupKeyList: item.GroupKeyList(),
+		BTxnState:     item.TxnState(),
+		BTValid:       item.TValid(),
+		BTLease:       item.TLease(),
+		BPrev:         item.Prev(),
+		BLinkedLen:    item.LinkedLen(),
+		BIsDeleted:    item.IsDeleted(),
+		BVersion:      item.Version(),
+	}
+	if exp, ok := item.(txn.ExpiringItem); ok {
+		it.BTExpire = exp.TExpire()
+	}
+	return it
+}
+
+// phase is the step of the commit protocol an operation belongs to.
+type phase int
+
+const (
+	phaseNone phase = iota
+	// phaseRead covers the reads, which never change the records
+	phaseRead
+	phasePrepare
+	phaseGroupKey
+	phaseCommit
+	// phaseOther covers the other writes, such as the rollbacks and
+	// rollforwards of the records of other transactions
+	phaseOther
+	// phaseAbort covers the writes of a coordinator aborting its own
+	// transaction
+	phaseAbort
+)
+
+func (p phase) String() string {
+	return [...]string{"none", "read", "prepare", "group key", "commit", "other", "abort"}[p]
+}
+
+// gate decides whether the operations of a node are delivered.
+type gate interface {
+	// pass is called before an operation of phase p on datastore ds,
+	// which is dropped if it returns an error.
+	pass(p phase, ds string) error
+}
+
+// conn is the connector of a node to a store. The operations go through
+// the gate of the node, and the ones of the transaction txnId are told
+// apart from the rollbacks and rollforwards of the others.
+type conn struct {
+	st    *store
+	ds    string
+	txnId func() string
+	gate  gate
+}
+
+// owns reports whether the group key list belongs to the transaction txnId.
+func (c *conn) owns(groupKeyList string) bool {
+	id := c.txnId()
+	return id != "" && strings.Contains(groupKeyList, ":"+id)
+}
+
+// phaseOf returns the phase of the conditional update writing item to key.
+// Replacing a record of the transaction txnId by another one rolls it back.
+func (c *conn) phaseOf(key string, item txn.DataItem) phase {
+	if !c.owns(item.GroupKeyList()) {
+		c.st.mu.Lock()
+		old, ok := c.st.items[key]
+		c.st.mu.Unlock()
+		if ok && c.owns(old.BGroupKeyList) {
+			return phaseAbort
+		}
+		return phaseOther
+	}
+	switch {
+	case item.IsDeleted():
+		// the simulation never deletes, so this rolls back a created record
+		return phaseAbort
+	case item.TxnState() == config.PREPARED:
+		return phasePrepare
+	case item.TxnState() == config.COMMITTED:
+		return phaseCommit
+	}
+	return phaseOther
+}
+
+// do runs op if the gate lets the operation through.
+func (c *conn) do(p phase, op func() error) error {
+	if err := c.gate.pass(p, c.ds); err != nil {
+		return err
+	}
+	return op()
+}
+
+func (c *conn) Connect() error { return nil }
+
+func (c *conn) GetItem(key string) (txn.DataItem, error) {
+	var item bolt.BoltItem
+	err := c.do(phaseRead, func() error {
+		c.st.mu.Lock()
+		defer c.st.mu.Unlock()
+		var ok bool
+		if item, ok = c.st.items[key]; !ok {
+			return errors.New(txn.KeyNotFound)
+		}
+		return nil
+	})
+	if err != nil {
+		return &bolt.BoltItem{}, err
+	}
+	return &item, nil
+}
+
+func (c *conn) PutItem(key string, value txn.DataItem) (string, error) {
+	return value.Version(), c.do(phaseOther, func() error {
+		c.st.mu.Lock()
+		defer c.st.mu.Unlock()
+		c.st.items[key] = toItem(value)
+		return nil
+	})
+}
+
+func (c *conn) ConditionalUpdate(key string, value txn.DataItem, doCreate bool) (string, error) {
+	var newVer string
+	err := c.do(c.phaseOf(key, value), func() error {
+		c.st.mu.Lock()
+		defer c.st.mu.Unlock()
+		old, ok := c.st.items[key]
+		if doCreate && ok || !doCreate && (!ok || old.BVersion != value.Version()) {
+			return errors.New(txn.VersionMismatch)
+		}
+		item := toItem(value)
+		item.BVersion = util.AddToString(value.Version(), 1)
+		c.st.items[key] = item
+		newVer = item.BVersion
+		return nil
+	})
+	return newVer, err
+}
+
+func (c *conn) ConditionalCommit(key string, version string, tCommit int64) (string, error) {
+	var newVer string
+	err := c.do(phaseOther, func() error {
+		c.st.mu.Lock()
+		defer c.st.mu.Unlock()
+		item, ok := c.st.items[key]
+		if !ok || item.BVersion != version {
+			return errors.New(txn.VersionMismatch)
+		}
+		item.BTxnState = config.COMMITTED
+		item.BTValid = tCommit
+		item.BVersion = util.AddToString(version, 1)
+		c.st.items[key] = item
+		newVer = item.BVersion
+		return nil
+	})
+	return newVer, err
+}
+
+// ConditionalUpdateBatch performs the conditional updates one by one, in order.
+//
+// The executors only wait for all the records of a prepare to be read when
+// the connector is a BatchConnector, and they apply the updates afterwards,
+// so the updates of a failed prepare never land after it returns.
+func (c *conn) ConditionalUpdateBatch(ops []txn.UpdateOp) []txn.BatchResult {
+	results := make([]txn.BatchResult, len(ops))
+	for i, op := range ops {
+		results[i].Version, results[i].Err = c.ConditionalUpdate(op.Key, op.Value, op.DoCreate)
+	}
+	return results
+}
+
+// ConditionalCommitBatch performs the conditional commits one by one, in order.
+func (c *conn) ConditionalCommitBatch(infos []txn.CommitInfo, tCommit int64) []txn.BatchResult {
+	results := make([]txn.BatchResult, len(infos))
+	for i, info := range infos {
+		results[i].Version, results[i].Err = c.ConditionalCommit(info.Key, info.Version, tCommit)
+	}
+	return results
+}
+
+func (c *conn) Get(name string) (string, error) {
+	var value string
+	err := c.do(phaseRead, func() error {
+		c.st.mu.Lock()
+		defer c.st.mu.Unlock()
+		var ok bool
+		if value, ok = c.st.kvs[name]; !ok {
+			return errors.New(txn.KeyNotFound)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (c *conn) Put(name string, value any) error {
+	return c.do(phaseOther, func() error {
+		c.st.mu.Lock()
+		defer c.st.mu.Unlock()
+		c.st.kvs[name] = util.ToString(value)
+		return nil
+	})
+}
+
+func (c *conn) Delete(name string) error {
+	return c.do(phaseOther, func() error {
+		c.st.mu.Lock()
+		defer c.st.mu.Unlock()
+		delete(c.st.kvs, name)
+		delete(c.st.items, name)
+		return nil
+	})
+}
+
+// AtomicCreate creates the key-value pair name if it does not exist.
+// The group keys of the transaction txnId are created in phaseGroupKey,
+// or in phaseAbort when they abort it.
+func (c *conn) AtomicCreate(name string, value any) (string, error) {
+	p := phaseOther
+	if id := c.txnId(); id != "" && strings.HasSuffix(name, ":"+id) {
+		p = phaseGroupKey
+		var groupKey txn.GroupKey
+		if json.Unmarshal([]byte(util.ToString(value)), &groupKey) == nil &&
+			groupKey.TxnState == config.ABORTED {
+			p = phaseAbort
+		}
+	}
+	var old string
+	err := c.do(p, func() error {
+		c.st.mu.Lock()
+		defer c.st.mu.Unlock()
+		var ok bool
+		if old, ok = c.st.kvs[name]; ok {
+			return errors.New(txn.KeyExists)
+		}
+		c.st.kvs[name] = util.ToString(value)
+		return nil
+	})
+	return old, err
+}
+
+// openGate delivers every operation.
+type openGate struct{}
+
+func (openGate) pass(phase, string) error { return nil }
+func noTxn() string                       { return "" }
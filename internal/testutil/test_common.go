@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"testing"
 	"time"
+
+	"github.com/kkkzoz/oreo/pkg/config"
 )
 
 type Person struct {
@@ -42,6 +45,15 @@ func NewTestItem(value string) TestItem {
 	}
 }
 
+// UseCoordinatorGroupKeys makes the coordinator create the group keys,
+// which the local transactions need since no executor creates them,
+// until the end of the test.
+func UseCoordinatorGroupKeys(t testing.TB) {
+	level := config.Config.AblationLevel
+	config.Config.AblationLevel = 3
+	t.Cleanup(func() { config.Config.AblationLevel = level })
+}
+
 var InputItemList = []TestItem{
 	NewTestItem("item1"),
 	NewTestItem("item2"),
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+var (
+	errNotPrepared = errors.New("the record is not in the PREPARED state")
+	errUndecided   = errors.New("the transaction of the record is not decided yet")
+	errCommitted   = errors.New("the transaction of the record has committed")
+	errAborted     = errors.New("the transaction of the record has aborted")
+	errLeaseActive = errors.New("the lease of the record has not expired")
+	errNoGroupKeys = errors.New("the record has no group keys")
+)
+
+// Outcome is the outcome of a transaction derived from its group keys.
+type Outcome string
+
+const (
+	OutcomeCommitted Outcome = "COMMITTED"
+	OutcomeAborted   Outcome = "ABORTED"
+	// OutcomeUndecided means some group keys are missing and none is aborted,
+	// so the transaction is either running or has been abandoned.
+	OutcomeUndecided Outcome = "UNDECIDED"
+)
+
+// GroupKeyStatus is the state of a group key, if it exists.
+type GroupKeyStatus struct {
+	Url     string
+	Exists  bool
+	State   string `json:",omitempty"`
+	TCommit int64  `json:",omitempty"`
+}
+
+// Admin inspects and repairs the records through the connectors.
+type Admin struct {
+	conns     map[string]txn.Connector
+	dss       map[string]*txn.Datastore
+	groupKeys *txn.GroupKeyMaintainer
+}
+
+// NewAdmin creates a new Admin. The keys of conns are the datastore names
+// used by the transactions, which are the prefixes of their group keys.
+func NewAdmin(conns map[string]txn.Connector, factories map[string]txn.DataItemFactory) *Admin {
+	groupKeys := txn.NewGroupKeyMaintainer()
+	dss := make(map[string]*txn.Datastore, len(conns))
+	for name, conn := range conns {
+		dss[name] = txn.NewDatastore(name, conn, factories[name])
+		groupKeys.AddConnector(dss[name])
+	}
+	return &Admin{
+		conns:     conns,
+		dss:       dss,
+		groupKeys: groupKeys,
+	}
+}
+
+func (a *Admin) conn(dsName string) (txn.Connector, error) {
+	conn, ok := a.conns[dsName]
+	if !ok {
+		return nil, fmt.Errorf("datastore %s is not configured", dsName)
+	}
+	return conn, nil
+}
+
+// Chain returns the versions of a record, starting from the latest one
+// and following the Prev field.
+func (a *Admin) Chain(dsName string, key string) ([]txn.DataItem, error) {
+	conn, err := a.conn(dsName)
+	if err != nil {
+		return nil, err
+	}
+	item, err := conn.GetItem(key)
+	if err != nil {
+		return nil, err
+	}
+	chain := []txn.DataItem{item}
+	for item.Prev() != "" {
+		item, err = a.dss[dsName].PrevItem(item)
+		if err != nil {
+			return chain, fmt.Errorf("failed to decode version %d: %v", len(chain)+1, err)
+		}
+		chain = append(chain, item)
+	}
+	return chain, nil
+}
+
+// GroupKey returns the state of the group key at url,
+// which has the form "<datastore>:<txnId>".
+func (a *Admin) GroupKey(url string) (GroupKeyStatus, error) {
+	dsName, _, ok := strings.Cut(url, ":")
+	if !ok {
+		return GroupKeyStatus{}, fmt.Errorf("invalid group key %q, expected <datastore>:<txnId>", url)
+	}
+	if _, err := a.conn(dsName); err != nil {
+		return GroupKeyStatus{}, err
+	}
+	groupKey, err := a.groupKeys.GetSingleGroupKey(url)
+	if err != nil {
+		if errors.Is(err, txn.KeyNotFound) {
+			return GroupKeyStatus{Url: url}, nil
+		}
+		return GroupKeyStatus{}, err
+	}
+	return GroupKeyStatus{
+		Url:     url,
+		Exists:  true,
+		State:   stateName(groupKey.TxnState),
+		TCommit: groupKey.TCommit,
+	}, nil
+}
+
+// Outcome returns the outcome of the transaction that wrote the latest
+// version of the record key. The group keys are taken from the GroupKeyList
+// of the record, and the transaction is undecided while any of them is missing.
+// Unlike a reader, Outcome never aborts an undecided transaction.
+func (a *Admin) Outcome(dsName string, key string) (Outcome, []GroupKeyStatus, error) {
+	conn, err := a.conn(dsName)
+	if err != nil {
+		return "", nil, err
+	}
+	item, err := conn.GetItem(key)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(groupKeyUrls(item)) == 0 {
+		return "", nil, errNoGroupKeys
+	}
+	return a.recordOutcome(item)
+}
+
+// recordOutcome returns the outcome of the transaction that wrote item,
+// which requires all the group keys in its GroupKeyList.
+func (a *Admin) recordOutcome(item txn.DataItem) (Outcome, []GroupKeyStatus, error) {
+	statuses, err := a.groupKeyStatuses(groupKeyUrls(item))
+	if err != nil {
+		return "", nil, err
+	}
+	outcome := OutcomeCommitted
+	for _, status := range statuses {
+		if !status.Exists {
+			outcome = OutcomeUndecided
+			continue
+		}
+		if status.State == stateName(config.ABORTED) {
+			return OutcomeAborted, statuses, nil
+		}
+	}
+	return outcome, statuses, nil
+}
+
+func (a *Admin) groupKeyStatuses(urls []string) ([]GroupKeyStatus, error) {
+	statuses := make([]GroupKeyStatus, 0, len(urls))
+	for _, url := range urls {
+		status, err := a.GroupKey(url)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// RollForward commits a PREPARED record whose group keys are all committed.
+// If dryRun is set, the checks are performed but the record is not modified.
+func (a *Admin) RollForward(dsName string, key string, dryRun bool) (txn.DataItem, error) {
+	item, err := a.preparedItem(dsName, key)
+	if err != nil {
+		return nil, err
+	}
+	outcome, statuses, err := a.recordOutcome(item)
+	if err != nil {
+		return nil, err
+	}
+	switch outcome {
+	case OutcomeAborted:
+		return nil, errAborted
+	case OutcomeUndecided:
+		return nil, errUndecided
+	}
+	if dryRun {
+		return item, nil
+	}
+
+	tCommit := item.TValid()
+	for _, status := range statuses {
+		tCommit = max(tCommit, status.TCommit)
+	}
+	item.SetTValid(tCommit)
+	return a.dss[dsName].RollForwardItem(item)
+}
+
+// Rollback restores the previous version of a PREPARED record
+// whose transaction has aborted.
+//
+// If the transaction is undecided and the lease of the record has expired,
+// or ignoreLease is set, the transaction is aborted first by creating its
+// group keys in the ABORTED state, as a reader would do. This fails if the
+// coordinator commits the transaction in the meantime.
+// If dryRun is set, the checks are performed but nothing is modified.
+func (a *Admin) Rollback(dsName string, key string, ignoreLease bool, dryRun bool) (txn.DataItem, error) {
+	item, err := a.preparedItem(dsName, key)
+	if err != nil {
+		return nil, err
+	}
+	outcome, _, err := a.recordOutcome(item)
+	if err != nil {
+		return nil, err
+	}
+	switch outcome {
+	case OutcomeCommitted:
+		return nil, errCommitted
+	case OutcomeUndecided:
+		if !ignoreLease && !item.TLease().Before(config.Config.LeaseClock.Now()) {
+			return nil, errLeaseActive
+		}
+		if dryRun {
+			return item, nil
+		}
+		a.groupKeys.CreateGroupKey(groupKeyUrls(item), config.ABORTED)
+		outcome, _, err = a.recordOutcome(item)
+		if err != nil {
+			return nil, err
+		}
+		if outcome != OutcomeAborted {
+			return nil, fmt.Errorf("failed to abort the transaction, its outcome is %s", outcome)
+		}
+	}
+	if dryRun {
+		return item, nil
+	}
+	return a.dss[dsName].RollbackItem(item)
+}
+
+func (a *Admin) preparedItem(dsName string, key string) (txn.DataItem, error) {
+	conn, err := a.conn(dsName)
+	if err != nil {
+		return nil, err
+	}
+	item, err := conn.GetItem(key)
+	if err != nil {
+		return nil, err
+	}
+	if item.TxnState() != config.PREPARED {
+		return nil, errNotPrepared
+	}
+	return item, nil
+}
+
+// groupKeyUrls splits the GroupKeyList of item,
+// whose entries are separated by commas or whitespace.
+func groupKeyUrls(item txn.DataItem) []string {
+	return strings.FieldsFunc(item.GroupKeyList(), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+func stateName(state config.State) string {
+	switch state {
+	case config.EMPTY:
+		return "EMPTY"
+	case config.STARTED:
+		return "STARTED"
+	case config.PREPARED:
+		return "PREPARED"
+	case config.COMMITTED:
+		return "COMMITTED"
+	case config.ABORTED:
+		return "ABORTED"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", state)
+	}
+}
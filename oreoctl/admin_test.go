@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/postgres"
+	"github.com/kkkzoz/oreo/pkg/datastore/redis"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryConnection is an in-memory txn.Connector.
+type memoryConnection struct {
+	mu    sync.Mutex
+	items map[string]redis.RedisItem
+	kvs   map[string]string
+}
+
+func newMemoryConnection() *memoryConnection {
+	return &memoryConnection{
+		items: make(map[string]redis.RedisItem),
+		kvs:   make(map[string]string),
+	}
+}
+
+func toRedisItem(item txn.DataItem) redis.RedisItem {
+	return redis.RedisItem{
+		RKey:          item.Key(),
+		RValue:        item.Value(),
+		RGroupKeyList: item.GroupKeyList(),
+		RTxnState:     item.TxnState(),
+		RTValid:       item.TValid(),
+		RTLease:       item.TLease(),
+		RPrev:         item.Prev(),
+		RLinkedLen:    item.LinkedLen(),
+		RIsDeleted:    item.IsDeleted(),
+		RVersion:      item.Version(),
+	}
+}
+
+func (m *memoryConnection) Connect() error { return nil }
+
+func (m *memoryConnection) GetItem(key string) (txn.DataItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return &redis.RedisItem{}, errors.New(txn.KeyNotFound)
+	}
+	return &item, nil
+}
+
+func (m *memoryConnection) PutItem(key string, value txn.DataItem) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[key] = toRedisItem(value)
+	return value.Version(), nil
+}
+
+func (m *memoryConnection) ConditionalUpdate(key string, value txn.DataItem, doCreate bool) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.items[key]
+	if doCreate && ok || !doCreate && (!ok || old.RVersion != value.Version()) {
+		return "", errors.New(txn.VersionMismatch)
+	}
+	item := toRedisItem(value)
+	item.RVersion = util.AddToString(value.Version(), 1)
+	m.items[key] = item
+	return item.RVersion, nil
+}
+
+func (m *memoryConnection) ConditionalCommit(key string, version string, tCommit int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || item.RVersion != version {
+		return "", errors.New(txn.VersionMismatch)
+	}
+	item.RTxnState = config.COMMITTED
+	item.RTValid = tCommit
+	item.RVersion = util.AddToString(version, 1)
+	m.items[key] = item
+	return item.RVersion, nil
+}
+
+func (m *memoryConnection) Get(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.kvs[name]
+	if !ok {
+		return "", errors.New(txn.KeyNotFound)
+	}
+	return value, nil
+}
+
+func (m *memoryConnection) Put(name string, value any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.kvs[name] = util.ToString(value)
+	return nil
+}
+
+func (m *memoryConnection) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.kvs, name)
+	delete(m.items, name)
+	return nil
+}
+
+func (m *memoryConnection) AtomicCreate(name string, value any) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.kvs[name]; ok {
+		return old, errors.New(txn.KeyExists)
+	}
+	m.kvs[name] = util.ToString(value)
+	return "", nil
+}
+
+func newTestAdmin() (*Admin, *memoryConnection) {
+	conn := newMemoryConnection()
+	admin := NewAdmin(
+		map[string]txn.Connector{"redis": conn},
+		map[string]txn.DataItemFactory{"redis": &redis.RedisItemFactory{}},
+	)
+	return admin, conn
+}
+
+// putPrepared stores a record with a committed version "v1"
+// and a version "v2" prepared by txnId.
+func putPrepared(t *testing.T, conn *memoryConnection, key string, txnId string, tLease time.Time) {
+	committed := &redis.RedisItem{
+		RKey:       key,
+		RValue:     "v1",
+		RTxnState:  config.COMMITTED,
+		RTValid:    10,
+		RLinkedLen: 1,
+		RVersion:   "1",
+	}
+	prev, err := config.Config.Serializer.Serialize(committed)
+	assert.NoError(t, err)
+	_, err = conn.PutItem(key, &redis.RedisItem{
+		RKey:          key,
+		RValue:        "v2",
+		RGroupKeyList: "redis:" + txnId,
+		RTxnState:     config.PREPARED,
+		RTValid:       20,
+		RTLease:       tLease,
+		RPrev:         string(prev),
+		RLinkedLen:    2,
+		RVersion:      "2",
+	})
+	assert.NoError(t, err)
+}
+
+func TestChain(t *testing.T) {
+	admin, conn := newTestAdmin()
+	putPrepared(t, conn, "key", "txn1", time.Now())
+
+	chain, err := admin.Chain("redis", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(chain))
+	assert.Equal(t, "v2", chain[0].Value())
+	assert.Equal(t, config.PREPARED, chain[0].TxnState())
+	assert.Equal(t, "v1", chain[1].Value())
+	assert.Equal(t, config.COMMITTED, chain[1].TxnState())
+
+	_, err = admin.Chain("mongo", "key")
+	assert.Error(t, err)
+}
+
+func TestOutcome(t *testing.T) {
+	redisConn, mongoConn := newMemoryConnection(), newMemoryConnection()
+	admin := NewAdmin(
+		map[string]txn.Connector{"redis": redisConn, "mongo": mongoConn},
+		map[string]txn.DataItemFactory{
+			"redis": &redis.RedisItemFactory{},
+			"mongo": &redis.RedisItemFactory{},
+		},
+	)
+	putPrepared(t, redisConn, "key", "txn1", time.Now().Add(time.Hour))
+	item, _ := redisConn.GetItem("key")
+	item.SetGroupKeyList("redis:txn1,mongo:txn1")
+	redisConn.PutItem("key", item)
+
+	outcome, statuses, err := admin.Outcome("redis", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, OutcomeUndecided, outcome)
+	assert.Equal(t, 2, len(statuses))
+
+	// the group key in mongo is still missing
+	redisConn.Put("redis:txn1", `{"TxnState":3,"TCommit":25}`)
+	outcome, statuses, err = admin.Outcome("redis", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, OutcomeUndecided, outcome)
+	assert.Equal(t, "COMMITTED", statuses[0].State)
+	assert.Equal(t, int64(25), statuses[0].TCommit)
+	assert.False(t, statuses[1].Exists)
+
+	mongoConn.Put("mongo:txn1", `{"TxnState":3,"TCommit":25}`)
+	outcome, _, err = admin.Outcome("redis", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, OutcomeCommitted, outcome)
+
+	putPrepared(t, redisConn, "key2", "txn2", time.Now().Add(time.Hour))
+	redisConn.Put("redis:txn2", `{"TxnState":4}`)
+	outcome, _, err = admin.Outcome("redis", "key2")
+	assert.NoError(t, err)
+	assert.Equal(t, OutcomeAborted, outcome)
+
+	_, _, err = admin.Outcome("redis", "missing")
+	assert.Error(t, err)
+}
+
+func TestRollForward(t *testing.T) {
+	admin, conn := newTestAdmin()
+	putPrepared(t, conn, "key", "txn1", time.Now().Add(time.Hour))
+
+	_, err := admin.RollForward("redis", "key", false)
+	assert.ErrorIs(t, err, errUndecided)
+
+	conn.Put("redis:txn1", `{"TxnState":3,"TCommit":25}`)
+	_, err = admin.RollForward("redis", "key", true)
+	assert.NoError(t, err)
+	item, _ := conn.GetItem("key")
+	assert.Equal(t, config.PREPARED, item.TxnState())
+
+	_, err = admin.RollForward("redis", "key", false)
+	assert.NoError(t, err)
+	item, _ = conn.GetItem("key")
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+	assert.Equal(t, int64(25), item.TValid())
+	assert.Equal(t, "v2", item.Value())
+
+	_, err = admin.RollForward("redis", "key", false)
+	assert.ErrorIs(t, err, errNotPrepared)
+}
+
+func TestRollback(t *testing.T) {
+	admin, conn := newTestAdmin()
+	putPrepared(t, conn, "key", "txn1", time.Now().Add(time.Hour))
+
+	// the lease of the record is still valid
+	_, err := admin.Rollback("redis", "key", false, false)
+	assert.ErrorIs(t, err, errLeaseActive)
+
+	_, err = admin.Rollback("redis", "key", true, true)
+	assert.NoError(t, err)
+	_, err = conn.Get("redis:txn1")
+	assert.Error(t, err, "dry run must not abort the transaction")
+
+	_, err = admin.Rollback("redis", "key", true, false)
+	assert.NoError(t, err)
+	item, _ := conn.GetItem("key")
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+	assert.Equal(t, "v1", item.Value())
+	status, err := admin.GroupKey("redis:txn1")
+	assert.NoError(t, err)
+	assert.Equal(t, "ABORTED", status.State)
+}
+
+func TestRollbackCommitted(t *testing.T) {
+	admin, conn := newTestAdmin()
+	putPrepared(t, conn, "key", "txn1", time.Now().Add(-time.Hour))
+	conn.Put("redis:txn1", `{"TxnState":3,"TCommit":25}`)
+
+	_, err := admin.Rollback("redis", "key", true, false)
+	assert.ErrorIs(t, err, errCommitted)
+	item, _ := conn.GetItem("key")
+	assert.Equal(t, config.PREPARED, item.TxnState())
+}
+
+func TestRollbackExpiredLease(t *testing.T) {
+	admin, conn := newTestAdmin()
+	_, err := conn.PutItem("key", &redis.RedisItem{
+		RKey:          "key",
+		RValue:        "v1",
+		RGroupKeyList: "redis:txn1",
+		RTxnState:     config.PREPARED,
+		RTLease:       time.Now().Add(-time.Second),
+		RLinkedLen:    1,
+		RVersion:      "1",
+	})
+	assert.NoError(t, err)
+
+	// a record without a previous version is deleted
+	item, err := admin.Rollback("redis", "key", false, false)
+	assert.NoError(t, err)
+	assert.True(t, item.IsDeleted())
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+}
+
+func TestRunOutcomeJson(t *testing.T) {
+	admin, conn := newTestAdmin()
+	putPrepared(t, conn, "key", "txn1", time.Now())
+	conn.Put("redis:txn1", `{"TxnState":4}`)
+
+	var out bytes.Buffer
+	cmd := &command{admin: admin, out: &out, asJson: true}
+	assert.NoError(t, cmd.run("outcome", []string{"redis", "key"}))
+	var res struct {
+		TxnId     string
+		Outcome   Outcome
+		GroupKeys []GroupKeyStatus
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &res))
+	assert.Equal(t, "txn1", res.TxnId)
+	assert.Equal(t, OutcomeAborted, res.Outcome)
+	assert.Equal(t, "redis:txn1", res.GroupKeys[0].Url)
+
+	assert.Error(t, cmd.run("unknown", nil))
+}
+
+func TestParseDatastore(t *testing.T) {
+	name, _, factory, err := parseDatastore("redis=redis://:secret@localhost:6379")
+	assert.NoError(t, err)
+	assert.Equal(t, "redis", name)
+	assert.IsType(t, &redis.RedisItemFactory{}, factory)
+
+	_, _, _, err = parseDatastore("mongo=mongodb://localhost:27017/oreo")
+	assert.True(t, err != nil && strings.Contains(err.Error(), "collection"))
+
+	_, _, _, err = parseDatastore("cas=cassandra://host1,host2/oreo")
+	assert.NoError(t, err)
+
+	_, conn, _, err := parseDatastore("pg=postgres://u:p@localhost:5432/oreo?table=items&sslmode=disable")
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://u:p@localhost:5432/oreo?sslmode=disable", conn.(*postgres.PostgresConnection).GetAddress())
+
+	_, _, _, err = parseDatastore("redis://localhost:6379")
+	assert.Error(t, err)
+	_, _, _, err = parseDatastore("x=ftp://localhost")
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/datastore/cassandra"
+	"github.com/kkkzoz/oreo/pkg/datastore/couchdb"
+	"github.com/kkkzoz/oreo/pkg/datastore/dynamodb"
+	"github.com/kkkzoz/oreo/pkg/datastore/etcd"
+	"github.com/kkkzoz/oreo/pkg/datastore/mongo"
+	"github.com/kkkzoz/oreo/pkg/datastore/postgres"
+	"github.com/kkkzoz/oreo/pkg/datastore/redis"
+	"github.com/kkkzoz/oreo/pkg/datastore/tikv"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+// dsFlags collects the repeated -ds flags.
+type dsFlags []string
+
+func (f *dsFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *dsFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// parseDatastore parses a datastore of the form "<name>=<scheme>://<address>".
+// The supported forms are
+//
+//	redis://[:password@]host1:port1,host2:port2[?cluster=true|master=name][&prefix=p]
+//	kvrocks://[:password@]host:port
+//	mongodb://[user:password@]host:port/db/collection
+//	couchdb://[user:password@]host:port/db
+//	cassandra://[user:password@]host1,host2/keyspace
+//	dynamodb://host:port/table
+//	tikv://pd1,pd2
+//	bolt:///path/to/file.db
+//	postgres://[user:password@]host:port/db[?table=records]
+//	etcd://[user:password@]host1,host2[/prefix]
+func parseDatastore(spec string) (string, txn.Connector, txn.DataItemFactory, error) {
+	name, rawUrl, ok := strings.Cut(spec, "=")
+	if !ok || name == "" {
+		return "", nil, nil, fmt.Errorf("invalid datastore %q, expected <name>=<url>", spec)
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("invalid datastore %q: %v", spec, err)
+	}
+	password, _ := u.User.Password()
+	path := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	switch u.Scheme {
+	case "redis", "kvrocks":
+		query := u.Query()
+		conn := redis.NewRedisConnection(&redis.ConnectionOptions{
+			Addresses:  strings.Split(u.Host, ","),
+			MasterName: query.Get("master"),
+			Cluster:    query.Get("cluster") == "true",
+			KeyPrefix:  query.Get("prefix"),
+			Password:   password,
+		})
+		return name, conn, &redis.RedisItemFactory{}, nil
+	case "mongodb":
+		if len(path) != 2 {
+			return "", nil, nil, fmt.Errorf("invalid datastore %q, expected mongodb://host:port/db/collection", spec)
+		}
+		conn := mongo.NewMongoConnection(&mongo.ConnectionOptions{
+			Address:        "mongodb://" + u.Host,
+			Username:       u.User.Username(),
+			Password:       password,
+			DBName:         path[0],
+			CollectionName: path[1],
+		})
+		return name, conn, &mongo.MongoItemFactory{}, nil
+	case "couchdb":
+		if path[0] == "" {
+			return "", nil, nil, fmt.Errorf("invalid datastore %q, expected couchdb://host:port/db", spec)
+		}
+		conn := couchdb.NewCouchDBConnection(&couchdb.ConnectionOptions{
+			Address:  "http://" + u.Host,
+			Username: u.User.Username(),
+			Password: password,
+			DBName:   path[0],
+		})
+		return name, conn, &couchdb.CouchDBItemFactory{}, nil
+	case "cassandra":
+		if path[0] == "" {
+			return "", nil, nil, fmt.Errorf("invalid datastore %q, expected cassandra://hosts/keyspace", spec)
+		}
+		conn := cassandra.NewCassandraConnection(&cassandra.ConnectionOptions{
+			Hosts:    strings.Split(u.Host, ","),
+			Keyspace: path[0],
+			Username: u.User.Username(),
+			Password: password,
+		})
+		return name, conn, &cassandra.CassandraItemFactory{}, nil
+	case "dynamodb":
+		if path[0] == "" {
+			return "", nil, nil, fmt.Errorf("invalid datastore %q, expected dynamodb://host:port/table", spec)
+		}
+		conn := dynamodb.NewDynamoDBConnection(&dynamodb.ConnectionOptions{
+			Region:    "us-west-2",
+			TableName: path[0],
+			Endpoint:  "http://" + u.Host,
+		})
+		return name, conn, &dynamodb.DynamoDBItemFactory{}, nil
+	case "tikv":
+		conn := tikv.NewTiKVConnection(&tikv.ConnectionOptions{
+			PDAddrs: strings.Split(u.Host, ","),
+		})
+		return name, conn, &tikv.TiKVItemFactory{}, nil
+	case "bolt":
+		if u.Path == "" {
+			return "", nil, nil, fmt.Errorf("invalid datastore %q, expected bolt:///path/to/file.db", spec)
+		}
+		conn := bolt.NewBoltConnection(&bolt.ConnectionOptions{
+			Path:    u.Path,
+			Timeout: time.Second,
+		})
+		return name, conn, &bolt.BoltItemFactory{}, nil
+	case "postgres", "postgresql":
+		if path[0] == "" {
+			return "", nil, nil, fmt.Errorf("invalid datastore %q, expected postgres://host:port/db", spec)
+		}
+		query := u.Query()
+		table := query.Get("table")
+		query.Del("table")
+		u.RawQuery = query.Encode()
+		conn := postgres.NewPostgresConnection(&postgres.ConnectionOptions{
+			Address:   u.String(),
+			TableName: table,
+		})
+		return name, conn, &postgres.PostgresItemFactory{}, nil
+	case "etcd":
+		prefix := ""
+		if u.Path != "" && u.Path != "/" {
+			prefix = strings.TrimSuffix(u.Path, "/") + "/"
+		}
+		conn := etcd.NewEtcdConnection(&etcd.ConnectionOptions{
+			Endpoints: strings.Split(u.Host, ","),
+			Username:  u.User.Username(),
+			Password:  password,
+			Prefix:    prefix,
+		})
+		return name, conn, &etcd.EtcdItemFactory{}, nil
+	default:
+		return "", nil, nil, fmt.Errorf("unsupported datastore scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,267 @@
+// Command oreoctl inspects and repairs the transactional state
+// that Oreo keeps in the datastores.
+//
+// Usage:
+//
+//	oreoctl -ds <name>=<url> [-ds ...] [-json] <command> [args]
+//
+// The name of a datastore must be the name used by the transactions,
+// since it is the prefix of their group keys.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+const usage = `Usage: oreoctl -ds <name>=<url> [-ds ...] [-json] <command> [args]
+
+Commands:
+  chain <ds> <key>                       dump the version chain of a record
+  groupkey <ds>:<txnId>                  show the state of a group key
+  outcome <ds> <key>                     show the outcome of the transaction
+                                         that wrote a record
+  rollforward [-dry-run] <ds> <key>      commit a PREPARED record of a committed transaction
+  rollback [-dry-run] [-ignore-lease] <ds> <key>
+                                         roll back a PREPARED record of an aborted
+                                         or abandoned transaction
+
+Flags:
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "oreoctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	var dss dsFlags
+	fs := flag.NewFlagSet("oreoctl", flag.ContinueOnError)
+	fs.Var(&dss, "ds", "a datastore as <name>=<url>, can be repeated")
+	asJson := fs.Bool("json", false, "print the output as JSON")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no command given")
+	}
+
+	conns := make(map[string]txn.Connector, len(dss))
+	factories := make(map[string]txn.DataItemFactory, len(dss))
+	for _, spec := range dss {
+		name, conn, factory, err := parseDatastore(spec)
+		if err != nil {
+			return err
+		}
+		if err := conn.Connect(); err != nil {
+			return fmt.Errorf("failed to connect to %s: %v", name, err)
+		}
+		conns[name] = conn
+		factories[name] = factory
+	}
+	if len(conns) == 0 {
+		return fmt.Errorf("no datastore given, use -ds <name>=<url>")
+	}
+
+	cmd := &command{
+		admin:  NewAdmin(conns, factories),
+		out:    out,
+		asJson: *asJson,
+	}
+	return cmd.run(fs.Arg(0), fs.Args()[1:])
+}
+
+type command struct {
+	admin  *Admin
+	out    io.Writer
+	asJson bool
+}
+
+func (c *command) run(name string, args []string) error {
+	switch name {
+	case "chain":
+		return c.chain(args)
+	case "groupkey":
+		return c.groupKey(args)
+	case "outcome":
+		return c.outcome(args)
+	case "rollforward":
+		return c.rollForward(args)
+	case "rollback":
+		return c.rollback(args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// itemView is the printed form of a version of a record.
+type itemView struct {
+	Key          string
+	Value        string
+	TxnState     string
+	TValid       int64
+	TLease       time.Time
+	GroupKeyList string
+	LinkedLen    int
+	IsDeleted    bool
+	Version      string
+}
+
+func newItemView(item txn.DataItem) itemView {
+	return itemView{
+		Key:          item.Key(),
+		Value:        item.Value(),
+		TxnState:     stateName(item.TxnState()),
+		TValid:       item.TValid(),
+		TLease:       item.TLease(),
+		GroupKeyList: item.GroupKeyList(),
+		LinkedLen:    item.LinkedLen(),
+		IsDeleted:    item.IsDeleted(),
+		Version:      item.Version(),
+	}
+}
+
+func (c *command) chain(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: chain <ds> <key>")
+	}
+	chain, err := c.admin.Chain(args[0], args[1])
+	views := make([]itemView, 0, len(chain))
+	for _, item := range chain {
+		views = append(views, newItemView(item))
+	}
+	// print the versions decoded before an error
+	if len(views) > 0 {
+		if printErr := c.printItems(views); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+func (c *command) groupKey(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: groupkey <ds>:<txnId>")
+	}
+	status, err := c.admin.GroupKey(args[0])
+	if err != nil {
+		return err
+	}
+	return c.printGroupKeys([]GroupKeyStatus{status})
+}
+
+func (c *command) outcome(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: outcome <ds> <key>")
+	}
+	outcome, statuses, err := c.admin.Outcome(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	_, txnId, _ := strings.Cut(statuses[0].Url, ":")
+	if c.asJson {
+		return c.printJson(struct {
+			TxnId     string
+			Outcome   Outcome
+			GroupKeys []GroupKeyStatus
+		}{txnId, outcome, statuses})
+	}
+	fmt.Fprintf(c.out, "%s: %s\n", txnId, outcome)
+	return c.printGroupKeys(statuses)
+}
+
+func (c *command) rollForward(args []string) error {
+	fs := flag.NewFlagSet("rollforward", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only check that the record can be rolled forward")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: rollforward [-dry-run] <ds> <key>")
+	}
+	item, err := c.admin.RollForward(fs.Arg(0), fs.Arg(1), *dryRun)
+	if err != nil {
+		return err
+	}
+	return c.printResult("rolled forward", *dryRun, item)
+}
+
+func (c *command) rollback(args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only check that the record can be rolled back")
+	ignoreLease := fs.Bool("ignore-lease", false,
+		"abort an undecided transaction even if the lease of the record has not expired")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: rollback [-dry-run] [-ignore-lease] <ds> <key>")
+	}
+	item, err := c.admin.Rollback(fs.Arg(0), fs.Arg(1), *ignoreLease, *dryRun)
+	if err != nil {
+		return err
+	}
+	return c.printResult("rolled back", *dryRun, item)
+}
+
+func (c *command) printResult(action string, dryRun bool, item txn.DataItem) error {
+	if !c.asJson {
+		if dryRun {
+			fmt.Fprintf(c.out, "%s can be %s\n", item.Key(), action)
+		} else {
+			fmt.Fprintf(c.out, "%s %s\n", item.Key(), action)
+		}
+	}
+	return c.printItems([]itemView{newItemView(item)})
+}
+
+func (c *command) printItems(views []itemView) error {
+	if c.asJson {
+		return c.printJson(views)
+	}
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tSTATE\tTVALID\tTLEASE\tLINKED\tDELETED\tVERSION\tGROUPKEYS\tVALUE")
+	for i, v := range views {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%d\t%t\t%s\t%s\t%s\n",
+			i, v.TxnState, v.TValid, v.TLease.Format(time.RFC3339Nano), v.LinkedLen,
+			v.IsDeleted, v.Version, v.GroupKeyList, v.Value)
+	}
+	return w.Flush()
+}
+
+func (c *command) printGroupKeys(statuses []GroupKeyStatus) error {
+	if c.asJson {
+		return c.printJson(statuses)
+	}
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUPKEY\tSTATE\tTCOMMIT")
+	for _, s := range statuses {
+		state := s.State
+		if !s.Exists {
+			state = "NOT FOUND"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\n", s.Url, state, s.TCommit)
+	}
+	return w.Flush()
+}
+
+func (c *command) printJson(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
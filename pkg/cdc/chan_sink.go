@@ -0,0 +1,47 @@
+package cdc
+
+import "sync"
+
+var _ ChangeSink = (*ChanSink)(nil)
+
+// ChanSink is an in-process ChangeSink that sends the events to a channel.
+type ChanSink struct {
+	ch     chan ChangeEvent
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewChanSink creates a new ChanSink whose channel has the given buffer size.
+func NewChanSink(size int) *ChanSink {
+	return &ChanSink{ch: make(chan ChangeEvent, size)}
+}
+
+// Events returns the channel receiving the events.
+// It is closed by Close.
+func (s *ChanSink) Events() <-chan ChangeEvent {
+	return s.ch
+}
+
+// Publish sends the events to the channel, blocking while it is full.
+func (s *ChanSink) Publish(events []ChangeEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+	for _, event := range events {
+		s.ch <- event
+	}
+	return nil
+}
+
+// Close closes the channel.
+func (s *ChanSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+	return nil
+}
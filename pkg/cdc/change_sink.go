@@ -0,0 +1,33 @@
+// Package cdc publishes the writes of committed transactions.
+//
+// A transaction configured with an Outbox records its writes in the outbox
+// before it is prepared. A Relay then resolves the outcome of each recorded
+// transaction from its group keys and publishes the writes of the committed
+// ones to a ChangeSink in the order of their commit timestamps.
+// An entry is only removed from the outbox after it has been published,
+// so delivery is at-least-once and a sink may observe duplicates.
+package cdc
+
+import (
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+// ChangeEvent is a committed write.
+type ChangeEvent struct {
+	TxnId     string           `json:"txnId"`
+	Datastore string           `json:"datastore"`
+	Key       string           `json:"key"`
+	Value     string           `json:"value,omitempty"`
+	IsDeleted bool             `json:"isDeleted,omitempty"`
+	Deltas    []txn.MergeDelta `json:"deltas,omitempty"`
+	TCommit   int64            `json:"tCommit"`
+}
+
+// ChangeSink receives the committed writes.
+type ChangeSink interface {
+	// Publish delivers the events, which are ordered by TCommit.
+	// The events are redelivered if an error is returned.
+	Publish(events []ChangeEvent) error
+	// Close releases the resources held by the sink.
+	Close() error
+}
@@ -0,0 +1,56 @@
+package cdc
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEvents = []ChangeEvent{
+	{TxnId: "txn1", Datastore: "redis", Key: "k1", Value: `"v1"`, TCommit: 10},
+	{TxnId: "txn2", Datastore: "mongo", Key: "k2", IsDeleted: true, TCommit: 20},
+}
+
+func TestJSONLSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.jsonl")
+	sink, err := NewJSONLSink(path)
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Publish(testEvents[:1]))
+	assert.NoError(t, sink.Close())
+
+	// the file is appended to after reopening
+	sink, err = NewJSONLSink(path)
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Publish(testEvents[1:]))
+	assert.NoError(t, sink.Close())
+	assert.ErrorIs(t, sink.Publish(testEvents), ErrSinkClosed)
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	var events []ChangeEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event ChangeEvent
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	assert.Equal(t, testEvents, events)
+}
+
+func TestChanSink(t *testing.T) {
+	sink := NewChanSink(len(testEvents))
+	assert.NoError(t, sink.Publish(testEvents))
+	assert.NoError(t, sink.Close())
+	assert.ErrorIs(t, sink.Publish(testEvents), ErrSinkClosed)
+
+	var events []ChangeEvent
+	for event := range sink.Events() {
+		events = append(events, event)
+	}
+	assert.Equal(t, testEvents, events)
+}
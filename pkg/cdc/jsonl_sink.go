@@ -0,0 +1,62 @@
+package cdc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+// ErrSinkClosed is returned by Publish after the sink is closed.
+var ErrSinkClosed = errors.New("sink is closed")
+
+var _ ChangeSink = (*JSONLSink)(nil)
+
+// JSONLSink is a ChangeSink that appends the events to a file,
+// one JSON object per line.
+type JSONLSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewJSONLSink opens or creates the file at path for appending.
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLSink{file: file}, nil
+}
+
+// Publish appends the events to the file and syncs it.
+func (s *JSONLSink) Publish(events []ChangeEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrSinkClosed
+	}
+	w := bufio.NewWriter(s.file)
+	enc := json.NewEncoder(w)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the file.
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
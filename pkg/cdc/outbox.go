@@ -0,0 +1,145 @@
+package cdc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+var (
+	_ txn.Outbox = (*FileOutbox)(nil)
+	_ txn.Outbox = (*MemoryOutbox)(nil)
+)
+
+// FileOutbox is a txn.Outbox storing each entry as a JSON file in a directory.
+// Entries are written to a temporary file that is synced and renamed,
+// so a crash never leaves a partially written entry behind.
+type FileOutbox struct {
+	dir string
+}
+
+// NewFileOutbox creates a FileOutbox in dir, creating dir if needed.
+func NewFileOutbox(dir string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileOutbox{dir: dir}, nil
+}
+
+func (o *FileOutbox) path(txnId string) string {
+	return filepath.Join(o.dir, txnId+".json")
+}
+
+// Append writes the entry to its file.
+func (o *FileOutbox) Append(entry txn.OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(o.dir, entry.TxnId+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), o.path(entry.TxnId)); err != nil {
+		return err
+	}
+	// sync the directory so the rename survives a crash
+	dir, err := os.Open(o.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// Pending reads the entries in the directory.
+func (o *FileOutbox) Pending() ([]txn.OutboxEntry, error) {
+	files, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]txn.OutboxEntry, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(o.dir, file.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// removed in the meantime
+				continue
+			}
+			return nil, err
+		}
+		var entry txn.OutboxEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Remove deletes the file of the entry.
+func (o *FileOutbox) Remove(txnId string) error {
+	err := os.Remove(o.path(txnId))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// MemoryOutbox is a process-local txn.Outbox.
+// It does not survive a crash and is intended for tests.
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	entries map[string]txn.OutboxEntry
+}
+
+// NewMemoryOutbox creates a new instance of MemoryOutbox.
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{entries: make(map[string]txn.OutboxEntry)}
+}
+
+// Append stores the entry.
+func (o *MemoryOutbox) Append(entry txn.OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries[entry.TxnId] = entry
+	return nil
+}
+
+// Pending returns the stored entries.
+func (o *MemoryOutbox) Pending() ([]txn.OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := make([]txn.OutboxEntry, 0, len(o.entries))
+	for _, entry := range o.entries {
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Remove deletes the entry of txnId.
+func (o *MemoryOutbox) Remove(txnId string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.entries, txnId)
+	return nil
+}
@@ -0,0 +1,70 @@
+package cdc
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileOutbox(t *testing.T) {
+	outbox, err := NewFileOutbox(t.TempDir())
+	assert.NoError(t, err)
+
+	entry := txn.OutboxEntry{
+		TxnId:        "txn1",
+		StartTime:    100,
+		GroupKeyUrls: []string{"redis:txn1"},
+		Changes: []txn.Change{
+			{Datastore: "redis", Key: "k1", Value: `"v1"`},
+			{Datastore: "redis", Key: "k2", IsDeleted: true},
+			{Datastore: "redis", Key: "k3", Deltas: []txn.MergeDelta{
+				{Operator: txn.IncrementOperator, Delta: "1"},
+			}},
+		},
+		CreatedAt: time.Now().Round(0),
+	}
+	assert.NoError(t, outbox.Append(entry))
+	// appending again overwrites the entry
+	assert.NoError(t, outbox.Append(entry))
+
+	entries, err := outbox.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, entry.TxnId, entries[0].TxnId)
+	assert.Equal(t, entry.Changes, entries[0].Changes)
+	assert.True(t, entry.CreatedAt.Equal(entries[0].CreatedAt))
+
+	// the entries survive a restart
+	reopened, err := NewFileOutbox(outbox.dir)
+	assert.NoError(t, err)
+	entries, err = reopened.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.NoError(t, outbox.Remove("txn1"))
+	assert.NoError(t, outbox.Remove("txn1"))
+	entries, err = outbox.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	files, err := os.ReadDir(outbox.dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestMemoryOutbox(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	assert.NoError(t, outbox.Append(txn.OutboxEntry{TxnId: "txn1"}))
+	assert.NoError(t, outbox.Append(txn.OutboxEntry{TxnId: "txn2"}))
+	entries, err := outbox.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	assert.NoError(t, outbox.Remove("txn1"))
+	entries, err = outbox.Pending()
+	assert.NoError(t, err)
+	assert.Equal(t, []txn.OutboxEntry{{TxnId: "txn2"}}, entries)
+}
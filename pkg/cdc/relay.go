@@ -0,0 +1,242 @@
+package cdc
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/logger"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+// Relay publishes the entries of committed transactions from an Outbox
+// to a ChangeSink and drops the entries of aborted ones.
+//
+// The events are published in the order of TCommit. A committed entry is
+// held back while an entry that started before its TCommit is unresolved,
+// since that transaction may still commit with a smaller TCommit, and for
+// delay after it has been resolved, to leave time to the transactions that
+// are between their start and their Append.
+type Relay struct {
+	outbox    txn.Outbox
+	sink      ChangeSink
+	groupKeys *txn.GroupKeyMaintainer
+	// conns are the connectors of the datastores, by name.
+	conns map[string]txn.Connector
+	delay time.Duration
+
+	mu sync.Mutex
+	// resolvedAt is the time when the relay saw each committed entry resolved.
+	resolvedAt map[string]time.Time
+	// lastTCommit is the TCommit of the last published entry.
+	lastTCommit int64
+}
+
+// NewRelay creates a Relay reading the group keys through the connectors
+// of the given datastores, which must be the datastores of the transactions.
+func NewRelay(
+	outbox txn.Outbox,
+	sink ChangeSink,
+	delay time.Duration,
+	dss ...txn.Datastorer,
+) *Relay {
+	groupKeys := txn.NewGroupKeyMaintainer()
+	conns := make(map[string]txn.Connector, len(dss))
+	for _, ds := range dss {
+		groupKeys.AddConnector(ds)
+		conns[ds.GetName()] = ds.GetConn()
+	}
+	return &Relay{
+		outbox:     outbox,
+		sink:       sink,
+		groupKeys:  groupKeys,
+		conns:      conns,
+		delay:      delay,
+		resolvedAt: make(map[string]time.Time),
+	}
+}
+
+// Run calls Flush every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Flush(); err != nil {
+			logger.Log.Warnw("change relay flush failed", "cause", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// committedEntry is an outbox entry whose group keys are all committed.
+type committedEntry struct {
+	txn.OutboxEntry
+	tCommit int64
+}
+
+// Flush resolves the pending entries once and publishes
+// the committed ones that are ready.
+func (r *Relay) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries, err := r.outbox.Pending()
+	if err != nil {
+		return err
+	}
+
+	watermark := int64(math.MaxInt64)
+	committed := make([]committedEntry, 0, len(entries))
+	pending := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		pending[entry.TxnId] = true
+		state, tCommit, err := r.resolve(entry)
+		if err != nil {
+			return err
+		}
+		switch state {
+		case config.COMMITTED:
+			if _, ok := r.resolvedAt[entry.TxnId]; !ok {
+				r.resolvedAt[entry.TxnId] = time.Now()
+			}
+			committed = append(committed, committedEntry{OutboxEntry: entry, tCommit: tCommit})
+		case config.ABORTED:
+			if err := r.outbox.Remove(entry.TxnId); err != nil {
+				return err
+			}
+		default:
+			watermark = min(watermark, entry.StartTime)
+			// abort the transactions abandoned by their coordinators,
+			// the outcome is read again in the next flush
+			abandoned, err := r.abandoned(entry)
+			if err != nil {
+				return err
+			}
+			if abandoned {
+				r.groupKeys.CreateGroupKey(entry.GroupKeyUrls, config.ABORTED)
+			}
+		}
+	}
+	for txnId := range r.resolvedAt {
+		if !pending[txnId] {
+			delete(r.resolvedAt, txnId)
+		}
+	}
+
+	slices.SortFunc(committed, func(a, b committedEntry) int {
+		return cmp.Or(cmp.Compare(a.tCommit, b.tCommit), cmp.Compare(a.TxnId, b.TxnId))
+	})
+	ready := make([]committedEntry, 0, len(committed))
+	for _, entry := range committed {
+		if entry.tCommit >= watermark || time.Since(r.resolvedAt[entry.TxnId]) < r.delay {
+			// the later entries have to wait for this one
+			break
+		}
+		ready = append(ready, entry)
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+
+	var events []ChangeEvent
+	for _, entry := range ready {
+		if entry.tCommit < r.lastTCommit {
+			logger.Log.Warnw("change published out of order",
+				"txnId", entry.TxnId, "tCommit", entry.tCommit, "lastTCommit", r.lastTCommit)
+		}
+		r.lastTCommit = max(r.lastTCommit, entry.tCommit)
+		for _, change := range entry.Changes {
+			events = append(events, ChangeEvent{
+				TxnId:     entry.TxnId,
+				Datastore: change.Datastore,
+				Key:       change.Key,
+				Value:     change.Value,
+				IsDeleted: change.IsDeleted,
+				Deltas:    change.Deltas,
+				TCommit:   entry.tCommit,
+			})
+		}
+	}
+	if err := r.sink.Publish(events); err != nil {
+		return err
+	}
+	for _, entry := range ready {
+		if err := r.outbox.Remove(entry.TxnId); err != nil {
+			return err
+		}
+		delete(r.resolvedAt, entry.TxnId)
+	}
+	return nil
+}
+
+// resolve returns the outcome of the transaction of entry from its group keys.
+// The state is STARTED if the outcome is not decided yet.
+// The commit time is the largest TCommit of the group keys, or the start time
+// of the transaction if the group keys do not carry one.
+func (r *Relay) resolve(entry txn.OutboxEntry) (config.State, int64, error) {
+	if len(entry.GroupKeyUrls) == 0 {
+		// the transaction has no writes
+		return config.ABORTED, 0, nil
+	}
+	tCommit := entry.StartTime
+	decided := true
+	for _, url := range entry.GroupKeyUrls {
+		groupKey, err := r.groupKeys.GetSingleGroupKey(url)
+		if err != nil {
+			if errors.Is(err, txn.KeyNotFound) {
+				decided = false
+				continue
+			}
+			return config.STARTED, 0, err
+		}
+		if groupKey.TxnState == config.ABORTED {
+			return config.ABORTED, 0, nil
+		}
+		tCommit = max(tCommit, groupKey.TCommit)
+	}
+	if !decided {
+		return config.STARTED, 0, nil
+	}
+	return config.COMMITTED, tCommit, nil
+}
+
+// abandoned reports whether the undecided transaction of entry can be
+// aborted, following the rules of the readers: the leases of its prepared
+// records have expired. If none of its records is written yet, the
+// coordinator is given config.Config.LeaseTime from the append to prepare
+// them.
+func (r *Relay) abandoned(entry txn.OutboxEntry) (bool, error) {
+	keys := make(map[string][]string)
+	for _, change := range entry.Changes {
+		keys[change.Datastore] = append(keys[change.Datastore], change.Key)
+	}
+	var tLease time.Time
+	written := false
+	for dsName, dsKeys := range keys {
+		conn, ok := r.conns[dsName]
+		if !ok {
+			return false, errors.Errorf("datastore %s not found", dsName)
+		}
+		lease, found, err := txn.PreparedLease(conn, entry.TxnId, dsKeys)
+		if err != nil {
+			return false, err
+		}
+		written = written || found
+		if lease.After(tLease) {
+			tLease = lease
+		}
+	}
+	if !written {
+		return config.Config.LeaseClock.Now().Sub(entry.CreatedAt) > config.Config.LeaseTime, nil
+	}
+	return txn.LeaseExpired(tLease), nil
+}
@@ -0,0 +1,61 @@
+package cdc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayAbortsByLease(t *testing.T) {
+	conn := mock.NewMockMemoryConnection()
+	outbox := NewMemoryOutbox()
+	relay := NewRelay(outbox, NewChanSink(10), 0, bolt.NewBoltDatastore("mem", conn))
+	now := config.Config.LeaseClock.Now()
+	appendEntry := func(txnId string, createdAt time.Time) {
+		assert.NoError(t, outbox.Append(txn.OutboxEntry{
+			TxnId:        txnId,
+			GroupKeyUrls: []string{"mem:" + txnId},
+			Changes:      []txn.Change{{Datastore: "mem", Key: txnId + "-key", Value: "v"}},
+			CreatedAt:    createdAt,
+		}))
+	}
+	prepare := func(txnId string, tLease time.Time) {
+		_, err := conn.PutItem(txnId+"-key", bolt.NewBoltItem(txn.ItemOptions{
+			Key:          txnId + "-key",
+			Value:        "v",
+			GroupKeyList: "mem:" + txnId,
+			TxnState:     config.PREPARED,
+			TLease:       tLease,
+			LinkedLen:    1,
+		}))
+		assert.NoError(t, err)
+	}
+	pending := func() []string {
+		entries, err := outbox.Pending()
+		assert.NoError(t, err)
+		var txnIds []string
+		for _, entry := range entries {
+			txnIds = append(txnIds, entry.TxnId)
+		}
+		return txnIds
+	}
+
+	// the leases of the records are renewed past the lease time of the entry
+	appendEntry("renewed", now.Add(-time.Hour))
+	prepare("renewed", now.Add(time.Hour))
+	// the leases of the records have expired
+	appendEntry("expired", now)
+	prepare("expired", now.Add(-time.Second))
+	// the records are not prepared yet
+	appendEntry("preparing", now)
+	appendEntry("abandoned", now.Add(-time.Hour))
+
+	assert.NoError(t, relay.Flush())
+	assert.NoError(t, relay.Flush())
+	assert.ElementsMatch(t, []string{"renewed", "preparing"}, pending())
+}
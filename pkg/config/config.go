@@ -15,6 +15,8 @@ type Mode string
 
 type ReadStrategy string
 
+type IsolationLevel string
+
 const (
 	REMOTE Mode = "remote"
 	LOCAL  Mode = "local"
@@ -47,6 +49,13 @@ const (
 	Pessimistic  ReadStrategy = "pessimistic"
 	AssumeCommit ReadStrategy = "commit"
 	AssumeAbort  ReadStrategy = "abort"
+
+	// SnapshotIsolation validates the write set only,
+	// so write skew across keys that were read but not written is allowed.
+	SnapshotIsolation IsolationLevel = "si"
+
+	// Serializable additionally validates the read set at prepare time.
+	Serializable IsolationLevel = "serializable"
 )
 
 type debug struct {
@@ -66,15 +75,48 @@ type debug struct {
 	AssumptionCount int32
 }
 
+// Clock is the source of the time that leases are stamped with and checked against.
+type Clock interface {
+	Now() time.Time
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+// WallClock is the Clock reading the local wall clock.
+var WallClock Clock = wallClock{}
+
 type config struct {
 	// Mode specifies the mode of the transaction.
 	// It can be either REMOTE or LOCAL.
 	Mode Mode
 
 	// LeaseTime specifies the duration of time for which a record is leased.
+	// A coordinator renews the leases of its prepared records every third of
+	// the lease time until the outcome of its transaction is decided.
+	// It can be overridden per transaction by Transaction.SetLeaseTime.
 	LeaseTime time.Duration
 
+	// LeaseClock is the clock that leases are stamped with by coordinators
+	// and checked against by readers. All the nodes have to use clocks
+	// on the same timeline, such as timesource.OracleClock, otherwise
+	// clock skew shortens or lengthens the leases seen by other nodes.
+	LeaseClock Clock
+
+	// ClockSkewThreshold is the clock offset between two nodes
+	// above which a warning is logged. Zero disables the warnings.
+	ClockSkewThreshold time.Duration
+
+	// ExpiryGracePeriod is how long the records written with a TTL are kept
+	// after they expire, so the snapshots started before their expiry can
+	// still read them. It has to cover the longest transactions.
+	ExpiryGracePeriod time.Duration
+
 	// MaxRecordLength specifies the maximum length of a linked record.
+	// It is ignored once a retention policy is set by txn.SetRetentionPolicy.
 	MaxRecordLength int
 
 	// IdGenerator generates unique IDs for records.
@@ -99,10 +141,25 @@ type config struct {
 	ReadStrategy ReadStrategy
 
 	AblationLevel int
+
+	// MaxClockUncertainty specifies the maximum clock offset between any two nodes
+	// when timestamps come from local clocks instead of the time oracle.
+	// A non-zero value enables commit-wait on coordinators and read-restart
+	// for versions that fall in the uncertainty window. Zero disables both.
+	MaxClockUncertainty time.Duration
+
+	// IsolationLevel specifies the isolation level of transactions.
+	// With Serializable, the versions read from a datastore are validated
+	// after the writes to the same datastore are prepared, and the transaction
+	// aborts if any of them has been overwritten in the meantime.
+	IsolationLevel IsolationLevel
 }
 
 var Config = config{
 	LeaseTime:                   1000 * time.Millisecond,
+	LeaseClock:                  WallClock,
+	ClockSkewThreshold:          50 * time.Millisecond,
+	ExpiryGracePeriod:           time.Minute,
 	MaxRecordLength:             2,
 	IdGenerator:                 generator.NewUUIDGenerator(),
 	Serializer:                  serializer.NewJSON2Serializer(),
@@ -112,6 +169,8 @@ var Config = config{
 	MaxOutstandingRequest:       5,
 	ReadStrategy:                Pessimistic,
 	AblationLevel:               4,
+	MaxClockUncertainty:         0,
+	IsolationLevel:              SnapshotIsolation,
 }
 
 var Debug = debug{
@@ -0,0 +1,419 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var _ txn.BatchConnector = (*BoltConnection)(nil)
+var _ txn.KeyScanner = (*BoltConnection)(nil)
+
+var (
+	// itemBucket holds the DataItems written by GetItem, PutItem,
+	// ConditionalUpdate and ConditionalCommit.
+	itemBucket = []byte("items")
+	// kvBucket holds the plain values written by Put and AtomicCreate,
+	// such as the group keys.
+	kvBucket = []byte("kv")
+)
+
+// BoltConnection is an embedded, on-disk connector backed by a bbolt file.
+// bbolt serializes the read-write transactions, so the conditional
+// operations are atomic without any extra locking.
+//
+// A bbolt file can only be opened by one process at a time,
+// so the connection cannot be shared between processes.
+type BoltConnection struct {
+	db     *bbolt.DB
+	config ConnectionOptions
+
+	mu           sync.Mutex
+	hasConnected bool
+}
+
+type ConnectionOptions struct {
+	// Path is the path of the bbolt file, which is created if it does not exist.
+	Path string
+	// Timeout is how long Connect waits for the file lock.
+	// Zero waits forever.
+	Timeout time.Duration
+	// NoSync skips the fsync after each commit, trading durability for speed.
+	NoSync bool
+}
+
+func NewBoltConnection(config *ConnectionOptions) *BoltConnection {
+	if config == nil {
+		config = &ConnectionOptions{
+			Path:    "oreo.db",
+			Timeout: time.Second,
+		}
+	}
+	return &BoltConnection{
+		config:       *config,
+		hasConnected: false,
+	}
+}
+
+func (c *BoltConnection) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hasConnected {
+		return nil
+	}
+
+	db, err := bbolt.Open(c.config.Path, 0o600, &bbolt.Options{
+		Timeout: c.config.Timeout,
+		NoSync:  c.config.NoSync,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open bolt file %s: %v", c.config.Path, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{itemBucket, kvBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return err
+	}
+	c.db = db
+	c.hasConnected = true
+	return nil
+}
+
+// Close closes the bbolt file, releasing its lock.
+func (c *BoltConnection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.hasConnected {
+		return nil
+	}
+	c.hasConnected = false
+	return c.db.Close()
+}
+
+// GetAddress returns the path of the bbolt file.
+func (c *BoltConnection) GetAddress() string {
+	return c.config.Path
+}
+
+func (c *BoltConnection) checkConnected() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.hasConnected {
+		return fmt.Errorf("not connected to bolt")
+	}
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+	return nil
+}
+
+func getItem(tx *bbolt.Tx, key string) (*BoltItem, error) {
+	data := tx.Bucket(itemBucket).Get([]byte(key))
+	if data == nil {
+		return nil, errors.New(txn.KeyNotFound)
+	}
+	var item BoltItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, errors.New("failed to unmarshal item")
+	}
+	return &item, nil
+}
+
+func putItem(tx *bbolt.Tx, key string, value txn.DataItem) error {
+	data, err := json.Marshal(toBoltItem(value))
+	if err != nil {
+		return errors.New("failed to marshal item")
+	}
+	return tx.Bucket(itemBucket).Put([]byte(key), data)
+}
+
+// toBoltItem copies any DataItem into a BoltItem,
+// so items created by other factories can be stored as well.
+func toBoltItem(value txn.DataItem) *BoltItem {
+	if item, ok := value.(*BoltItem); ok {
+		return item
+	}
+	item := &BoltItem{
+		BKey:          value.Key(),
+		BValue:        value.Value(),
+		BGroupKeyList: value.GroupKeyList(),
+		BTxnState:     value.TxnState(),
+		BTValid:       value.TValid(),
+		BTLease:       value.TLease(),
+		BPrev:         value.Prev(),
+		BLinkedLen:    value.LinkedLen(),
+		BIsDeleted:    value.IsDeleted(),
+		BVersion:      value.Version(),
+	}
+	if it, ok := value.(txn.ExpiringItem); ok {
+		item.BTExpire = it.TExpire()
+	}
+	return item
+}
+
+func (c *BoltConnection) GetItem(key string) (txn.DataItem, error) {
+	if err := c.checkConnected(); err != nil {
+		return &BoltItem{}, err
+	}
+
+	var item *BoltItem
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		item, err = getItem(tx, key)
+		return err
+	})
+	if err != nil {
+		return &BoltItem{}, err
+	}
+	return item, nil
+}
+
+func (c *BoltConnection) PutItem(key string, value txn.DataItem) (string, error) {
+	if err := c.checkConnected(); err != nil {
+		return "", err
+	}
+
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		return putItem(tx, key, value)
+	})
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("PutItem key %s failed, err: %v", key, err))
+	}
+	return value.Version(), nil
+}
+
+// ConditionalUpdate writes value if the version of the stored item matches
+// the version of value, or if doCreate is set and the item does not exist.
+// It returns the new version, which is the old one plus one.
+func (c *BoltConnection) ConditionalUpdate(
+	key string,
+	value txn.DataItem,
+	doCreate bool,
+) (string, error) {
+	if err := c.checkConnected(); err != nil {
+		return "", err
+	}
+
+	var newVer string
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		newVer, err = conditionalUpdate(tx, key, value, doCreate)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return newVer, nil
+}
+
+func conditionalUpdate(
+	tx *bbolt.Tx,
+	key string,
+	value txn.DataItem,
+	doCreate bool,
+) (string, error) {
+	old, err := getItem(tx, key)
+	switch {
+	case err != nil && !errors.Is(err, txn.KeyNotFound):
+		return "", err
+	case doCreate && old != nil:
+		return "", errors.New(txn.VersionMismatch)
+	case !doCreate && (old == nil || old.Version() != value.Version()):
+		return "", errors.New(txn.VersionMismatch)
+	}
+
+	item := *toBoltItem(value)
+	item.BVersion = util.AddToString(value.Version(), 1)
+	if err := putItem(tx, key, &item); err != nil {
+		return "", err
+	}
+	return item.BVersion, nil
+}
+
+// ConditionalUpdateBatch performs the conditional updates in one bbolt
+// transaction, so they are written with a single fsync.
+// A failed update does not affect the others.
+func (c *BoltConnection) ConditionalUpdateBatch(ops []txn.UpdateOp) []txn.BatchResult {
+	results := make([]txn.BatchResult, len(ops))
+	err := c.checkConnected()
+	if err == nil {
+		err = c.db.Update(func(tx *bbolt.Tx) error {
+			for i, op := range ops {
+				ver, err := conditionalUpdate(tx, op.Key, op.Value, op.DoCreate)
+				results[i] = txn.BatchResult{Version: ver, Err: err}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		for i := range results {
+			results[i] = txn.BatchResult{Err: err}
+		}
+	}
+	return results
+}
+
+// ConditionalCommit marks the item as COMMITTED at tCommit
+// if its version matches version, and returns the new version.
+func (c *BoltConnection) ConditionalCommit(
+	key string,
+	version string,
+	tCommit int64,
+) (string, error) {
+	if err := c.checkConnected(); err != nil {
+		return "", err
+	}
+
+	var newVer string
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		newVer, err = conditionalCommit(tx, key, version, tCommit)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return newVer, nil
+}
+
+func conditionalCommit(tx *bbolt.Tx, key string, version string, tCommit int64) (string, error) {
+	item, err := getItem(tx, key)
+	if err != nil {
+		return "", err
+	}
+	if item.Version() != version {
+		return "", errors.New(txn.VersionMismatch)
+	}
+	item.BTxnState = config.COMMITTED
+	item.BTValid = tCommit
+	item.BVersion = util.AddToString(version, 1)
+	if err := putItem(tx, key, item); err != nil {
+		return "", err
+	}
+	return item.BVersion, nil
+}
+
+// ConditionalCommitBatch performs the conditional commits in one bbolt transaction.
+func (c *BoltConnection) ConditionalCommitBatch(
+	infos []txn.CommitInfo,
+	tCommit int64,
+) []txn.BatchResult {
+	results := make([]txn.BatchResult, len(infos))
+	err := c.checkConnected()
+	if err == nil {
+		err = c.db.Update(func(tx *bbolt.Tx) error {
+			for i, info := range infos {
+				ver, err := conditionalCommit(tx, info.Key, info.Version, tCommit)
+				results[i] = txn.BatchResult{Version: ver, Err: err}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		for i := range results {
+			results[i] = txn.BatchResult{Err: err}
+		}
+	}
+	return results
+}
+
+func (c *BoltConnection) AtomicCreate(name string, value any) (string, error) {
+	if err := c.checkConnected(); err != nil {
+		return "", err
+	}
+
+	var old string
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(kvBucket)
+		if data := bucket.Get([]byte(name)); data != nil {
+			old = string(data)
+			return errors.New(txn.KeyExists)
+		}
+		return bucket.Put([]byte(name), []byte(util.ToString(value)))
+	})
+	if err != nil {
+		return old, err
+	}
+	return "", nil
+}
+
+func (c *BoltConnection) Get(name string) (string, error) {
+	if err := c.checkConnected(); err != nil {
+		return "", err
+	}
+
+	var value string
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(kvBucket).Get([]byte(name))
+		if data == nil {
+			return errors.New(txn.KeyNotFound)
+		}
+		value = string(data)
+		return nil
+	})
+	return value, err
+}
+
+func (c *BoltConnection) Put(name string, value any) error {
+	if err := c.checkConnected(); err != nil {
+		return err
+	}
+
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(kvBucket).Put([]byte(name), []byte(util.ToString(value)))
+	})
+}
+
+// Delete removes name from both the items and the plain values.
+func (c *BoltConnection) Delete(name string) error {
+	if err := c.checkConnected(); err != nil {
+		return err
+	}
+
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(itemBucket).Delete([]byte(name)); err != nil {
+			return err
+		}
+		return tx.Bucket(kvBucket).Delete([]byte(name))
+	})
+}
+
+// ScanKeys calls fn with the key of every item. The keys are collected
+// first, so fn may use the connection, and the items written meanwhile
+// may be missed.
+func (c *BoltConnection) ScanKeys(fn func(key string) error) error {
+	if err := c.checkConnected(); err != nil {
+		return err
+	}
+
+	var keys []string
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(itemBucket).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
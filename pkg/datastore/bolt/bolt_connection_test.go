@@ -0,0 +1,67 @@
+package bolt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/kkkzoz/oreo/pkg/txn/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+type boltTestSuiteHelper struct{}
+
+func (h *boltTestSuiteHelper) MakeItem(ops txn.ItemOptions) txn.DataItem {
+	return &BoltItem{
+		BKey:          ops.Key,
+		BValue:        util.ToJSONString(ops.Value),
+		BGroupKeyList: ops.GroupKeyList,
+		BTxnState:     ops.TxnState,
+		BTValid:       ops.TValid,
+		BTLease:       ops.TLease,
+		BPrev:         ops.Prev,
+		BIsDeleted:    ops.IsDeleted,
+		BVersion:      ops.Version,
+	}
+}
+
+func (h *boltTestSuiteHelper) NewInstance() txn.DataItem {
+	return &BoltItem{}
+}
+
+func newTestBoltConnection(t *testing.T) *BoltConnection {
+	conn := NewBoltConnection(&ConnectionOptions{
+		Path: filepath.Join(t.TempDir(), "oreo.db"),
+	})
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Could not open bolt: %s", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestBoltConnector_InterfaceSuite(t *testing.T) {
+	testsuite.TestConnectorSuite(t, newTestBoltConnection(t), &boltTestSuiteHelper{})
+}
+
+func TestBoltConnectionReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oreo.db")
+	conn := NewBoltConnection(&ConnectionOptions{Path: path})
+	assert.NoError(t, conn.Connect())
+	ver, err := conn.ConditionalUpdate("key", &BoltItem{BKey: "key", BValue: "value"}, true)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Put("groupKey", "committed"))
+	assert.NoError(t, conn.Close())
+
+	conn = NewBoltConnection(&ConnectionOptions{Path: path})
+	assert.NoError(t, conn.Connect())
+	defer conn.Close()
+	item, err := conn.GetItem("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", item.Value())
+	assert.Equal(t, ver, item.Version())
+	value, err := conn.Get("groupKey")
+	assert.NoError(t, err)
+	assert.Equal(t, "committed", value)
+}
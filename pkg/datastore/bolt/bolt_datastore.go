@@ -0,0 +1,7 @@
+package bolt
+
+import "github.com/kkkzoz/oreo/pkg/txn"
+
+func NewBoltDatastore(name string, conn txn.Connector) txn.Datastorer {
+	return txn.NewDatastore(name, conn, &BoltItemFactory{})
+}
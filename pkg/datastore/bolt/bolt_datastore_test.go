@@ -0,0 +1,117 @@
+package bolt
+
+import (
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTransaction(conns map[string]*BoltConnection) *txn.Transaction {
+	tx := txn.NewTransaction()
+	for name, conn := range conns {
+		tx.AddDatastore(NewBoltDatastore(name, conn))
+	}
+	return tx
+}
+
+func TestBoltTransactionAcrossDatastores(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*BoltConnection{
+		"bolt1": newTestBoltConnection(t),
+		"bolt2": newTestBoltConnection(t),
+	}
+
+	txn1 := newTestTransaction(conns)
+	assert.NoError(t, txn1.Start())
+	person := testutil.NewDefaultPerson()
+	assert.NoError(t, txn1.Write("bolt1", "person", person))
+	assert.NoError(t, txn1.Write("bolt2", "item", testutil.NewTestItem("item")))
+	assert.NoError(t, txn1.Commit())
+
+	txn2 := newTestTransaction(conns)
+	assert.NoError(t, txn2.Start())
+	var gotPerson testutil.Person
+	assert.NoError(t, txn2.Read("bolt1", "person", &gotPerson))
+	assert.Equal(t, person, gotPerson)
+	var gotItem testutil.TestItem
+	assert.NoError(t, txn2.Read("bolt2", "item", &gotItem))
+	assert.Equal(t, testutil.NewTestItem("item"), gotItem)
+	assert.NoError(t, txn2.Commit())
+}
+
+func TestBoltTransactionConflict(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*BoltConnection{"bolt": newTestBoltConnection(t)}
+
+	init := newTestTransaction(conns)
+	assert.NoError(t, init.Start())
+	assert.NoError(t, init.Write("bolt", "key", "v0"))
+	assert.NoError(t, init.Commit())
+
+	txn1 := newTestTransaction(conns)
+	txn2 := newTestTransaction(conns)
+	assert.NoError(t, txn1.Start())
+	assert.NoError(t, txn2.Start())
+	var value string
+	assert.NoError(t, txn1.Read("bolt", "key", &value))
+	assert.NoError(t, txn2.Read("bolt", "key", &value))
+	assert.NoError(t, txn1.Write("bolt", "key", "v1"))
+	assert.NoError(t, txn2.Write("bolt", "key", "v2"))
+	assert.NoError(t, txn1.Commit())
+	assert.Error(t, txn2.Commit())
+
+	txn3 := newTestTransaction(conns)
+	assert.NoError(t, txn3.Start())
+	assert.NoError(t, txn3.Read("bolt", "key", &value))
+	assert.Equal(t, "v1", value)
+}
+
+func TestBoltTransactionPreparesInBatch(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+	config.Config.ConcurrentOptimizationLevel = config.PARALLELIZE_ON_UPDATE
+	defer func() { config.Config.ConcurrentOptimizationLevel = config.DEFAULT }()
+
+	conns := map[string]*BoltConnection{"bolt": newTestBoltConnection(t)}
+	keys := []string{"key1", "key2", "key3", "key4", "key5"}
+
+	txn1 := newTestTransaction(conns)
+	assert.NoError(t, txn1.Start())
+	for _, key := range keys {
+		assert.NoError(t, txn1.Write("bolt", key, key+"-v1"))
+	}
+	assert.NoError(t, txn1.Commit())
+
+	txn2 := newTestTransaction(conns)
+	assert.NoError(t, txn2.Start())
+	var value string
+	for _, key := range keys {
+		assert.NoError(t, txn2.Read("bolt", key, &value))
+		assert.Equal(t, key+"-v1", value)
+		assert.NoError(t, txn2.Write("bolt", key, key+"-v2"))
+	}
+	assert.NoError(t, txn2.Commit())
+
+	// one of the records is updated after txn3 reads it
+	txn3 := newTestTransaction(conns)
+	txn4 := newTestTransaction(conns)
+	assert.NoError(t, txn3.Start())
+	assert.NoError(t, txn4.Start())
+	for _, key := range keys {
+		assert.NoError(t, txn3.Read("bolt", key, &value))
+		assert.NoError(t, txn3.Write("bolt", key, key+"-v3"))
+	}
+	assert.NoError(t, txn4.Read("bolt", "key3", &value))
+	assert.NoError(t, txn4.Write("bolt", "key3", "key3-v4"))
+	assert.NoError(t, txn4.Commit())
+	assert.Error(t, txn3.Commit())
+
+	txn5 := newTestTransaction(conns)
+	assert.NoError(t, txn5.Start())
+	assert.NoError(t, txn5.Read("bolt", "key3", &value))
+	assert.Equal(t, "key3-v4", value)
+}
@@ -0,0 +1,173 @@
+package bolt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+var _ txn.DataItem = (*BoltItem)(nil)
+var _ txn.ExpiringItem = (*BoltItem)(nil)
+
+type BoltItem struct {
+	BKey          string       `json:"Key"`
+	BValue        string       `json:"Value"`
+	BGroupKeyList string       `json:"GroupKeyList"`
+	BTxnState     config.State `json:"State"`
+	BTValid       int64        `json:"TValid"`
+	BTLease       time.Time    `json:"TLease"`
+	BPrev         string       `json:"Prev"`
+	BLinkedLen    int          `json:"LinkedLen"`
+	BIsDeleted    bool         `json:"IsDeleted"`
+	BVersion      string       `json:"Version,omitempty"`
+	BTExpire      time.Time    `json:"TExpire,omitzero"`
+}
+
+func NewBoltItem(options txn.ItemOptions) *BoltItem {
+	if options.Value == nil {
+		options.Value = ""
+	}
+
+	return &BoltItem{
+		BKey:          options.Key,
+		BValue:        options.Value.(string),
+		BGroupKeyList: options.GroupKeyList,
+		BTxnState:     options.TxnState,
+		BTValid:       options.TValid,
+		BTLease:       options.TLease,
+		BPrev:         options.Prev,
+		BLinkedLen:    options.LinkedLen,
+		BIsDeleted:    options.IsDeleted,
+		BVersion:      options.Version,
+	}
+}
+
+func (b *BoltItem) Key() string {
+	return b.BKey
+}
+
+func (b *BoltItem) Value() string {
+	return b.BValue
+}
+
+func (b *BoltItem) SetValue(value string) {
+	b.BValue = value
+}
+
+func (b *BoltItem) GroupKeyList() string {
+	return b.BGroupKeyList
+}
+
+func (b *BoltItem) SetGroupKeyList(groupKeyList string) {
+	b.BGroupKeyList = groupKeyList
+}
+
+func (b *BoltItem) TxnState() config.State {
+	return b.BTxnState
+}
+
+func (b *BoltItem) SetTxnState(state config.State) {
+	b.BTxnState = state
+}
+
+func (b *BoltItem) TValid() int64 {
+	return b.BTValid
+}
+
+func (b *BoltItem) SetTValid(tValid int64) {
+	b.BTValid = tValid
+}
+
+func (b *BoltItem) TLease() time.Time {
+	return b.BTLease
+}
+
+func (b *BoltItem) SetTLease(tLease time.Time) {
+	b.BTLease = tLease
+}
+
+func (b *BoltItem) Prev() string {
+	return b.BPrev
+}
+
+func (b *BoltItem) SetPrev(prev string) {
+	b.BPrev = prev
+}
+
+func (b *BoltItem) LinkedLen() int {
+	return b.BLinkedLen
+}
+
+func (b *BoltItem) SetLinkedLen(linkedLen int) {
+	b.BLinkedLen = linkedLen
+}
+
+func (b *BoltItem) IsDeleted() bool {
+	return b.BIsDeleted
+}
+
+func (b *BoltItem) SetIsDeleted(isDeleted bool) {
+	b.BIsDeleted = isDeleted
+}
+
+func (b *BoltItem) Version() string {
+	return b.BVersion
+}
+
+func (b *BoltItem) SetVersion(version string) {
+	b.BVersion = version
+}
+
+func (b *BoltItem) TExpire() time.Time {
+	return b.BTExpire
+}
+
+func (b *BoltItem) SetTExpire(tExpire time.Time) {
+	b.BTExpire = tExpire
+}
+
+func (b *BoltItem) Equal(other txn.DataItem) bool {
+	if other == nil {
+		return false
+	}
+	otherItem, ok := other.(*BoltItem)
+	if !ok {
+		return false
+	}
+
+	return b.Key() == otherItem.Key() &&
+		b.Value() == otherItem.Value() &&
+		b.GroupKeyList() == otherItem.GroupKeyList() &&
+		b.TxnState() == otherItem.TxnState() &&
+		b.TValid() == otherItem.TValid() &&
+		b.TLease().Equal(otherItem.TLease()) &&
+		b.Prev() == otherItem.Prev() &&
+		b.LinkedLen() == otherItem.LinkedLen() &&
+		b.IsDeleted() == otherItem.IsDeleted() &&
+		b.Version() == otherItem.Version() &&
+		b.TExpire().Equal(otherItem.TExpire())
+}
+
+func (b *BoltItem) Empty() bool {
+	return b == nil || (b.Key() == "" && b.Value() == "")
+}
+
+func (b *BoltItem) String() string {
+	return fmt.Sprintf(`BoltItem{
+    Key:       %s,
+    Value:     %s,
+    GroupKeyList:     %s,
+    TxnState:  %s,
+    TValid:    %v,
+    TLease:    %s,
+    Prev:      %s,
+    LinkedLen: %d,
+    IsDeleted: %v,
+    Version:   %s,
+}`, b.BKey, b.BValue, b.BGroupKeyList, util.ToString(b.BTxnState),
+		b.BTValid, b.BTLease.Format(time.RFC3339),
+		b.BPrev, b.BLinkedLen, b.BIsDeleted, b.BVersion)
+}
@@ -0,0 +1,11 @@
+package bolt
+
+import "github.com/kkkzoz/oreo/pkg/txn"
+
+var _ txn.DataItemFactory = (*BoltItemFactory)(nil)
+
+type BoltItemFactory struct{}
+
+func (b *BoltItemFactory) NewDataItem(options txn.ItemOptions) txn.DataItem {
+	return NewBoltItem(options)
+}
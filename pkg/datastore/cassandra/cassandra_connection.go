@@ -2,6 +2,7 @@ package cassandra
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -12,8 +13,11 @@ import (
 	"github.com/kkkzoz/oreo/pkg/txn"
 )
 
-var _ txn.Connector = (*CassandraConnection)(nil)
+var _ txn.Expirer = (*CassandraConnection)(nil)
 
+// CassandraConnection is not a txn.BatchConnector: Cassandra only accepts a
+// batch of lightweight transactions that touch a single partition, and every
+// key is a partition of its own.
 type CassandraConnection struct {
 	session      *gocql.Session
 	config       ConnectionOptions
@@ -111,13 +115,14 @@ func (c *CassandraConnection) GetItem(key string) (txn.DataItem, error) {
 
 	var item CassandraItem
 	err := c.session.Query(`
-        SELECT key, value, group_key_list, txn_state, t_valid, t_lease, prev, linked_len, is_deleted, version 
+        SELECT key, value, group_key_list, txn_state, t_valid, t_lease, prev, linked_len, is_deleted, version, t_expire 
         FROM items WHERE key = ?`, key).Scan(
 		&item.CKey, &item.CValue, &item.CGroupKeyList, &item.CTxnState,
 		&item.CTValid, &item.CTLease, &item.CPrev, &item.CLinkedLen,
-		&item.CIsDeleted, &item.CVersion)
+		&item.CIsDeleted, &item.CVersion, &item.CTExpire)
 
-	if err == gocql.ErrNotFound {
+	// the columns of a row expired by ExpireAt are null
+	if err == gocql.ErrNotFound || (err == nil && item.CVersion == "") {
 		return &CassandraItem{}, errors.New(txn.KeyNotFound)
 	}
 	if err != nil {
@@ -141,11 +146,11 @@ func (c *CassandraConnection) PutItem(key string, value txn.DataItem) (string, e
 	}
 
 	err := c.session.Query(`
-        INSERT INTO items (key, value, group_key_list, txn_state, t_valid, t_lease, prev, linked_len, is_deleted, version)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+        INSERT INTO items (key, value, group_key_list, txn_state, t_valid, t_lease, prev, linked_len, is_deleted, version, t_expire)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		key, item.CValue, item.CGroupKeyList, item.CTxnState,
 		item.CTValid, item.CTLease, item.CPrev, item.CLinkedLen,
-		item.CIsDeleted, item.CVersion).Exec()
+		item.CIsDeleted, item.CVersion, item.CTExpire).Exec()
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("PutItem key %s failed, err: %v", key, err))
 	}
@@ -175,12 +180,12 @@ func (c *CassandraConnection) ConditionalUpdate(
 
 		// 使用 Cassandra 的轻量级事务(LWT)确保原子性
 		applied, err := c.session.Query(`
-            INSERT INTO items (key, value, group_key_list, txn_state, t_valid, t_lease, prev, linked_len, is_deleted, version)
-            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            INSERT INTO items (key, value, group_key_list, txn_state, t_valid, t_lease, prev, linked_len, is_deleted, version, t_expire)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
             IF NOT EXISTS`,
 			key, value.Value(), value.GroupKeyList(), value.TxnState(),
 			value.TValid(), value.TLease(), value.Prev(), value.LinkedLen(),
-			value.IsDeleted(), newVer).ScanCAS()
+			value.IsDeleted(), newVer, expiryOf(value)).ScanCAS()
 		if err != nil {
 			return "", errors.New(
 				fmt.Sprintf("ConditionalUpdate(doCreate) key %s failed, err: %v", key, err),
@@ -193,15 +198,16 @@ func (c *CassandraConnection) ConditionalUpdate(
 	}
 
 	// 更新现有记录，使用 LWT 确保版本匹配
+	// rewriting every column without a TTL clears the one set by ExpireAt
 	applied, err := c.session.Query(`
         UPDATE items 
         SET value = ?, group_key_list = ?, txn_state = ?, t_valid = ?, 
-            t_lease = ?, prev = ?, linked_len = ?, is_deleted = ?, version = ?
+            t_lease = ?, prev = ?, linked_len = ?, is_deleted = ?, version = ?, t_expire = ?
         WHERE key = ?
         IF version = ?`,
 		value.Value(), value.GroupKeyList(), value.TxnState(), value.TValid(),
 		value.TLease(), value.Prev(), value.LinkedLen(), value.IsDeleted(),
-		newVer, key, value.Version()).ScanCAS()
+		newVer, expiryOf(value), key, value.Version()).ScanCAS()
 	// gocql: not enough columns to scan into: have 1 want 2
 	// this is ok because it only occurs when the conditional update fails
 	if err != nil {
@@ -243,6 +249,38 @@ func (c *CassandraConnection) ConditionalCommit(
 	return version, nil
 }
 
+// ExpireAt makes the row of key expire at expireAt, unless it is no longer
+// at version, by rewriting its columns USING TTL. The TTL of Cassandra
+// applies to the columns, not to the row, so GetItem reports the rows whose
+// columns have expired as not found.
+func (c *CassandraConnection) ExpireAt(key string, version string, expireAt time.Time) error {
+	item, err := c.GetItem(key)
+	if err != nil {
+		return err
+	}
+	if item.Version() != version {
+		return errors.New(txn.VersionMismatch)
+	}
+	ttl := max(int(math.Ceil(time.Until(expireAt).Seconds())), 1)
+
+	applied, err := c.session.Query(`
+        UPDATE items USING TTL ?
+        SET value = ?, group_key_list = ?, txn_state = ?, t_valid = ?, 
+            t_lease = ?, prev = ?, linked_len = ?, is_deleted = ?, version = ?, t_expire = ?
+        WHERE key = ?
+        IF version = ?`,
+		ttl, item.Value(), item.GroupKeyList(), item.TxnState(), item.TValid(),
+		item.TLease(), item.Prev(), item.LinkedLen(), item.IsDeleted(),
+		version, expiryOf(item), key, version).ScanCAS()
+	if err != nil {
+		return errors.New(fmt.Sprintf("ExpireAt key %s failed, err: %v", key, err))
+	}
+	if !applied {
+		return errors.New(txn.VersionMismatch)
+	}
+	return nil
+}
+
 // AtomicCreate creates a key-value pair in the 'kv' table if the key does not already exist.
 func (c *CassandraConnection) AtomicCreate(name string, value any) (string, error) {
 	if !c.hasConnected {
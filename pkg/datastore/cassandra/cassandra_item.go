@@ -10,6 +10,7 @@ import (
 )
 
 var _ txn.DataItem = (*CassandraItem)(nil)
+var _ txn.ExpiringItem = (*CassandraItem)(nil)
 
 type CassandraItem struct {
 	// 主键
@@ -32,6 +33,8 @@ type CassandraItem struct {
 	CIsDeleted bool `cql:"is_deleted"     json:"IsDeleted"`
 	// 版本控制 - Cassandra使用时间戳作为版本
 	CVersion string `cql:"version"        json:"Version"`
+	// 过期时间
+	CTExpire time.Time `cql:"t_expire"       json:"TExpire,omitzero"`
 }
 
 // CQL创建表的语句
@@ -47,6 +50,7 @@ CREATE TABLE IF NOT EXISTS items (
     linked_len int,
     is_deleted boolean,
     version text,
+    t_expire timestamp,
     PRIMARY KEY (key)
 ) WITH gc_grace_seconds = 172800`
 
@@ -146,6 +150,14 @@ func (c *CassandraItem) SetVersion(version string) {
 	c.CVersion = version
 }
 
+func (c *CassandraItem) TExpire() time.Time {
+	return c.CTExpire
+}
+
+func (c *CassandraItem) SetTExpire(tExpire time.Time) {
+	c.CTExpire = tExpire
+}
+
 func (c *CassandraItem) Equal(other txn.DataItem) bool {
 	if other == nil {
 		return false
@@ -164,7 +176,16 @@ func (c *CassandraItem) Equal(other txn.DataItem) bool {
 		c.Prev() == otherItem.Prev() &&
 		c.LinkedLen() == otherItem.LinkedLen() &&
 		c.IsDeleted() == otherItem.IsDeleted() &&
-		c.Version() == otherItem.Version()
+		c.Version() == otherItem.Version() &&
+		c.TExpire().Equal(otherItem.TExpire())
+}
+
+// expiryOf returns the expiry of item, or the zero time if it has none.
+func expiryOf(item txn.DataItem) time.Time {
+	if it, ok := item.(txn.ExpiringItem); ok {
+		return it.TExpire()
+	}
+	return time.Time{}
 }
 
 func (c *CassandraItem) Empty() bool {
@@ -16,7 +16,7 @@ import (
 	"github.com/kkkzoz/oreo/pkg/txn"
 )
 
-var _ txn.Connector = (*CouchDBConnection)(nil)
+var _ txn.BatchConnector = (*CouchDBConnection)(nil)
 
 var httpClient = &http.Client{
 	Transport: &http.Transport{
@@ -202,6 +202,143 @@ func (r *CouchDBConnection) ConditionalUpdate(
 	return newVer, nil
 }
 
+// bulkDoc is a document of a _bulk_docs request,
+// which carries its ID along with the item.
+type bulkDoc struct {
+	ID string `json:"_id"`
+	*CouchDBItem
+}
+
+// toCouchDBItem copies any DataItem into a CouchDBItem.
+func toCouchDBItem(value txn.DataItem) *CouchDBItem {
+	if item, ok := value.(*CouchDBItem); ok {
+		return item
+	}
+	return &CouchDBItem{
+		CKey:          value.Key(),
+		CValue:        value.Value(),
+		CGroupKeyList: value.GroupKeyList(),
+		CTxnState:     value.TxnState(),
+		CTValid:       value.TValid(),
+		CTLease:       value.TLease(),
+		CPrev:         value.Prev(),
+		CLinkedLen:    value.LinkedLen(),
+		CIsDeleted:    value.IsDeleted(),
+		CVersion:      value.Version(),
+	}
+}
+
+// bulkDocs writes docs with a single _bulk_docs request
+// into the results at the given indexes.
+// A conflicting document is reported as VersionMismatch.
+func (r *CouchDBConnection) bulkDocs(
+	docs []interface{},
+	indexes []int,
+	results []txn.BatchResult,
+) {
+	if len(docs) == 0 {
+		return
+	}
+	bulkResults, err := r.db.BulkDocs(context.Background(), docs)
+	for j, i := range indexes {
+		switch {
+		case err != nil:
+			results[i].Err = err
+		case j >= len(bulkResults):
+			results[i].Err = errors.Errorf("missing result of _bulk_docs")
+		case kivik.HTTPStatus(bulkResults[j].Error) == http.StatusConflict:
+			results[i].Err = errors.New(txn.VersionMismatch)
+		case bulkResults[j].Error != nil:
+			results[i].Err = bulkResults[j].Error
+		default:
+			results[i].Version = bulkResults[j].Rev
+		}
+	}
+}
+
+// ConditionalUpdateBatch sends the conditional updates in a single _bulk_docs request.
+// As with Put, the revision of each item is checked by CouchDB.
+func (r *CouchDBConnection) ConditionalUpdateBatch(ops []txn.UpdateOp) []txn.BatchResult {
+	results := make([]txn.BatchResult, len(ops))
+	if !r.hasConnected {
+		for i := range results {
+			results[i].Err = fmt.Errorf("not connected to CouchDB")
+		}
+		return results
+	}
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+
+	docs := make([]interface{}, 0, len(ops))
+	indexes := make([]int, 0, len(ops))
+	for i, op := range ops {
+		// the document already exists if the item has a revision
+		if op.DoCreate && op.Value.Version() != "" {
+			results[i].Err = errors.New(txn.VersionMismatch)
+			continue
+		}
+		docs = append(docs, bulkDoc{ID: op.Key, CouchDBItem: toCouchDBItem(op.Value)})
+		indexes = append(indexes, i)
+	}
+	r.bulkDocs(docs, indexes, results)
+	return results
+}
+
+// ConditionalCommitBatch reads the items at the given revisions with a single
+// _bulk_get request, and writes them back as COMMITTED with a single
+// _bulk_docs request, which fails for the items updated in the meantime.
+func (r *CouchDBConnection) ConditionalCommitBatch(
+	infos []txn.CommitInfo,
+	tCommit int64,
+) []txn.BatchResult {
+	results := make([]txn.BatchResult, len(infos))
+	if !r.hasConnected {
+		for i := range results {
+			results[i].Err = fmt.Errorf("not connected to CouchDB")
+		}
+		return results
+	}
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+
+	refs := make([]kivik.BulkGetReference, len(infos))
+	for i, info := range infos {
+		refs[i] = kivik.BulkGetReference{ID: info.Key, Rev: info.Version}
+	}
+	items := make(map[string]*CouchDBItem, len(infos))
+	rs := r.db.BulkGet(context.Background(), refs)
+	for rs.Next() {
+		var item CouchDBItem
+		if err := rs.ScanDoc(&item); err != nil {
+			continue
+		}
+		if id, err := rs.ID(); err == nil {
+			items[id] = &item
+		}
+	}
+	err := rs.Err()
+	_ = rs.Close()
+
+	docs := make([]interface{}, 0, len(infos))
+	indexes := make([]int, 0, len(infos))
+	for i, info := range infos {
+		item, ok := items[info.Key]
+		if err != nil || !ok {
+			results[i].Err = errors.New(txn.VersionMismatch)
+			continue
+		}
+		item.SetTxnState(config.COMMITTED)
+		item.SetTValid(tCommit)
+		item.SetVersion(info.Version)
+		docs = append(docs, bulkDoc{ID: info.Key, CouchDBItem: item})
+		indexes = append(indexes, i)
+	}
+	r.bulkDocs(docs, indexes, results)
+	return results
+}
+
 // ConditionalCommit atomically commits a transaction if the revision matches.
 func (r *CouchDBConnection) ConditionalCommit(
 	key string,
@@ -20,7 +20,8 @@ import (
 	"github.com/kkkzoz/oreo/pkg/txn"
 )
 
-var _ txn.Connector = (*DynamoDBConnection)(nil)
+var _ txn.BatchConnector = (*DynamoDBConnection)(nil)
+var _ txn.Expirer = (*DynamoDBConnection)(nil)
 
 type KeyValueItem struct {
 	ID    string `dynamodbav:"ID"`
@@ -171,34 +172,7 @@ func (d *DynamoDBConnection) ConditionalUpdate(
 	}
 
 	newVer := util.AddToString(value.Version(), 1)
-
-	updateExpr := "SET #val = :val, #gkl = :gkl, #ts = :ts, #tv = :tv, " +
-		"#tl = :tl, #prev = :prev, #ll = :ll, #id = :id, #ver = :ver"
-
-	exprAttrNames := map[string]string{
-		"#val":  "Value",
-		"#gkl":  "GroupKeyList",
-		"#ts":   "TxnState",
-		"#tv":   "TValid",
-		"#tl":   "TLease",
-		"#prev": "Prev",
-		"#ll":   "LinkedLen",
-		"#id":   "IsDeleted",
-		"#ver":  "Version",
-	}
-
-	exprAttrValues := map[string]types.AttributeValue{
-		":val":    &types.AttributeValueMemberS{Value: value.Value()},
-		":gkl":    &types.AttributeValueMemberS{Value: value.GroupKeyList()},
-		":ts":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", value.TxnState())},
-		":tv":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", value.TValid())},
-		":tl":     &types.AttributeValueMemberS{Value: value.TLease().Format(time.RFC3339Nano)},
-		":prev":   &types.AttributeValueMemberS{Value: value.Prev()},
-		":ll":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", value.LinkedLen())},
-		":id":     &types.AttributeValueMemberBOOL{Value: value.IsDeleted()},
-		":ver":    &types.AttributeValueMemberS{Value: newVer},
-		":oldver": &types.AttributeValueMemberS{Value: value.Version()},
-	}
+	updateExpr, exprAttrNames, exprAttrValues := updateExpression(value, newVer)
 
 	_, err := d.client.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
 		TableName: aws.String(d.tableName),
@@ -235,20 +209,7 @@ func (d *DynamoDBConnection) ConditionalCommit(
 	}
 
 	newVer := util.AddToString(version, 1)
-
-	updateExpr := "SET #ts = :ts, #tv = :tv, #ver = :ver"
-	exprAttrNames := map[string]string{
-		"#ts":  "TxnState",
-		"#tv":  "TValid",
-		"#ver": "Version",
-	}
-
-	exprAttrValues := map[string]types.AttributeValue{
-		":ts":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", oreoconfig.COMMITTED)},
-		":tv":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", tCommit)},
-		":ver":    &types.AttributeValueMemberS{Value: newVer},
-		":oldver": &types.AttributeValueMemberS{Value: version},
-	}
+	updateExpr, exprAttrNames, exprAttrValues := commitExpression(version, newVer, tCommit)
 
 	_, err := d.client.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
 		TableName: aws.String(d.tableName),
@@ -271,6 +232,296 @@ func (d *DynamoDBConnection) ConditionalCommit(
 	return newVer, nil
 }
 
+// expireAtAttribute is the attribute holding the expiry of the items
+// in epoch seconds, to be set as the TTL attribute of the table.
+const expireAtAttribute = "ExpireAt"
+
+// ExpireAt makes the item of key expire at expireAt through the TTL
+// attribute of the table, unless it is no longer at version. The TTL
+// has to be enabled on expireAtAttribute, and DynamoDB deletes the
+// expired items within a few days, so they are still collected by
+// txn.CollectExpired meanwhile.
+func (d *DynamoDBConnection) ExpireAt(key string, version string, expireAt time.Time) error {
+	if !d.hasConnected {
+		return errors.Errorf("not connected to DynamoDB")
+	}
+
+	_, err := d.client.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"ID": &types.AttributeValueMemberS{Value: key},
+		},
+		UpdateExpression: aws.String("SET #ea = :ea"),
+		ExpressionAttributeNames: map[string]string{
+			"#ea":  expireAtAttribute,
+			"#ver": "Version",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ea":  &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", expireAt.Unix())},
+			":ver": &types.AttributeValueMemberS{Value: version},
+		},
+		ConditionExpression: aws.String("#ver = :ver"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return errors.New(txn.VersionMismatch)
+		}
+		return err
+	}
+	return nil
+}
+
+// updateExpression returns the expression of the conditional update
+// writing value with newVer.
+func updateExpression(
+	value txn.DataItem,
+	newVer string,
+) (string, map[string]string, map[string]types.AttributeValue) {
+	updateExpr := "SET #val = :val, #gkl = :gkl, #ts = :ts, #tv = :tv, " +
+		"#tl = :tl, #prev = :prev, #ll = :ll, #id = :id, #ver = :ver, #te = :te " +
+		"REMOVE #ea"
+
+	exprAttrNames := map[string]string{
+		"#val":  "Value",
+		"#gkl":  "GroupKeyList",
+		"#ts":   "TxnState",
+		"#tv":   "TValid",
+		"#tl":   "TLease",
+		"#prev": "Prev",
+		"#ll":   "LinkedLen",
+		"#id":   "IsDeleted",
+		"#ver":  "Version",
+		"#te":   "TExpire",
+		"#ea":   expireAtAttribute,
+	}
+
+	exprAttrValues := map[string]types.AttributeValue{
+		":te":     &types.AttributeValueMemberS{Value: expiryOf(value).Format(time.RFC3339Nano)},
+		":val":    &types.AttributeValueMemberS{Value: value.Value()},
+		":gkl":    &types.AttributeValueMemberS{Value: value.GroupKeyList()},
+		":ts":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", value.TxnState())},
+		":tv":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", value.TValid())},
+		":tl":     &types.AttributeValueMemberS{Value: value.TLease().Format(time.RFC3339Nano)},
+		":prev":   &types.AttributeValueMemberS{Value: value.Prev()},
+		":ll":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", value.LinkedLen())},
+		":id":     &types.AttributeValueMemberBOOL{Value: value.IsDeleted()},
+		":ver":    &types.AttributeValueMemberS{Value: newVer},
+		":oldver": &types.AttributeValueMemberS{Value: value.Version()},
+	}
+	return updateExpr, exprAttrNames, exprAttrValues
+}
+
+// commitExpression returns the expression of the conditional commit
+// of version at tCommit.
+func commitExpression(
+	version string,
+	newVer string,
+	tCommit int64,
+) (string, map[string]string, map[string]types.AttributeValue) {
+	updateExpr := "SET #ts = :ts, #tv = :tv, #ver = :ver"
+	exprAttrNames := map[string]string{
+		"#ts":  "TxnState",
+		"#tv":  "TValid",
+		"#ver": "Version",
+	}
+
+	exprAttrValues := map[string]types.AttributeValue{
+		":ts":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", oreoconfig.COMMITTED)},
+		":tv":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", tCommit)},
+		":ver":    &types.AttributeValueMemberS{Value: newVer},
+		":oldver": &types.AttributeValueMemberS{Value: version},
+	}
+	return updateExpr, exprAttrNames, exprAttrValues
+}
+
+// itemAttributes returns the attributes of the item storing value with newVer.
+func itemAttributes(
+	key string,
+	value txn.DataItem,
+	newVer string,
+) (map[string]types.AttributeValue, error) {
+	return attributevalue.MarshalMap(DynamoDBItem{
+		DKey:          key,
+		DValue:        value.Value(),
+		DGroupKeyList: value.GroupKeyList(),
+		DTxnState:     value.TxnState(),
+		DTValid:       value.TValid(),
+		DTLease:       value.TLease(),
+		DPrev:         value.Prev(),
+		DLinkedLen:    value.LinkedLen(),
+		DIsDeleted:    value.IsDeleted(),
+		DVersion:      newVer,
+		DTExpire:      expiryOf(value),
+	})
+}
+
+// conditionalUpdateItem returns the item of a TransactWriteItems request
+// applying the update expression to key if its version is :oldver.
+func (d *DynamoDBConnection) conditionalUpdateItem(
+	key string,
+	updateExpr string,
+	exprAttrNames map[string]string,
+	exprAttrValues map[string]types.AttributeValue,
+) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(d.tableName),
+			Key: map[string]types.AttributeValue{
+				"ID": &types.AttributeValueMemberS{Value: key},
+			},
+			UpdateExpression:          aws.String(updateExpr),
+			ExpressionAttributeNames:  exprAttrNames,
+			ExpressionAttributeValues: exprAttrValues,
+			ConditionExpression:       aws.String("#ver = :oldver"),
+		},
+	}
+}
+
+// maxTransactItems is the maximum number of items in a TransactWriteItems request.
+const maxTransactItems = 100
+
+// transactWrite sends items with TransactWriteItems requests of up to
+// maxTransactItems items.
+//
+// A TransactWriteItems request is applied atomically, while the operations
+// of a batch are independent. So when a request is canceled, the items whose
+// condition failed are reported as VersionMismatch and the others, which
+// were not applied, are retried one by one with retry.
+func (d *DynamoDBConnection) transactWrite(
+	items []types.TransactWriteItem,
+	newVers []string,
+	retry func(indexes []int) []txn.BatchResult,
+) []txn.BatchResult {
+	results := make([]txn.BatchResult, len(items))
+	var retried []int
+	for start := 0; start < len(items); start += maxTransactItems {
+		end := min(start+maxTransactItems, len(items))
+		_, err := d.client.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: items[start:end],
+		})
+		if err == nil {
+			for i := start; i < end; i++ {
+				results[i].Version = newVers[i]
+			}
+			continue
+		}
+
+		var tce *types.TransactionCanceledException
+		if !errors.As(err, &tce) || len(tce.CancellationReasons) != end-start {
+			for i := start; i < end; i++ {
+				results[i].Err = err
+			}
+			continue
+		}
+		for j, reason := range tce.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				results[start+j].Err = errors.New(txn.VersionMismatch)
+			} else {
+				retried = append(retried, start+j)
+			}
+		}
+	}
+
+	if len(retried) > 0 {
+		for j, res := range retry(retried) {
+			results[retried[j]] = res
+		}
+	}
+	return results
+}
+
+// ConditionalUpdateBatch sends the conditional updates with TransactWriteItems.
+func (d *DynamoDBConnection) ConditionalUpdateBatch(ops []txn.UpdateOp) []txn.BatchResult {
+	results := make([]txn.BatchResult, len(ops))
+	if !d.hasConnected {
+		for i := range results {
+			results[i].Err = errors.Errorf("not connected to DynamoDB")
+		}
+		return results
+	}
+	if oreoconfig.Debug.DebugMode {
+		time.Sleep(oreoconfig.Debug.ConnAdditionalLatency)
+	}
+
+	items := make([]types.TransactWriteItem, 0, len(ops))
+	newVers := make([]string, 0, len(ops))
+	indexes := make([]int, 0, len(ops))
+	for i, op := range ops {
+		newVer := util.AddToString(op.Value.Version(), 1)
+		if op.DoCreate {
+			av, err := itemAttributes(op.Key, op.Value, newVer)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			items = append(items, types.TransactWriteItem{
+				Put: &types.Put{
+					TableName:           aws.String(d.tableName),
+					Item:                av,
+					ConditionExpression: aws.String("attribute_not_exists(ID)"),
+				},
+			})
+		} else {
+			updateExpr, exprAttrNames, exprAttrValues := updateExpression(op.Value, newVer)
+			items = append(items,
+				d.conditionalUpdateItem(op.Key, updateExpr, exprAttrNames, exprAttrValues))
+		}
+		newVers = append(newVers, newVer)
+		indexes = append(indexes, i)
+	}
+	if len(items) == 0 {
+		return results
+	}
+
+	written := d.transactWrite(items, newVers, func(retried []int) []txn.BatchResult {
+		retriedOps := make([]txn.UpdateOp, len(retried))
+		for j, k := range retried {
+			retriedOps[j] = ops[indexes[k]]
+		}
+		return txn.ConditionalUpdateEach(d, retriedOps)
+	})
+	for k, res := range written {
+		results[indexes[k]] = res
+	}
+	return results
+}
+
+// ConditionalCommitBatch sends the conditional commits with TransactWriteItems.
+func (d *DynamoDBConnection) ConditionalCommitBatch(
+	infos []txn.CommitInfo,
+	tCommit int64,
+) []txn.BatchResult {
+	if !d.hasConnected {
+		results := make([]txn.BatchResult, len(infos))
+		for i := range results {
+			results[i].Err = errors.Errorf("not connected to DynamoDB")
+		}
+		return results
+	}
+	if oreoconfig.Debug.DebugMode {
+		time.Sleep(oreoconfig.Debug.ConnAdditionalLatency)
+	}
+	if len(infos) == 0 {
+		return nil
+	}
+
+	items := make([]types.TransactWriteItem, len(infos))
+	newVers := make([]string, len(infos))
+	for i, info := range infos {
+		newVers[i] = util.AddToString(info.Version, 1)
+		updateExpr, exprAttrNames, exprAttrValues := commitExpression(info.Version, newVers[i], tCommit)
+		items[i] = d.conditionalUpdateItem(info.Key, updateExpr, exprAttrNames, exprAttrValues)
+	}
+	return d.transactWrite(items, newVers, func(retried []int) []txn.BatchResult {
+		retriedInfos := make([]txn.CommitInfo, len(retried))
+		for j, i := range retried {
+			retriedInfos[j] = infos[i]
+		}
+		return txn.ConditionalCommitEach(d, retriedInfos, tCommit)
+	})
+}
+
 func (d *DynamoDBConnection) AtomicCreate(key string, value any) (string, error) {
 	if !d.hasConnected {
 		return "", errors.Errorf("not connected to DynamoDB")
@@ -311,18 +562,7 @@ func (d *DynamoDBConnection) atomicCreateDynamoItem(
 ) (string, error) {
 	newVer := util.AddToString(value.Version(), 1)
 
-	av, err := attributevalue.MarshalMap(DynamoDBItem{
-		DKey:          key,
-		DValue:        value.Value(),
-		DGroupKeyList: value.GroupKeyList(),
-		DTxnState:     value.TxnState(),
-		DTValid:       value.TValid(),
-		DTLease:       value.TLease(),
-		DPrev:         value.Prev(),
-		DLinkedLen:    value.LinkedLen(),
-		DIsDeleted:    value.IsDeleted(),
-		DVersion:      newVer,
-	})
+	av, err := itemAttributes(key, value, newVer)
 	if err != nil {
 		return "", err
 	}
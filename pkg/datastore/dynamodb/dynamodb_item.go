@@ -10,18 +10,20 @@ import (
 )
 
 var _ txn.DataItem = (*DynamoDBItem)(nil)
+var _ txn.ExpiringItem = (*DynamoDBItem)(nil)
 
 type DynamoDBItem struct {
-	DKey          string       `dynamodbav:"ID"           json:"Key"`          // 分区键
-	DValue        string       `dynamodbav:"Value"        json:"Value"`        // 值
-	DGroupKeyList string       `dynamodbav:"GroupKeyList" json:"GroupKeyList"` // 组键列表
-	DTxnState     config.State `dynamodbav:"TxnState"     json:"TxnState"`     // 事务状态
-	DTValid       int64        `dynamodbav:"TValid"       json:"TValid"`       // 有效时间戳
-	DTLease       time.Time    `dynamodbav:"TLease"       json:"TLease"`       // 租约时间
-	DPrev         string       `dynamodbav:"Prev"         json:"Prev"`         // 前驱
-	DLinkedLen    int          `dynamodbav:"LinkedLen"    json:"LinkedLen"`    // 链接长度
-	DIsDeleted    bool         `dynamodbav:"IsDeleted"    json:"IsDeleted"`    // 删除标记
-	DVersion      string       `dynamodbav:"Version"      json:"Version"`      // 版本号
+	DKey          string       `dynamodbav:"ID"           json:"Key"`              // 分区键
+	DValue        string       `dynamodbav:"Value"        json:"Value"`            // 值
+	DGroupKeyList string       `dynamodbav:"GroupKeyList" json:"GroupKeyList"`     // 组键列表
+	DTxnState     config.State `dynamodbav:"TxnState"     json:"TxnState"`         // 事务状态
+	DTValid       int64        `dynamodbav:"TValid"       json:"TValid"`           // 有效时间戳
+	DTLease       time.Time    `dynamodbav:"TLease"       json:"TLease"`           // 租约时间
+	DPrev         string       `dynamodbav:"Prev"         json:"Prev"`             // 前驱
+	DLinkedLen    int          `dynamodbav:"LinkedLen"    json:"LinkedLen"`        // 链接长度
+	DIsDeleted    bool         `dynamodbav:"IsDeleted"    json:"IsDeleted"`        // 删除标记
+	DVersion      string       `dynamodbav:"Version"      json:"Version"`          // 版本号
+	DTExpire      time.Time    `dynamodbav:"TExpire"      json:"TExpire,omitzero"` // 过期时间
 }
 
 func NewDynamoDBItem(options txn.ItemOptions) *DynamoDBItem {
@@ -119,6 +121,14 @@ func (d *DynamoDBItem) SetVersion(version string) {
 	d.DVersion = version
 }
 
+func (d *DynamoDBItem) TExpire() time.Time {
+	return d.DTExpire
+}
+
+func (d *DynamoDBItem) SetTExpire(tExpire time.Time) {
+	d.DTExpire = tExpire
+}
+
 func (d *DynamoDBItem) Equal(other txn.DataItem) bool {
 	if other == nil {
 		return false
@@ -137,7 +147,16 @@ func (d *DynamoDBItem) Equal(other txn.DataItem) bool {
 		d.Prev() == otherItem.Prev() &&
 		d.LinkedLen() == otherItem.LinkedLen() &&
 		d.IsDeleted() == otherItem.IsDeleted() &&
-		d.Version() == otherItem.Version()
+		d.Version() == otherItem.Version() &&
+		d.TExpire().Equal(otherItem.TExpire())
+}
+
+// expiryOf returns the expiry of item, or the zero time if it has none.
+func expiryOf(item txn.DataItem) time.Time {
+	if it, ok := item.(txn.ExpiringItem); ok {
+		return it.TExpire()
+	}
+	return time.Time{}
 }
 
 func (d *DynamoDBItem) Empty() bool {
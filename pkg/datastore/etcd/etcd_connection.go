@@ -0,0 +1,421 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var _ txn.Connector = (*EtcdConnection)(nil)
+var _ txn.KeyScanner = (*EtcdConnection)(nil)
+
+const defaultEtcdTimeout = 5000 * time.Millisecond
+
+// EtcdConnection is a connector backed by etcd.
+//
+// The version of an item is the mod revision of its etcd key, so
+// ConditionalUpdate, ConditionalCommit and AtomicCreate are etcd transactions
+// comparing the mod or create revision. The DataItems are kept under
+// "<Prefix>items/" and the plain values written by Put and AtomicCreate,
+// such as the group keys, under "<Prefix>kv/".
+type EtcdConnection struct {
+	client       *clientv3.Client
+	config       ConnectionOptions
+	hasConnected bool
+}
+
+type ConnectionOptions struct {
+	Endpoints   []string
+	Username    string
+	Password    string
+	Prefix      string
+	DialTimeout time.Duration
+}
+
+var defaultOptions = ConnectionOptions{
+	Endpoints:   []string{"localhost:2379"},
+	Prefix:      "/oreo/",
+	DialTimeout: 5 * time.Second,
+}
+
+// NewEtcdConnection creates a new etcd connection.
+func NewEtcdConnection(config *ConnectionOptions) *EtcdConnection {
+	finalConfig := defaultOptions
+	if config != nil {
+		if len(config.Endpoints) > 0 {
+			finalConfig.Endpoints = config.Endpoints
+		}
+		if config.Prefix != "" {
+			finalConfig.Prefix = config.Prefix
+		}
+		if config.DialTimeout > 0 {
+			finalConfig.DialTimeout = config.DialTimeout
+		}
+		finalConfig.Username = config.Username
+		finalConfig.Password = config.Password
+	}
+
+	return &EtcdConnection{
+		config:       finalConfig,
+		hasConnected: false,
+	}
+}
+
+// Connect establishes a connection to the etcd cluster.
+func (e *EtcdConnection) Connect() error {
+	if e.hasConnected {
+		return nil
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   e.config.Endpoints,
+		Username:    e.config.Username,
+		Password:    e.config.Password,
+		DialTimeout: e.config.DialTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to etcd: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+	if _, err := client.Status(ctx, e.config.Endpoints[0]); err != nil {
+		_ = client.Close()
+		return fmt.Errorf("failed to connect to etcd: %v", err)
+	}
+
+	e.client = client
+	e.hasConnected = true
+	return nil
+}
+
+// Close closes the etcd client.
+func (e *EtcdConnection) Close() error {
+	if !e.hasConnected {
+		return nil
+	}
+	e.hasConnected = false
+	return e.client.Close()
+}
+
+// GetAddress returns the first endpoint of the etcd cluster.
+func (e *EtcdConnection) GetAddress() string {
+	return e.config.Endpoints[0]
+}
+
+func (e *EtcdConnection) checkConnected() error {
+	if !e.hasConnected {
+		return errors.Errorf("not connected to etcd")
+	}
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+	return nil
+}
+
+func (e *EtcdConnection) itemKey(key string) string {
+	return e.config.Prefix + "items/" + key
+}
+
+func (e *EtcdConnection) kvKey(name string) string {
+	return e.config.Prefix + "kv/" + name
+}
+
+func toVersion(revision int64) string {
+	return strconv.FormatInt(revision, 10)
+}
+
+// toRevision parses a version into a mod revision.
+// The empty version, or one not issued by etcd, is reported as a mismatch.
+func toRevision(version string) (int64, error) {
+	revision, err := strconv.ParseInt(version, 10, 64)
+	if err != nil || revision <= 0 {
+		return 0, errors.New(txn.VersionMismatch)
+	}
+	return revision, nil
+}
+
+// marshalItem encodes value without its version,
+// which is kept by etcd as the mod revision.
+func marshalItem(value txn.DataItem) (string, error) {
+	item := EtcdItem{
+		EKey:          value.Key(),
+		EValue:        value.Value(),
+		EGroupKeyList: value.GroupKeyList(),
+		ETxnState:     value.TxnState(),
+		ETValid:       value.TValid(),
+		ETLease:       value.TLease(),
+		EPrev:         value.Prev(),
+		ELinkedLen:    value.LinkedLen(),
+		EIsDeleted:    value.IsDeleted(),
+	}
+	if it, ok := value.(txn.ExpiringItem); ok {
+		item.ETExpire = it.TExpire()
+	}
+	data, err := json.Marshal(&item)
+	if err != nil {
+		return "", errors.New("failed to marshal item")
+	}
+	return string(data), nil
+}
+
+func unmarshalItem(data []byte, modRevision int64) (*EtcdItem, error) {
+	var item EtcdItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, errors.New("failed to unmarshal item")
+	}
+	item.EVersion = toVersion(modRevision)
+	return &item, nil
+}
+
+// GetItem retrieves a structured transaction item from etcd.
+func (e *EtcdConnection) GetItem(key string) (txn.DataItem, error) {
+	if err := e.checkConnected(); err != nil {
+		return &EtcdItem{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, e.itemKey(key))
+	if err != nil {
+		return &EtcdItem{}, err
+	}
+	if len(resp.Kvs) == 0 {
+		return &EtcdItem{}, errors.New(txn.KeyNotFound)
+	}
+	item, err := unmarshalItem(resp.Kvs[0].Value, resp.Kvs[0].ModRevision)
+	if err != nil {
+		return &EtcdItem{}, err
+	}
+	return item, nil
+}
+
+// PutItem writes an item unconditionally and returns its new version.
+func (e *EtcdConnection) PutItem(key string, value txn.DataItem) (string, error) {
+	if err := e.checkConnected(); err != nil {
+		return "", err
+	}
+
+	data, err := marshalItem(value)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+
+	resp, err := e.client.Put(ctx, e.itemKey(key), data)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("PutItem key %s failed, err: %v", key, err))
+	}
+	return toVersion(resp.Header.Revision), nil
+}
+
+// ConditionalUpdate writes value if the mod revision of the stored item
+// matches the version of value, or if doCreate is set and the item does
+// not exist. It returns the new version.
+func (e *EtcdConnection) ConditionalUpdate(
+	key string,
+	value txn.DataItem,
+	doCreate bool,
+) (string, error) {
+	if err := e.checkConnected(); err != nil {
+		return "", err
+	}
+
+	etcdKey := e.itemKey(key)
+	var cmp clientv3.Cmp
+	if doCreate {
+		cmp = clientv3.Compare(clientv3.CreateRevision(etcdKey), "=", 0)
+	} else {
+		revision, err := toRevision(value.Version())
+		if err != nil {
+			return "", err
+		}
+		cmp = clientv3.Compare(clientv3.ModRevision(etcdKey), "=", revision)
+	}
+
+	data, err := marshalItem(value)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+
+	resp, err := e.client.Txn(ctx).
+		If(cmp).
+		Then(clientv3.OpPut(etcdKey, data)).
+		Commit()
+	if err != nil {
+		return "", err
+	}
+	if !resp.Succeeded {
+		return "", errors.New(txn.VersionMismatch)
+	}
+	return toVersion(resp.Header.Revision), nil
+}
+
+// ConditionalCommit marks the item as COMMITTED at tCommit
+// if its mod revision matches version, and returns the new version.
+func (e *EtcdConnection) ConditionalCommit(
+	key string,
+	version string,
+	tCommit int64,
+) (string, error) {
+	if err := e.checkConnected(); err != nil {
+		return "", err
+	}
+
+	revision, err := toRevision(version)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+
+	etcdKey := e.itemKey(key)
+	getResp, err := e.client.Get(ctx, etcdKey)
+	if err != nil {
+		return "", err
+	}
+	if len(getResp.Kvs) == 0 || getResp.Kvs[0].ModRevision != revision {
+		return "", errors.New(txn.VersionMismatch)
+	}
+	item, err := unmarshalItem(getResp.Kvs[0].Value, revision)
+	if err != nil {
+		return "", err
+	}
+	item.ETxnState = config.COMMITTED
+	item.ETValid = tCommit
+	data, err := marshalItem(item)
+	if err != nil {
+		return "", err
+	}
+
+	// the item may have changed since it was read
+	resp, err := e.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(etcdKey), "=", revision)).
+		Then(clientv3.OpPut(etcdKey, data)).
+		Commit()
+	if err != nil {
+		return "", err
+	}
+	if !resp.Succeeded {
+		return "", errors.New(txn.VersionMismatch)
+	}
+	return toVersion(resp.Header.Revision), nil
+}
+
+// AtomicCreate creates a key-value pair if the key does not already exist.
+// Otherwise it returns the existing value and KeyExists.
+func (e *EtcdConnection) AtomicCreate(name string, value any) (string, error) {
+	if err := e.checkConnected(); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+
+	etcdKey := e.kvKey(name)
+	resp, err := e.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(etcdKey), "=", 0)).
+		Then(clientv3.OpPut(etcdKey, util.ToString(value))).
+		Else(clientv3.OpGet(etcdKey)).
+		Commit()
+	if err != nil {
+		return "", err
+	}
+	if resp.Succeeded {
+		return "", nil
+	}
+
+	// the key already exists, return the old value
+	kvs := resp.Responses[0].GetResponseRange().Kvs
+	if len(kvs) == 0 {
+		return "", errors.New(txn.KeyExists)
+	}
+	return string(kvs[0].Value), errors.New(txn.KeyExists)
+}
+
+// Get retrieves a plain value written by Put or AtomicCreate.
+func (e *EtcdConnection) Get(name string) (string, error) {
+	if err := e.checkConnected(); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, e.kvKey(name))
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", errors.New(txn.KeyNotFound)
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Put sets the plain value for a given key.
+func (e *EtcdConnection) Put(name string, value any) error {
+	if err := e.checkConnected(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+
+	_, err := e.client.Put(ctx, e.kvKey(name), util.ToString(value))
+	return err
+}
+
+// Delete removes name from both the items and the plain values.
+func (e *EtcdConnection) Delete(name string) error {
+	if err := e.checkConnected(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+
+	_, err := e.client.Txn(ctx).
+		Then(clientv3.OpDelete(e.itemKey(name)), clientv3.OpDelete(e.kvKey(name))).
+		Commit()
+	return err
+}
+
+// ScanKeys calls fn with the key of every item. The keys are listed
+// first, so fn may use the connection, and the items written meanwhile
+// may be missed.
+func (e *EtcdConnection) ScanKeys(fn func(key string) error) error {
+	if err := e.checkConnected(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdTimeout)
+	defer cancel()
+
+	prefix := e.itemKey("")
+	resp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return err
+	}
+	for _, kv := range resp.Kvs {
+		if err := fn(strings.TrimPrefix(string(kv.Key), prefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package etcd
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/kkkzoz/oreo/pkg/txn/testsuite"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+var testEtcdEndpoint string
+
+type etcdTestSuiteHelper struct{}
+
+func (h *etcdTestSuiteHelper) MakeItem(ops txn.ItemOptions) txn.DataItem {
+	return &EtcdItem{
+		EKey:          ops.Key,
+		EValue:        util.ToJSONString(ops.Value),
+		EGroupKeyList: ops.GroupKeyList,
+		ETxnState:     ops.TxnState,
+		ETValid:       ops.TValid,
+		ETLease:       ops.TLease,
+		EPrev:         ops.Prev,
+		EIsDeleted:    ops.IsDeleted,
+		EVersion:      ops.Version,
+	}
+}
+
+func (h *etcdTestSuiteHelper) NewInstance() txn.DataItem {
+	return &EtcdItem{}
+}
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "oreo-etcd")
+	if err != nil {
+		log.Fatalf("Could not create the etcd data dir: %s", err)
+	}
+
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+	clientUrl, _ := url.Parse("http://127.0.0.1:0")
+	peerUrl, _ := url.Parse("http://127.0.0.1:0")
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{*clientUrl}, []url.URL{*clientUrl}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{*peerUrl}, []url.URL{*peerUrl}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	server, err := embed.StartEtcd(cfg)
+	if err != nil {
+		log.Fatalf("Could not start etcd: %s", err)
+	}
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(30 * time.Second):
+		log.Fatalf("etcd took too long to start")
+	}
+	testEtcdEndpoint = server.Clients[0].Addr().String()
+
+	exitCode := m.Run()
+	server.Close()
+	_ = os.RemoveAll(dir)
+	os.Exit(exitCode)
+}
+
+func newTestEtcdConnection(t *testing.T, prefix string) *EtcdConnection {
+	conn := NewEtcdConnection(&ConnectionOptions{
+		Endpoints: []string{testEtcdEndpoint},
+		Prefix:    prefix,
+	})
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Could not connect to etcd: %s", err)
+	}
+	// not closed on cleanup, since a failed commit aborts in the background
+	return conn
+}
+
+func TestEtcdConnector_InterfaceSuite(t *testing.T) {
+	testsuite.TestConnectorSuite(t, newTestEtcdConnection(t, "/suite/"), &etcdTestSuiteHelper{})
+}
+
+func TestEtcdVersionIsModRevision(t *testing.T) {
+	conn := newTestEtcdConnection(t, "/revision/")
+
+	ver, err := conn.ConditionalUpdate("key", &EtcdItem{EKey: "key", EValue: "v1"}, true)
+	assert.NoError(t, err)
+	_, err = conn.ConditionalUpdate("key", &EtcdItem{EKey: "key", EValue: "v1"}, true)
+	assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+	// a write to another key moves the revision of the cluster
+	assert.NoError(t, conn.Put("other", "value"))
+
+	item, err := conn.GetItem("key")
+	assert.NoError(t, err)
+	assert.Equal(t, ver, item.Version())
+
+	item.SetValue("v2")
+	newVer, err := conn.ConditionalUpdate("key", item, false)
+	assert.NoError(t, err)
+	assert.Greater(t, util.ToInt(newVer), util.ToInt(ver))
+	_, err = conn.ConditionalUpdate("key", item, false)
+	assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+	_, err = conn.ConditionalCommit("key", ver, 10)
+	assert.EqualError(t, err, txn.VersionMismatch.Error())
+	commitVer, err := conn.ConditionalCommit("key", newVer, 10)
+	assert.NoError(t, err)
+	item, err = conn.GetItem("key")
+	assert.NoError(t, err)
+	assert.Equal(t, commitVer, item.Version())
+	assert.Equal(t, "v2", item.Value())
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+	assert.Equal(t, int64(10), item.TValid())
+}
+
+func TestEtcdAtomicCreate(t *testing.T) {
+	conn := newTestEtcdConnection(t, "/atomic/")
+
+	old, err := conn.AtomicCreate("groupKey", "first")
+	assert.NoError(t, err)
+	assert.Equal(t, "", old)
+
+	old, err = conn.AtomicCreate("groupKey", "second")
+	assert.EqualError(t, err, txn.KeyExists.Error())
+	assert.Equal(t, "first", old)
+
+	// items and plain values do not collide
+	_, err = conn.GetItem("groupKey")
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+}
@@ -0,0 +1,7 @@
+package etcd
+
+import "github.com/kkkzoz/oreo/pkg/txn"
+
+func NewEtcdDatastore(name string, conn txn.Connector) txn.Datastorer {
+	return txn.NewDatastore(name, conn, &EtcdItemFactory{})
+}
@@ -0,0 +1,71 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTransaction(conns map[string]*EtcdConnection) *txn.Transaction {
+	tx := txn.NewTransaction()
+	for name, conn := range conns {
+		tx.AddDatastore(NewEtcdDatastore(name, conn))
+	}
+	return tx
+}
+
+func TestEtcdTransactionAcrossDatastores(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*EtcdConnection{
+		"etcd1": newTestEtcdConnection(t, "/etcd1/"),
+		"etcd2": newTestEtcdConnection(t, "/etcd2/"),
+	}
+
+	txn1 := newTestTransaction(conns)
+	assert.NoError(t, txn1.Start())
+	person := testutil.NewDefaultPerson()
+	assert.NoError(t, txn1.Write("etcd1", "person", person))
+	assert.NoError(t, txn1.Write("etcd2", "item", testutil.NewTestItem("item")))
+	assert.NoError(t, txn1.Commit())
+
+	txn2 := newTestTransaction(conns)
+	assert.NoError(t, txn2.Start())
+	var gotPerson testutil.Person
+	assert.NoError(t, txn2.Read("etcd1", "person", &gotPerson))
+	assert.Equal(t, person, gotPerson)
+	var gotItem testutil.TestItem
+	assert.NoError(t, txn2.Read("etcd2", "item", &gotItem))
+	assert.Equal(t, testutil.NewTestItem("item"), gotItem)
+	assert.NoError(t, txn2.Commit())
+}
+
+func TestEtcdTransactionConflict(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*EtcdConnection{"etcd": newTestEtcdConnection(t, "/conflict/")}
+
+	init := newTestTransaction(conns)
+	assert.NoError(t, init.Start())
+	assert.NoError(t, init.Write("etcd", "key", "v0"))
+	assert.NoError(t, init.Commit())
+
+	txn1 := newTestTransaction(conns)
+	txn2 := newTestTransaction(conns)
+	assert.NoError(t, txn1.Start())
+	assert.NoError(t, txn2.Start())
+	var value string
+	assert.NoError(t, txn1.Read("etcd", "key", &value))
+	assert.NoError(t, txn2.Read("etcd", "key", &value))
+	assert.NoError(t, txn1.Write("etcd", "key", "v1"))
+	assert.NoError(t, txn2.Write("etcd", "key", "v2"))
+	assert.NoError(t, txn1.Commit())
+	assert.Error(t, txn2.Commit())
+
+	txn3 := newTestTransaction(conns)
+	assert.NoError(t, txn3.Start())
+	assert.NoError(t, txn3.Read("etcd", "key", &value))
+	assert.Equal(t, "v1", value)
+}
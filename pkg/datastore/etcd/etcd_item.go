@@ -0,0 +1,173 @@
+package etcd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+var _ txn.DataItem = (*EtcdItem)(nil)
+var _ txn.ExpiringItem = (*EtcdItem)(nil)
+
+type EtcdItem struct {
+	EKey          string       `json:"Key"`
+	EValue        string       `json:"Value"`
+	EGroupKeyList string       `json:"GroupKeyList"`
+	ETxnState     config.State `json:"State"`
+	ETValid       int64        `json:"TValid"`
+	ETLease       time.Time    `json:"TLease"`
+	EPrev         string       `json:"Prev"`
+	ELinkedLen    int          `json:"LinkedLen"`
+	EIsDeleted    bool         `json:"IsDeleted"`
+	EVersion      string       `json:"Version,omitempty"`
+	ETExpire      time.Time    `json:"TExpire,omitzero"`
+}
+
+func NewEtcdItem(options txn.ItemOptions) *EtcdItem {
+	if options.Value == nil {
+		options.Value = ""
+	}
+
+	return &EtcdItem{
+		EKey:          options.Key,
+		EValue:        options.Value.(string),
+		EGroupKeyList: options.GroupKeyList,
+		ETxnState:     options.TxnState,
+		ETValid:       options.TValid,
+		ETLease:       options.TLease,
+		EPrev:         options.Prev,
+		ELinkedLen:    options.LinkedLen,
+		EIsDeleted:    options.IsDeleted,
+		EVersion:      options.Version,
+	}
+}
+
+func (e *EtcdItem) Key() string {
+	return e.EKey
+}
+
+func (e *EtcdItem) Value() string {
+	return e.EValue
+}
+
+func (e *EtcdItem) SetValue(value string) {
+	e.EValue = value
+}
+
+func (e *EtcdItem) GroupKeyList() string {
+	return e.EGroupKeyList
+}
+
+func (e *EtcdItem) SetGroupKeyList(groupKeyList string) {
+	e.EGroupKeyList = groupKeyList
+}
+
+func (e *EtcdItem) TxnState() config.State {
+	return e.ETxnState
+}
+
+func (e *EtcdItem) SetTxnState(state config.State) {
+	e.ETxnState = state
+}
+
+func (e *EtcdItem) TValid() int64 {
+	return e.ETValid
+}
+
+func (e *EtcdItem) SetTValid(tValid int64) {
+	e.ETValid = tValid
+}
+
+func (e *EtcdItem) TLease() time.Time {
+	return e.ETLease
+}
+
+func (e *EtcdItem) SetTLease(tLease time.Time) {
+	e.ETLease = tLease
+}
+
+func (e *EtcdItem) Prev() string {
+	return e.EPrev
+}
+
+func (e *EtcdItem) SetPrev(prev string) {
+	e.EPrev = prev
+}
+
+func (e *EtcdItem) LinkedLen() int {
+	return e.ELinkedLen
+}
+
+func (e *EtcdItem) SetLinkedLen(linkedLen int) {
+	e.ELinkedLen = linkedLen
+}
+
+func (e *EtcdItem) IsDeleted() bool {
+	return e.EIsDeleted
+}
+
+func (e *EtcdItem) SetIsDeleted(isDeleted bool) {
+	e.EIsDeleted = isDeleted
+}
+
+func (e *EtcdItem) Version() string {
+	return e.EVersion
+}
+
+func (e *EtcdItem) SetVersion(version string) {
+	e.EVersion = version
+}
+
+func (e *EtcdItem) TExpire() time.Time {
+	return e.ETExpire
+}
+
+func (e *EtcdItem) SetTExpire(tExpire time.Time) {
+	e.ETExpire = tExpire
+}
+
+func (e *EtcdItem) Equal(other txn.DataItem) bool {
+	if other == nil {
+		return false
+	}
+	otherItem, ok := other.(*EtcdItem)
+	if !ok {
+		return false
+	}
+
+	return e.Key() == otherItem.Key() &&
+		e.Value() == otherItem.Value() &&
+		e.GroupKeyList() == otherItem.GroupKeyList() &&
+		e.TxnState() == otherItem.TxnState() &&
+		e.TValid() == otherItem.TValid() &&
+		e.TLease().Equal(otherItem.TLease()) &&
+		e.Prev() == otherItem.Prev() &&
+		e.LinkedLen() == otherItem.LinkedLen() &&
+		e.IsDeleted() == otherItem.IsDeleted() &&
+		e.Version() == otherItem.Version() &&
+		e.TExpire().Equal(otherItem.TExpire())
+}
+
+func (e *EtcdItem) Empty() bool {
+	return e == nil || (e.Key() == "" && e.Value() == "")
+}
+
+func (e *EtcdItem) String() string {
+	return fmt.Sprintf(`EtcdItem{
+    Key:       %s,
+    Value:     %s,
+    GroupKeyList:     %s,
+    TxnState:  %s,
+    TValid:    %v,
+    TLease:    %s,
+    Prev:      %s,
+    LinkedLen: %d,
+    IsDeleted: %v,
+    Version:   %s,
+}`, e.EKey, e.EValue, e.EGroupKeyList, util.ToString(e.ETxnState),
+		e.ETValid, e.ETLease.Format(time.RFC3339),
+		e.EPrev, e.ELinkedLen, e.EIsDeleted, e.EVersion)
+}
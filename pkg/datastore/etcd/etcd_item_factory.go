@@ -0,0 +1,11 @@
+package etcd
+
+import "github.com/kkkzoz/oreo/pkg/txn"
+
+var _ txn.DataItemFactory = (*EtcdItemFactory)(nil)
+
+type EtcdItemFactory struct{}
+
+func (e *EtcdItemFactory) NewDataItem(options txn.ItemOptions) txn.DataItem {
+	return NewEtcdItem(options)
+}
@@ -3,6 +3,7 @@ package mongo
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/go-errors/errors"
@@ -15,7 +16,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var _ txn.Connector = (*MongoConnection)(nil)
+var _ txn.BatchConnector = (*MongoConnection)(nil)
+var _ txn.Namespacer = (*MongoConnection)(nil)
+var _ txn.Expirer = (*MongoConnection)(nil)
 
 const defaultMongoTimeout = 5000 * time.Millisecond
 
@@ -31,6 +34,14 @@ type MongoConnection struct {
 	Address      string
 	config       ConnectionOptions
 	hasConnected bool
+
+	// namespaces caches the connections returned by WithNamespace
+	nsMu       sync.Mutex
+	namespaces map[string]*MongoConnection
+
+	// ttlIndexed is set once the TTL index used by ExpireAt is created
+	ttlMu      sync.Mutex
+	ttlIndexed bool
 }
 
 type ConnectionOptions struct {
@@ -117,6 +128,84 @@ func (m *MongoConnection) Connect() error {
 	return nil
 }
 
+// WithNamespace returns a connection to the records of namespace ns, kept
+// in the collection "<CollectionName>_<ns>" of the same database.
+// The connection shares the client of m, so it must not be closed.
+func (m *MongoConnection) WithNamespace(ns string) (txn.Connector, error) {
+	if err := m.Connect(); err != nil {
+		return nil, err
+	}
+	m.nsMu.Lock()
+	defer m.nsMu.Unlock()
+	if conn, ok := m.namespaces[ns]; ok {
+		return conn, nil
+	}
+	if m.namespaces == nil {
+		m.namespaces = make(map[string]*MongoConnection)
+	}
+	cfg := m.config
+	cfg.CollectionName = m.config.CollectionName + "_" + ns
+	conn := &MongoConnection{
+		client:       m.client,
+		db:           m.db,
+		coll:         m.db.Collection(cfg.CollectionName),
+		Address:      m.Address,
+		config:       cfg,
+		hasConnected: true,
+	}
+	m.namespaces[ns] = conn
+	return conn, nil
+}
+
+// expireAtField is the date field of the documents watched by the TTL index.
+const expireAtField = "ExpireAt"
+
+// ExpireAt makes the document of key expire at expireAt through a TTL index
+// on expireAtField, which is created on the first call, unless it is no
+// longer at version. The TTL monitor of MongoDB runs every minute,
+// so the document lives up to a minute longer.
+func (m *MongoConnection) ExpireAt(key string, version string, expireAt time.Time) error {
+	if !m.hasConnected {
+		return errors.Errorf("not connected to MongoDB")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultMongoTimeout)
+	defer cancel()
+
+	if err := m.ensureTTLIndex(ctx); err != nil {
+		return err
+	}
+	res, err := m.coll.UpdateOne(ctx,
+		bson.M{"_id": key, "Version": version},
+		bson.D{{Key: "$set", Value: bson.D{{Key: expireAtField, Value: expireAt}}}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New(txn.VersionMismatch)
+	}
+	return nil
+}
+
+// ensureTTLIndex creates the TTL index on expireAtField if it is not created yet.
+func (m *MongoConnection) ensureTTLIndex(ctx context.Context) error {
+	m.ttlMu.Lock()
+	defer m.ttlMu.Unlock()
+	if m.ttlIndexed {
+		return nil
+	}
+	_, err := m.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: expireAtField, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return err
+	}
+	m.ttlIndexed = true
+	return nil
+}
+
 // Close disconnects from the MongoDB server.
 func (m *MongoConnection) Close() error {
 	if !m.hasConnected {
@@ -168,6 +257,7 @@ func (m *MongoConnection) PutItem(key string, value txn.DataItem) (string, error
 		bson.M{"_id": key},
 		bson.D{
 			{Key: "$set", Value: value},
+			{Key: "$unset", Value: bson.D{{Key: expireAtField, Value: ""}}},
 		},
 		options.Update().SetUpsert(true),
 	)
@@ -202,19 +292,7 @@ func (m *MongoConnection) ConditionalUpdate(
 	newVer := util.AddToString(value.Version(), 1)
 
 	filter := bson.M{"_id": key, "Version": value.Version()}
-	update := bson.D{
-		{Key: "$set", Value: bson.D{
-			{Key: "Value", Value: value.Value()},
-			{Key: "GroupKeyList", Value: value.GroupKeyList()},
-			{Key: "TxnState", Value: value.TxnState()},
-			{Key: "TValid", Value: value.TValid()},
-			{Key: "TLease", Value: value.TLease().Format(time.RFC3339Nano)},
-			{Key: "Prev", Value: value.Prev()},
-			{Key: "LinkedLen", Value: value.LinkedLen()},
-			{Key: "IsDeleted", Value: value.IsDeleted()},
-			{Key: "Version", Value: newVer},
-		}},
-	}
+	update := itemUpdate(value, newVer)
 	after := options.After
 	opts := &options.FindOneAndUpdateOptions{
 		ReturnDocument: &after,
@@ -243,6 +321,177 @@ func (m *MongoConnection) ConditionalUpdate(
 	return newVer, nil
 }
 
+// itemFields returns the fields of the document storing value with newVer.
+func itemFields(value txn.DataItem, newVer string) bson.D {
+	return bson.D{
+		{Key: "Value", Value: value.Value()},
+		{Key: "GroupKeyList", Value: value.GroupKeyList()},
+		{Key: "TxnState", Value: value.TxnState()},
+		{Key: "TValid", Value: value.TValid()},
+		{Key: "TLease", Value: value.TLease().Format(time.RFC3339Nano)},
+		{Key: "Prev", Value: value.Prev()},
+		{Key: "LinkedLen", Value: value.LinkedLen()},
+		{Key: "IsDeleted", Value: value.IsDeleted()},
+		{Key: "Version", Value: newVer},
+		{Key: "TExpire", Value: expiryOf(value).Format(time.RFC3339Nano)},
+	}
+}
+
+// itemUpdate returns the update storing value with newVer,
+// which clears the native expiry set by ExpireAt.
+func itemUpdate(value txn.DataItem, newVer string) bson.D {
+	return bson.D{
+		{Key: "$set", Value: itemFields(value, newVer)},
+		{Key: "$unset", Value: bson.D{{Key: expireAtField, Value: ""}}},
+	}
+}
+
+// itemDocument returns the document storing value with newVer under key.
+func itemDocument(key string, value txn.DataItem, newVer string) bson.D {
+	return append(bson.D{{Key: "_id", Value: key}}, itemFields(value, newVer)...)
+}
+
+// batchState is the part of a document read back after a bulkWrite.
+type batchState struct {
+	Key          string       `bson:"_id"`
+	GroupKeyList string       `bson:"GroupKeyList"`
+	TxnState     config.State `bson:"TxnState"`
+	Version      string       `bson:"Version"`
+}
+
+// bulkWrite sends models in a single unordered bulkWrite.
+//
+// The bulkWrite only reports how many updates matched their filter, so when
+// some of them did not, the documents are read back and isApplied tells
+// whether the model at an index was applied to its document.
+// A failed insert of an existing document is reported as VersionMismatch.
+func (m *MongoConnection) bulkWrite(
+	keys []string,
+	models []mongo.WriteModel,
+	newVers []string,
+	isApplied func(i int, state *batchState) bool,
+) []txn.BatchResult {
+	results := make([]txn.BatchResult, len(models))
+	fail := func(err error) []txn.BatchResult {
+		for i := range results {
+			results[i] = txn.BatchResult{Err: err}
+		}
+		return results
+	}
+	if !m.hasConnected {
+		return fail(errors.Errorf("not connected to MongoDB"))
+	}
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+	if len(models) == 0 {
+		return results
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultMongoTimeout)
+	defer cancel()
+
+	numUpdates := 0
+	for _, model := range models {
+		if _, ok := model.(*mongo.UpdateOneModel); ok {
+			numUpdates++
+		}
+	}
+	res, err := m.coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	failed := make(map[int]bool)
+	if err != nil {
+		var bwe mongo.BulkWriteException
+		if !errors.As(err, &bwe) {
+			return fail(err)
+		}
+		for _, we := range bwe.WriteErrors {
+			failed[we.Index] = true
+			if we.HasErrorCode(11000) {
+				results[we.Index].Err = errors.New(txn.VersionMismatch)
+			} else {
+				results[we.Index].Err = we
+			}
+		}
+	}
+
+	var states map[string]*batchState
+	if res == nil || res.MatchedCount < int64(numUpdates) {
+		cursor, err := m.coll.Find(ctx, bson.M{"_id": bson.M{"$in": keys}})
+		if err != nil {
+			return fail(err)
+		}
+		var docs []*batchState
+		if err := cursor.All(ctx, &docs); err != nil {
+			return fail(err)
+		}
+		states = make(map[string]*batchState, len(docs))
+		for _, doc := range docs {
+			states[doc.Key] = doc
+		}
+	}
+
+	for i, model := range models {
+		if failed[i] {
+			continue
+		}
+		if _, ok := model.(*mongo.UpdateOneModel); ok && states != nil {
+			state, ok := states[keys[i]]
+			if !ok || !isApplied(i, state) {
+				results[i].Err = errors.New(txn.VersionMismatch)
+				continue
+			}
+		}
+		results[i].Version = newVers[i]
+	}
+	return results
+}
+
+// ConditionalUpdateBatch sends the conditional updates in a single bulkWrite.
+func (m *MongoConnection) ConditionalUpdateBatch(ops []txn.UpdateOp) []txn.BatchResult {
+	keys := make([]string, len(ops))
+	models := make([]mongo.WriteModel, len(ops))
+	newVers := make([]string, len(ops))
+	for i, op := range ops {
+		keys[i] = op.Key
+		newVers[i] = util.AddToString(op.Value.Version(), 1)
+		if op.DoCreate {
+			models[i] = mongo.NewInsertOneModel().
+				SetDocument(itemDocument(op.Key, op.Value, newVers[i]))
+			continue
+		}
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": op.Key, "Version": op.Value.Version()}).
+			SetUpdate(itemUpdate(op.Value, newVers[i]))
+	}
+	return m.bulkWrite(keys, models, newVers, func(i int, state *batchState) bool {
+		return state.Version == newVers[i] && state.GroupKeyList == ops[i].Value.GroupKeyList()
+	})
+}
+
+// ConditionalCommitBatch sends the conditional commits in a single bulkWrite.
+func (m *MongoConnection) ConditionalCommitBatch(
+	infos []txn.CommitInfo,
+	tCommit int64,
+) []txn.BatchResult {
+	keys := make([]string, len(infos))
+	models := make([]mongo.WriteModel, len(infos))
+	newVers := make([]string, len(infos))
+	for i, info := range infos {
+		keys[i] = info.Key
+		newVers[i] = util.AddToString(info.Version, 1)
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": info.Key, "Version": info.Version}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{
+				{Key: "TxnState", Value: config.COMMITTED},
+				{Key: "Version", Value: newVers[i]},
+				{Key: "TValid", Value: tCommit},
+			}}})
+	}
+	return m.bulkWrite(keys, models, newVers, func(i int, state *batchState) bool {
+		return state.Version == newVers[i] && state.TxnState == config.COMMITTED
+	})
+}
+
 // ConditionalCommit atomically commits a transaction if the version matches.
 func (m *MongoConnection) ConditionalCommit(
 	key string,
@@ -335,18 +584,7 @@ func (m *MongoConnection) atomicCreateMongoItem(key string, value txn.DataItem)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			_, err := m.coll.InsertOne(ctx, bson.D{
-				{Key: "_id", Value: key},
-				{Key: "Value", Value: value.Value()},
-				{Key: "GroupKeyList", Value: value.GroupKeyList()},
-				{Key: "TxnState", Value: value.TxnState()},
-				{Key: "TValid", Value: value.TValid()},
-				{Key: "TLease", Value: value.TLease().Format(time.RFC3339Nano)},
-				{Key: "Prev", Value: value.Prev()},
-				{Key: "LinkedLen", Value: value.LinkedLen()},
-				{Key: "IsDeleted", Value: value.IsDeleted()},
-				{Key: "Version", Value: newVer},
-			})
+			_, err := m.coll.InsertOne(ctx, itemDocument(key, value, newVer))
 			if err != nil {
 				return "", err
 			}
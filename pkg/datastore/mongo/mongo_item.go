@@ -13,6 +13,7 @@ import (
 )
 
 var _ txn.DataItem = (*MongoItem)(nil)
+var _ txn.ExpiringItem = (*MongoItem)(nil)
 
 type MongoItem struct {
 	MKey          string       `bson:"_id"          json:"Key"`
@@ -25,6 +26,7 @@ type MongoItem struct {
 	MLinkedLen    int          `bson:"LinkedLen"    json:"LinkedLen"`
 	MIsDeleted    bool         `bson:"IsDeleted"    json:"IsDeleted"`
 	MVersion      string       `bson:"Version"      json:"Version"`
+	MTExpire      time.Time    `bson:"TExpire"      json:"TExpire,omitzero"`
 }
 
 func NewMongoItem(options txn.ItemOptions) *MongoItem {
@@ -122,6 +124,14 @@ func (m *MongoItem) SetVersion(version string) {
 	m.MVersion = version
 }
 
+func (m *MongoItem) TExpire() time.Time {
+	return m.MTExpire
+}
+
+func (m *MongoItem) SetTExpire(tExpire time.Time) {
+	m.MTExpire = tExpire
+}
+
 func (r MongoItem) String() string {
 	return fmt.Sprintf(`MongoItem{
     Key:       %s,
@@ -144,7 +154,7 @@ func (r *MongoItem) Empty() bool {
 		r.MGroupKeyList == "" && r.MTxnState == config.State(0) &&
 		r.MTValid == 0 && r.MTLease.IsZero() &&
 		r.MPrev == "" && r.MLinkedLen == 0 &&
-		!r.MIsDeleted && r.MVersion == ""
+		!r.MIsDeleted && r.MVersion == "" && r.MTExpire.IsZero()
 }
 
 func (r *MongoItem) Equal(other txn.DataItem) bool {
@@ -157,7 +167,16 @@ func (r *MongoItem) Equal(other txn.DataItem) bool {
 		r.MPrev == other.Prev() &&
 		r.MLinkedLen == other.LinkedLen() &&
 		r.MIsDeleted == other.IsDeleted() &&
-		r.MVersion == other.Version()
+		r.MVersion == other.Version() &&
+		r.MTExpire.Equal(expiryOf(other))
+}
+
+// expiryOf returns the expiry of item, or the zero time if it has none.
+func expiryOf(item txn.DataItem) time.Time {
+	if it, ok := item.(txn.ExpiringItem); ok {
+		return it.TExpire()
+	}
+	return time.Time{}
 }
 
 func (mi MongoItem) MarshalBinary() (data []byte, err error) {
@@ -176,6 +195,7 @@ func (mi MongoItem) MarshalBSONValue() (bsontype.Type, []byte, error) {
 		"LinkedLen":    mi.MLinkedLen,
 		"IsDeleted":    mi.MIsDeleted,
 		"Version":      mi.MVersion,
+		"TExpire":      mi.MTExpire.Format(time.RFC3339Nano),
 	}
 	return bson.MarshalValue(m)
 }
@@ -225,6 +245,12 @@ func (mi *MongoItem) UnmarshalBSONValue(t bsontype.Type, raw []byte) error {
 	if value, ok := m["Version"]; ok {
 		mi.MVersion = value.(string)
 	}
+	if value, ok := m["TExpire"]; ok {
+		mi.MTExpire, err = time.Parse(time.RFC3339Nano, value.(string))
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
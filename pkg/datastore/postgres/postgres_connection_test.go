@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/kkkzoz/oreo/pkg/txn/testsuite"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+var testPostgresURI string
+
+type postgresTestSuiteHelper struct{}
+
+func (h *postgresTestSuiteHelper) MakeItem(ops txn.ItemOptions) txn.DataItem {
+	return &PostgresItem{
+		PKey:          ops.Key,
+		PValue:        util.ToJSONString(ops.Value),
+		PGroupKeyList: ops.GroupKeyList,
+		PTxnState:     ops.TxnState,
+		PTValid:       ops.TValid,
+		PTLease:       ops.TLease,
+		PPrev:         ops.Prev,
+		PIsDeleted:    ops.IsDeleted,
+		PVersion:      ops.Version,
+	}
+}
+
+func (h *postgresTestSuiteHelper) NewInstance() txn.DataItem {
+	return &PostgresItem{}
+}
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+
+	req := testcontainers.ContainerRequest{
+		Image:        "postgres:16",
+		ExposedPorts: []string{"5432/tcp"},
+		Env: map[string]string{
+			"POSTGRES_USER":     "postgres",
+			"POSTGRES_PASSWORD": "postgres",
+			"POSTGRES_DB":       "oreo",
+		},
+		// the server restarts once after the init scripts
+		WaitingFor: wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).
+			WithStartupTimeout(time.Minute),
+	}
+
+	postgresContainer, err := testcontainers.GenericContainer(
+		ctx,
+		testcontainers.GenericContainerRequest{
+			ContainerRequest: req,
+			Started:          true,
+		},
+	)
+	if err != nil {
+		log.Fatalf("Could not start PostgreSQL container: %s", err)
+	}
+
+	mappedPort, _ := postgresContainer.MappedPort(ctx, "5432")
+	host, _ := postgresContainer.Host(ctx)
+	testPostgresURI = fmt.Sprintf("postgres://postgres:postgres@%s:%s/oreo", host, mappedPort.Port())
+
+	exitCode := m.Run()
+	if err := postgresContainer.Terminate(ctx); err != nil {
+		log.Fatalf("Could not stop PostgreSQL container: %s", err)
+	}
+	os.Exit(exitCode)
+}
+
+func newTestPostgresConnection() *PostgresConnection {
+	connection := NewPostgresConnection(&ConnectionOptions{
+		Address:   testPostgresURI,
+		TableName: "records",
+	})
+	if err := connection.Connect(); err != nil {
+		log.Fatalf("Could not connect to PostgreSQL: %s", err)
+	}
+	return connection
+}
+
+func TestPostgresConnector_InterfaceSuite(t *testing.T) {
+	testsuite.TestConnectorSuite(t, newTestPostgresConnection(), &postgresTestSuiteHelper{})
+}
+
+func TestPostgresConditionalCommitKeepsRecord(t *testing.T) {
+	conn := newTestPostgresConnection()
+	key := "test_key_commit_record"
+	assert.NoError(t, conn.Delete(key))
+
+	ver, err := conn.ConditionalUpdate(key, &PostgresItem{
+		PKey:          key,
+		PValue:        "value",
+		PGroupKeyList: "postgres:txn1",
+		PTxnState:     config.PREPARED,
+		PTValid:       10,
+		PLinkedLen:    1,
+	}, true)
+	assert.NoError(t, err)
+
+	ver, err = conn.ConditionalCommit(key, ver, 20)
+	assert.NoError(t, err)
+	_, err = conn.ConditionalCommit(key, "0", 30)
+	assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+	item, err := conn.GetItem(key)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", item.Value())
+	assert.Equal(t, "postgres:txn1", item.GroupKeyList())
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+	assert.Equal(t, int64(20), item.TValid())
+	assert.Equal(t, ver, item.Version())
+}
+
+func TestPostgresAtomicCreate(t *testing.T) {
+	conn := newTestPostgresConnection()
+	key := "test_key_atomic_create"
+	assert.NoError(t, conn.Delete(key))
+
+	old, err := conn.AtomicCreate(key, "first")
+	assert.NoError(t, err)
+	assert.Equal(t, "", old)
+
+	old, err = conn.AtomicCreate(key, "second")
+	assert.EqualError(t, err, txn.KeyExists.Error())
+	assert.Equal(t, "first", old)
+}
@@ -0,0 +1,7 @@
+package postgres
+
+import "github.com/kkkzoz/oreo/pkg/txn"
+
+func NewPostgresDatastore(name string, conn txn.Connector) txn.Datastorer {
+	return txn.NewDatastore(name, conn, &PostgresItemFactory{})
+}
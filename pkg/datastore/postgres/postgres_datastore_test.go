@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresTransactionAcrossTables(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conn1 := newTestPostgresConnection()
+	conn2 := NewPostgresConnection(&ConnectionOptions{
+		Address:   testPostgresURI,
+		TableName: "records2",
+	})
+	assert.NoError(t, conn2.Connect())
+	for _, key := range []string{"person", "item"} {
+		assert.NoError(t, conn1.Delete(key))
+		assert.NoError(t, conn2.Delete(key))
+	}
+
+	newTransaction := func() *txn.Transaction {
+		tx := txn.NewTransaction()
+		tx.AddDatastore(NewPostgresDatastore("postgres1", conn1))
+		tx.AddDatastore(NewPostgresDatastore("postgres2", conn2))
+		return tx
+	}
+
+	txn1 := newTransaction()
+	assert.NoError(t, txn1.Start())
+	person := testutil.NewDefaultPerson()
+	assert.NoError(t, txn1.Write("postgres1", "person", person))
+	assert.NoError(t, txn1.Write("postgres2", "item", testutil.NewTestItem("item")))
+	assert.NoError(t, txn1.Commit())
+
+	txn2 := newTransaction()
+	assert.NoError(t, txn2.Start())
+	var gotPerson testutil.Person
+	assert.NoError(t, txn2.Read("postgres1", "person", &gotPerson))
+	assert.Equal(t, person, gotPerson)
+	var gotItem testutil.TestItem
+	assert.NoError(t, txn2.Read("postgres2", "item", &gotItem))
+	assert.Equal(t, testutil.NewTestItem("item"), gotItem)
+	assert.NoError(t, txn2.Commit())
+}
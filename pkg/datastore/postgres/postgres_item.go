@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+var _ txn.DataItem = (*PostgresItem)(nil)
+var _ txn.ExpiringItem = (*PostgresItem)(nil)
+
+type PostgresItem struct {
+	PKey          string       `json:"Key"`
+	PValue        string       `json:"Value"`
+	PGroupKeyList string       `json:"GroupKeyList"`
+	PTxnState     config.State `json:"State"`
+	PTValid       int64        `json:"TValid"`
+	PTLease       time.Time    `json:"TLease"`
+	PPrev         string       `json:"Prev"`
+	PLinkedLen    int          `json:"LinkedLen"`
+	PIsDeleted    bool         `json:"IsDeleted"`
+	PVersion      string       `json:"Version,omitempty"`
+	PTExpire      time.Time    `json:"TExpire,omitzero"`
+}
+
+func NewPostgresItem(options txn.ItemOptions) *PostgresItem {
+	if options.Value == nil {
+		options.Value = ""
+	}
+
+	return &PostgresItem{
+		PKey:          options.Key,
+		PValue:        options.Value.(string),
+		PGroupKeyList: options.GroupKeyList,
+		PTxnState:     options.TxnState,
+		PTValid:       options.TValid,
+		PTLease:       options.TLease,
+		PPrev:         options.Prev,
+		PLinkedLen:    options.LinkedLen,
+		PIsDeleted:    options.IsDeleted,
+		PVersion:      options.Version,
+	}
+}
+
+func (p *PostgresItem) Key() string {
+	return p.PKey
+}
+
+func (p *PostgresItem) Value() string {
+	return p.PValue
+}
+
+func (p *PostgresItem) SetValue(value string) {
+	p.PValue = value
+}
+
+func (p *PostgresItem) GroupKeyList() string {
+	return p.PGroupKeyList
+}
+
+func (p *PostgresItem) SetGroupKeyList(groupKeyList string) {
+	p.PGroupKeyList = groupKeyList
+}
+
+func (p *PostgresItem) TxnState() config.State {
+	return p.PTxnState
+}
+
+func (p *PostgresItem) SetTxnState(state config.State) {
+	p.PTxnState = state
+}
+
+func (p *PostgresItem) TValid() int64 {
+	return p.PTValid
+}
+
+func (p *PostgresItem) SetTValid(tValid int64) {
+	p.PTValid = tValid
+}
+
+func (p *PostgresItem) TLease() time.Time {
+	return p.PTLease
+}
+
+func (p *PostgresItem) SetTLease(tLease time.Time) {
+	p.PTLease = tLease
+}
+
+func (p *PostgresItem) Prev() string {
+	return p.PPrev
+}
+
+func (p *PostgresItem) SetPrev(prev string) {
+	p.PPrev = prev
+}
+
+func (p *PostgresItem) LinkedLen() int {
+	return p.PLinkedLen
+}
+
+func (p *PostgresItem) SetLinkedLen(linkedLen int) {
+	p.PLinkedLen = linkedLen
+}
+
+func (p *PostgresItem) IsDeleted() bool {
+	return p.PIsDeleted
+}
+
+func (p *PostgresItem) SetIsDeleted(isDeleted bool) {
+	p.PIsDeleted = isDeleted
+}
+
+func (p *PostgresItem) Version() string {
+	return p.PVersion
+}
+
+func (p *PostgresItem) SetVersion(version string) {
+	p.PVersion = version
+}
+
+func (p *PostgresItem) TExpire() time.Time {
+	return p.PTExpire
+}
+
+func (p *PostgresItem) SetTExpire(tExpire time.Time) {
+	p.PTExpire = tExpire
+}
+
+func (p *PostgresItem) Equal(other txn.DataItem) bool {
+	if other == nil {
+		return false
+	}
+	otherItem, ok := other.(*PostgresItem)
+	if !ok {
+		return false
+	}
+
+	return p.Key() == otherItem.Key() &&
+		p.Value() == otherItem.Value() &&
+		p.GroupKeyList() == otherItem.GroupKeyList() &&
+		p.TxnState() == otherItem.TxnState() &&
+		p.TValid() == otherItem.TValid() &&
+		p.TLease().Equal(otherItem.TLease()) &&
+		p.Prev() == otherItem.Prev() &&
+		p.LinkedLen() == otherItem.LinkedLen() &&
+		p.IsDeleted() == otherItem.IsDeleted() &&
+		p.Version() == otherItem.Version() &&
+		p.TExpire().Equal(otherItem.TExpire())
+}
+
+func (p *PostgresItem) Empty() bool {
+	return p == nil || (p.Key() == "" && p.Value() == "")
+}
+
+func (p *PostgresItem) String() string {
+	return fmt.Sprintf(`PostgresItem{
+    Key:       %s,
+    Value:     %s,
+    GroupKeyList:     %s,
+    TxnState:  %s,
+    TValid:    %v,
+    TLease:    %s,
+    Prev:      %s,
+    LinkedLen: %d,
+    IsDeleted: %v,
+    Version:   %s,
+}`, p.PKey, p.PValue, p.PGroupKeyList, util.ToString(p.PTxnState),
+		p.PTValid, p.PTLease.Format(time.RFC3339),
+		p.PPrev, p.PLinkedLen, p.PIsDeleted, p.PVersion)
+}
@@ -0,0 +1,11 @@
+package postgres
+
+import "github.com/kkkzoz/oreo/pkg/txn"
+
+var _ txn.DataItemFactory = (*PostgresItemFactory)(nil)
+
+type PostgresItemFactory struct{}
+
+func (p *PostgresItemFactory) NewDataItem(options txn.ItemOptions) txn.DataItem {
+	return NewPostgresItem(options)
+}
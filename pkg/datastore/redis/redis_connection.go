@@ -2,6 +2,8 @@ package redis
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-errors/errors"
@@ -14,30 +16,49 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-// RedisConnection implements the txn.Connector interface.
-var _ txn.Connector = (*RedisConnection)(nil)
-
+// RedisConnection implements the txn.BatchConnector interface.
+var _ txn.BatchConnector = (*RedisConnection)(nil)
+var _ txn.Expirer = (*RedisConnection)(nil)
+var _ txn.KeyScanner = (*RedisConnection)(nil)
+
+// RedisConnection works with a single Redis or KVRocks server, a Redis Cluster
+// or a master monitored by Redis Sentinel.
+//
+// Every operation touches exactly one key, which its script gets in KEYS,
+// so a record and each of its group keys are always handled by single-slot
+// commands on a Redis Cluster. The scripts are run by SHA and reloaded when
+// a node answers NOSCRIPT, e.g. a replica promoted after a failover.
 type RedisConnection struct {
-	rdb                  *redis.Client
-	Address              string
-	se                   serializer.Serializer
-	connected            bool
-	atomicCreateSHA      string
-	atomicCreateItemSHA  string
-	conditionalUpdateSHA string
-	conditionalCommitSHA string
+	rdb       redis.UniversalClient
+	Address   string
+	keyPrefix string
+	se        serializer.Serializer
+	connected bool
 }
 
 type ConnectionOptions struct {
-	Address  string
-	Password string
-	se       serializer.Serializer
-	PoolSize int
+	Address string
+	// Addresses are the seed nodes of a Redis Cluster, or the sentinels
+	// if MasterName is set. They take precedence over Address.
+	Addresses []string
+	// MasterName is the name of the master monitored by the sentinels.
+	// Setting it connects through Redis Sentinel.
+	MasterName string
+	// Cluster connects to a Redis Cluster even with a single seed address.
+	Cluster bool
+	// KeyPrefix lays out the keys as "<KeyPrefix>:{<name>}", so the slot of
+	// a key only depends on its name, whatever braces the prefix contains.
+	// It is empty by default, which keeps the names as they are.
+	KeyPrefix        string
+	Password         string
+	SentinelPassword string
+	se               serializer.Serializer
+	PoolSize         int
 }
 
 const AtomicCreateScript = `
 if redis.call('EXISTS', KEYS[1]) == 0 then
-	return redis.call('SET',ARGV[1] , ARGV[2])
+	return redis.call('SET', KEYS[1], ARGV[2])
 else
 	return redis.error_reply('already exists')
 end
@@ -55,6 +76,7 @@ if redis.call('EXISTS', KEYS[1]) == 0 then
 	redis.call('HSET', KEYS[1], 'Prev', ARGV[9])
 	redis.call('HSET', KEYS[1], 'LinkedLen', ARGV[10])
 	redis.call('HSET', KEYS[1], 'IsDeleted', ARGV[11])
+	redis.call('HSET', KEYS[1], 'TExpire', ARGV[12])
 	return redis.call('HGETALL', KEYS[1])
 else
 	return redis.error_reply('version mismatch')
@@ -73,6 +95,8 @@ if redis.call('HGET', KEYS[1], 'Version') == ARGV[1] then
 	redis.call('HSET', KEYS[1], 'Prev', ARGV[9])
 	redis.call('HSET', KEYS[1], 'LinkedLen', ARGV[10])
 	redis.call('HSET', KEYS[1], 'IsDeleted', ARGV[11])
+	redis.call('HSET', KEYS[1], 'TExpire', ARGV[12])
+	redis.call('PERSIST', KEYS[1])
 	return redis.call('HGETALL', KEYS[1])
 else
 	return redis.error_reply('version mismatch')
@@ -90,6 +114,22 @@ else
 end
 `
 
+const ExpireAtScript = `
+if redis.call('HGET', KEYS[1], 'Version') == ARGV[1] then
+	return redis.call('PEXPIREAT', KEYS[1], ARGV[2])
+else
+	return redis.error_reply('version mismatch')
+end
+`
+
+var (
+	atomicCreateScript      = redis.NewScript(AtomicCreateScript)
+	atomicCreateItemScript  = redis.NewScript(AtomicCreateItemScript)
+	conditionalUpdateScript = redis.NewScript(ConditionalUpdateScript)
+	conditionalCommitScript = redis.NewScript(ConditionalCommitScript)
+	expireAtScript          = redis.NewScript(ExpireAtScript)
+)
+
 var defaultOptions = ConnectionOptions{
 	Address:  "localhost:6379",
 	Password: "",
@@ -108,9 +148,16 @@ func NewRedisConnection(config *ConnectionOptions) *RedisConnection {
 		if config.Address != "" {
 			finalConfig.Address = config.Address
 		}
+		if len(config.Addresses) > 0 {
+			finalConfig.Addresses = config.Addresses
+		}
+		finalConfig.MasterName = config.MasterName
+		finalConfig.Cluster = config.Cluster
+		finalConfig.KeyPrefix = config.KeyPrefix
 		if config.Password != "" {
 			finalConfig.Password = config.Password
 		}
+		finalConfig.SentinelPassword = config.SentinelPassword
 		if config.se != nil {
 			finalConfig.se = config.se
 		}
@@ -120,19 +167,51 @@ func NewRedisConnection(config *ConnectionOptions) *RedisConnection {
 		}
 	}
 
+	addrs := finalConfig.Addresses
+	if len(addrs) == 0 {
+		addrs = []string{finalConfig.Address}
+	}
+	opts := &redis.UniversalOptions{
+		Addrs:            addrs,
+		MasterName:       finalConfig.MasterName,
+		Password:         finalConfig.Password,
+		SentinelPassword: finalConfig.SentinelPassword,
+		PoolSize:         finalConfig.PoolSize,
+		PoolTimeout:      10 * time.Second,
+	}
+	var rdb redis.UniversalClient
+	if finalConfig.Cluster && finalConfig.MasterName == "" {
+		rdb = redis.NewClusterClient(opts.Cluster())
+	} else {
+		rdb = redis.NewUniversalClient(opts)
+	}
+
 	return &RedisConnection{
-		rdb: redis.NewClient(&redis.Options{
-			Addr:        finalConfig.Address,
-			Password:    finalConfig.Password,
-			PoolSize:    finalConfig.PoolSize,
-			PoolTimeout: 10 * time.Second,
-		}),
-		Address: finalConfig.Address,
-		se:      finalConfig.se,
+		rdb:       rdb,
+		Address:   strings.Join(addrs, ","),
+		keyPrefix: finalConfig.KeyPrefix,
+		se:        finalConfig.se,
+	}
+}
+
+// key returns the Redis key holding name.
+func (r *RedisConnection) key(name string) string {
+	if r.keyPrefix == "" {
+		return name
+	}
+	return r.keyPrefix + ":{" + name + "}"
+}
+
+// name returns the name laid out as key by the key method.
+func (r *RedisConnection) name(key string) string {
+	if r.keyPrefix == "" {
+		return key
 	}
+	return strings.TrimSuffix(strings.TrimPrefix(key, r.keyPrefix+":{"), "}")
 }
 
 // Connect establishes a connection to the Redis server and loads Lua scripts.
+// On a Redis Cluster, the scripts are loaded on every master.
 func (r *RedisConnection) Connect() error {
 	if r.connected {
 		return nil
@@ -142,30 +221,23 @@ func (r *RedisConnection) Connect() error {
 	logger.Log.Debugw("Start Connect", "address", r.Address)
 	defer logger.Log.Debugw("End   Connect", "address", r.Address)
 
-	// Define all scripts and their destinations in one place.
-	scriptsToLoad := []struct {
-		scriptContent string
-		destSHA       *string
-	}{
-		{scriptContent: AtomicCreateScript, destSHA: &r.atomicCreateSHA},
-		{scriptContent: AtomicCreateItemScript, destSHA: &r.atomicCreateItemSHA},
-		{scriptContent: ConditionalUpdateScript, destSHA: &r.conditionalUpdateSHA},
-		{scriptContent: ConditionalCommitScript, destSHA: &r.conditionalCommitSHA},
+	scriptsToLoad := []*redis.Script{
+		atomicCreateScript,
+		atomicCreateItemScript,
+		conditionalUpdateScript,
+		conditionalCommitScript,
+		expireAtScript,
 	}
 
 	var eg errgroup.Group
 	ctx := context.Background()
 
 	// Iterate and load each script concurrently.
+	// The SHAs are known in advance, so loading only warms up the servers.
 	for _, s := range scriptsToLoad {
 		script := s // Capture loop variable for the closure.
 		eg.Go(func() error {
-			sha, err := r.rdb.ScriptLoad(ctx, script.scriptContent).Result()
-			if err != nil {
-				return err
-			}
-			*script.destSHA = sha // Store the result in the destination field.
-			return nil
+			return script.Load(ctx, r.rdb).Err()
 		})
 	}
 
@@ -180,7 +252,7 @@ func (r *RedisConnection) GetItem(key string) (txn.DataItem, error) {
 	}
 
 	var value RedisItem
-	err := r.rdb.HGetAll(context.Background(), key).Scan(&value)
+	err := r.rdb.HGetAll(context.Background(), r.key(key)).Scan(&value)
 	if err != nil {
 		return &RedisItem{}, err
 	}
@@ -198,6 +270,7 @@ func (r *RedisConnection) PutItem(key string, value txn.DataItem) (string, error
 	}
 
 	ctx := context.Background()
+	key = r.key(key)
 	_, err := r.rdb.Pipelined(ctx, func(rdb redis.Pipeliner) error {
 		rdb.HSet(ctx, key, "Key", value.Key())
 		rdb.HSet(ctx, key, "Value", value.Value())
@@ -209,6 +282,8 @@ func (r *RedisConnection) PutItem(key string, value txn.DataItem) (string, error
 		rdb.HSet(ctx, key, "LinkedLen", value.LinkedLen())
 		rdb.HSet(ctx, key, "IsDeleted", value.IsDeleted())
 		rdb.HSet(ctx, key, "Version", value.Version())
+		rdb.HSet(ctx, key, "TExpire", expiryOf(value).Format(time.RFC3339Nano))
+		rdb.Persist(ctx, key)
 		return nil
 	})
 	if err != nil {
@@ -233,10 +308,8 @@ func (r *RedisConnection) ConditionalUpdate(
 		ctx := context.Background()
 		newVer := util.AddToString(value.Version(), 1)
 
-		_, err := r.rdb.EvalSha(ctx, r.atomicCreateItemSHA, []string{value.Key()}, value.Version(), value.Key(),
-			value.Value(), value.GroupKeyList(), value.TxnState(), value.TValid(), value.TLease(),
-			newVer, value.Prev(), value.LinkedLen(), value.IsDeleted()).
-			Result()
+		_, err := atomicCreateItemScript.Run(ctx, r.rdb, []string{r.key(key)},
+			updateArgs(value, newVer)...).Result()
 		if err != nil {
 			if err.Error() == "version mismatch" {
 				logger.Log.Warnw(
@@ -258,10 +331,8 @@ func (r *RedisConnection) ConditionalUpdate(
 	ctx := context.Background()
 	newVer := util.AddToString(value.Version(), 1)
 
-	_, err := r.rdb.EvalSha(ctx, r.conditionalUpdateSHA, []string{value.Key()}, value.Version(), value.Key(),
-		value.Value(), value.GroupKeyList(), value.TxnState(), value.TValid(), value.TLease(),
-		newVer, value.Prev(), value.LinkedLen(), value.IsDeleted()).
-		Result()
+	_, err := conditionalUpdateScript.Run(ctx, r.rdb, []string{r.key(key)},
+		updateArgs(value, newVer)...).Result()
 	if err != nil {
 		if err.Error() == "version mismatch" {
 			logger.Log.Warnw(
@@ -278,6 +349,95 @@ func (r *RedisConnection) ConditionalUpdate(
 	return newVer, nil
 }
 
+// updateArgs returns the arguments of the scripts writing value with newVer.
+func updateArgs(value txn.DataItem, newVer string) []any {
+	return []any{
+		value.Version(), value.Key(),
+		value.Value(), value.GroupKeyList(), value.TxnState(), value.TValid(), value.TLease(),
+		newVer, value.Prev(), value.LinkedLen(), value.IsDeleted(), expiryOf(value),
+	}
+}
+
+// runPipelined runs the scripts of a batch by SHA in a single pipeline.
+// The ones answered with NOSCRIPT are run again one by one,
+// which loads the script on the node.
+func (r *RedisConnection) runPipelined(
+	scripts []*redis.Script,
+	keys []string,
+	args [][]any,
+	newVers []string,
+) []txn.BatchResult {
+	ctx := context.Background()
+	pipe := r.rdb.Pipeline()
+	cmds := make([]*redis.Cmd, len(scripts))
+	for i, script := range scripts {
+		cmds[i] = script.EvalSha(ctx, pipe, []string{r.key(keys[i])}, args[i]...)
+	}
+	// the errors are read from each command
+	_, _ = pipe.Exec(ctx)
+
+	results := make([]txn.BatchResult, len(scripts))
+	for i, cmd := range cmds {
+		err := cmd.Err()
+		if redis.HasErrorPrefix(err, "NOSCRIPT") {
+			err = scripts[i].Run(ctx, r.rdb, []string{r.key(keys[i])}, args[i]...).Err()
+		}
+		switch {
+		case err == nil:
+			results[i] = txn.BatchResult{Version: newVers[i]}
+		case err.Error() == "version mismatch":
+			results[i] = txn.BatchResult{Err: errors.New(txn.VersionMismatch)}
+		default:
+			results[i] = txn.BatchResult{Err: err}
+		}
+	}
+	return results
+}
+
+// ConditionalUpdateBatch sends the conditional updates in a single pipeline.
+func (r *RedisConnection) ConditionalUpdateBatch(ops []txn.UpdateOp) []txn.BatchResult {
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+
+	scripts := make([]*redis.Script, len(ops))
+	keys := make([]string, len(ops))
+	args := make([][]any, len(ops))
+	newVers := make([]string, len(ops))
+	for i, op := range ops {
+		scripts[i] = conditionalUpdateScript
+		if op.DoCreate {
+			scripts[i] = atomicCreateItemScript
+		}
+		keys[i] = op.Key
+		newVers[i] = util.AddToString(op.Value.Version(), 1)
+		args[i] = updateArgs(op.Value, newVers[i])
+	}
+	return r.runPipelined(scripts, keys, args, newVers)
+}
+
+// ConditionalCommitBatch sends the conditional commits in a single pipeline.
+func (r *RedisConnection) ConditionalCommitBatch(
+	infos []txn.CommitInfo,
+	tCommit int64,
+) []txn.BatchResult {
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+
+	scripts := make([]*redis.Script, len(infos))
+	keys := make([]string, len(infos))
+	args := make([][]any, len(infos))
+	newVers := make([]string, len(infos))
+	for i, info := range infos {
+		scripts[i] = conditionalCommitScript
+		keys[i] = info.Key
+		newVers[i] = util.AddToString(info.Version, 1)
+		args[i] = []any{info.Version, config.COMMITTED, newVers[i], tCommit}
+	}
+	return r.runPipelined(scripts, keys, args, newVers)
+}
+
 // ConditionalCommit atomically commits a transaction if the version matches.
 func (r *RedisConnection) ConditionalCommit(
 	key string,
@@ -291,8 +451,8 @@ func (r *RedisConnection) ConditionalCommit(
 	ctx := context.Background()
 	newVer := util.AddToString(version, 1)
 
-	_, err := r.rdb.EvalSha(ctx, r.conditionalCommitSHA,
-		[]string{key}, version, config.COMMITTED, newVer, tCommit).Result()
+	_, err := conditionalCommitScript.Run(ctx, r.rdb,
+		[]string{r.key(key)}, version, config.COMMITTED, newVer, tCommit).Result()
 	if err != nil {
 		if err.Error() == "version mismatch" {
 			return "", errors.New(txn.VersionMismatch)
@@ -309,8 +469,8 @@ func (r *RedisConnection) AtomicCreate(name string, value any) (string, error) {
 	}
 
 	ctx := context.Background()
-	_, err := r.rdb.
-		EvalSha(ctx, r.atomicCreateSHA, []string{name}, name, value).Result()
+	_, err := atomicCreateScript.
+		Run(ctx, r.rdb, []string{r.key(name)}, name, value).Result()
 	if err != nil {
 		if err.Error() == "already exists" {
 			old, err := r.Get(name)
@@ -331,7 +491,7 @@ func (r *RedisConnection) Get(name string) (string, error) {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	str, err := r.rdb.Get(context.Background(), name).Result()
+	str, err := r.rdb.Get(context.Background(), r.key(name)).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return "", errors.New(txn.KeyNotFound)
@@ -347,7 +507,7 @@ func (r *RedisConnection) Put(name string, value any) error {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	return r.rdb.Set(context.Background(), name, value, 0).Err()
+	return r.rdb.Set(context.Background(), r.key(name), value, 0).Err()
 }
 
 // Delete removes a key-value pair.
@@ -356,7 +516,63 @@ func (r *RedisConnection) Delete(name string) error {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	return r.rdb.Del(context.Background(), name).Err()
+	return r.rdb.Del(context.Background(), r.key(name)).Err()
+}
+
+// ExpireAt makes the record key expire at expireAt with PEXPIREAT,
+// unless it is no longer at version.
+// The next ConditionalUpdate of the record clears the expiry.
+func (r *RedisConnection) ExpireAt(key string, version string, expireAt time.Time) error {
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+
+	err := expireAtScript.Run(context.Background(), r.rdb,
+		[]string{r.key(key)}, version, expireAt.UnixMilli()).Err()
+	if err != nil && err.Error() == "version mismatch" {
+		return errors.New(txn.VersionMismatch)
+	}
+	return err
+}
+
+// ScanKeys calls fn with the key of every record matching the prefix of the
+// connection. The records are hashes, so the group keys and the other plain
+// values are skipped. On a Redis Cluster, the masters are scanned in turn,
+// so fn is never called concurrently.
+func (r *RedisConnection) ScanKeys(fn func(key string) error) error {
+	ctx := context.Background()
+	scan := func(client *redis.Client) error {
+		iter := client.ScanType(ctx, 0, r.key("*"), 0, "hash").Iterator()
+		for iter.Next(ctx) {
+			if err := fn(r.name(iter.Val())); err != nil {
+				return err
+			}
+		}
+		return iter.Err()
+	}
+	if cluster, ok := r.rdb.(*redis.ClusterClient); ok {
+		var mu sync.Mutex
+		var masters []*redis.Client
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			mu.Lock()
+			defer mu.Unlock()
+			masters = append(masters, client)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, client := range masters {
+			if err := scan(client); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if client, ok := r.rdb.(*redis.Client); ok {
+		return scan(client)
+	}
+	return errors.New("unsupported redis client")
 }
 
 // Close disconnects from the Redis server.
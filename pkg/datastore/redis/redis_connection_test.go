@@ -10,6 +10,7 @@ import (
 	"github.com/kkkzoz/oreo/internal/util"
 	"github.com/kkkzoz/oreo/pkg/txn"
 	"github.com/kkkzoz/oreo/pkg/txn/testsuite"
+	"github.com/stretchr/testify/assert"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
@@ -80,3 +81,64 @@ func newTestRedisConnection() *RedisConnection {
 func TestRedisConnector_InterfaceSuite(t *testing.T) {
 	testsuite.TestConnectorSuite(t, newTestRedisConnection(), &redisTestSuiteHelper{})
 }
+
+func TestRedisConnectionReloadsScripts(t *testing.T) {
+	conn := newTestRedisConnection()
+	_ = conn.Delete("reload")
+
+	// a failover promotes a replica without the scripts
+	assert.NoError(t, conn.rdb.ScriptFlush(context.Background()).Err())
+
+	ver, err := conn.ConditionalUpdate("reload", &RedisItem{RKey: "reload", RValue: "v1"}, true)
+	assert.NoError(t, err)
+	_, err = conn.ConditionalCommit("reload", ver, 10)
+	assert.NoError(t, err)
+	item, err := conn.GetItem("reload")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", item.Value())
+}
+
+func TestRedisConnectionKeyPrefix(t *testing.T) {
+	conn := NewRedisConnection(&ConnectionOptions{
+		Address:   testRedisURI,
+		KeyPrefix: "oreo",
+	})
+	assert.NoError(t, conn.Connect())
+	_ = conn.Delete("record")
+	_ = conn.Delete("redis:txn1")
+
+	_, err := conn.ConditionalUpdate("record", &RedisItem{RKey: "record", RValue: "v1"}, true)
+	assert.NoError(t, err)
+	_, err = conn.AtomicCreate("redis:txn1", "committed")
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	assert.Equal(t, int64(1), conn.rdb.Exists(ctx, "oreo:{record}").Val())
+	assert.Equal(t, "committed", conn.rdb.Get(ctx, "oreo:{redis:txn1}").Val())
+	item, err := conn.GetItem("record")
+	assert.NoError(t, err)
+	assert.Equal(t, "record", item.Key())
+}
+
+func TestRedisConnectionScanKeys(t *testing.T) {
+	conn := NewRedisConnection(&ConnectionOptions{
+		Address:   testRedisURI,
+		KeyPrefix: "scan",
+	})
+	assert.NoError(t, conn.Connect())
+	_ = conn.Delete("record")
+	_ = conn.Delete("redis:txn1")
+
+	_, err := conn.ConditionalUpdate("record", &RedisItem{RKey: "record", RValue: "v1"}, true)
+	assert.NoError(t, err)
+	_, err = conn.AtomicCreate("redis:txn1", "committed")
+	assert.NoError(t, err)
+
+	// the group key is not a record
+	var keys []string
+	assert.NoError(t, conn.ScanKeys(func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"record"}, keys)
+}
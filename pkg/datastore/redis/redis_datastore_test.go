@@ -1793,3 +1793,139 @@ func TestItemVersionUpdate(t *testing.T) {
 		assert.Equal(t, util.AddToString(dbItem.Version(), 2), res.Version())
 	})
 }
+
+func TestSerializableRejectsWriteSkew(t *testing.T) {
+	run := func(level config.IsolationLevel) (error, error) {
+		config.Config.IsolationLevel = level
+		defer func() { config.Config.IsolationLevel = config.SnapshotIsolation }()
+
+		conn := newTestRedisConnection()
+		for _, key := range []string{"item1", "item2"} {
+			dbItem := &RedisItem{
+				RKey:          key,
+				RValue:        util.ToJSONString(testutil.NewTestItem(key)),
+				RGroupKeyList: "TestSerializableRejectsWriteSkew",
+				RTxnState:     config.COMMITTED,
+				RTValid:       time.Now().Add(-10 * time.Second).UnixMicro(),
+				RLinkedLen:    1,
+				RVersion:      "1",
+			}
+			conn.PutItem(dbItem.Key(), dbItem)
+		}
+
+		// both transactions read both items and write a different one
+		txn1 := NewTransactionWithSetup()
+		txn2 := NewTransactionWithSetup()
+		txn1.Start()
+		txn2.Start()
+		var item testutil.TestItem
+		for _, txn := range []*trxn.Transaction{txn1, txn2} {
+			assert.NoError(t, txn.Read("redis", "item1", &item))
+			assert.NoError(t, txn.Read("redis", "item2", &item))
+		}
+		txn1.Write("redis", "item1", testutil.NewTestItem("item1-txn1"))
+		txn2.Write("redis", "item2", testutil.NewTestItem("item2-txn2"))
+		return txn1.Commit(), txn2.Commit()
+	}
+
+	t.Run("snapshot isolation", func(t *testing.T) {
+		err1, err2 := run(config.SnapshotIsolation)
+		assert.NoError(t, err1)
+		assert.NoError(t, err2)
+	})
+
+	t.Run("serializable", func(t *testing.T) {
+		err1, err2 := run(config.Serializable)
+		assert.NoError(t, err1)
+		assert.ErrorContains(t, err2, trxn.ReadConflict.Error())
+	})
+}
+
+func TestSerializableReadOnlyValidatesAbsentKey(t *testing.T) {
+	config.Config.IsolationLevel = config.Serializable
+	defer func() { config.Config.IsolationLevel = config.SnapshotIsolation }()
+
+	key := "TestSerializableReadOnlyValidatesAbsentKey"
+	conn := newTestRedisConnection()
+	conn.Delete(key)
+
+	txn1 := NewTransactionWithSetup()
+	txn1.Start()
+	var item testutil.TestItem
+	err := txn1.Read("redis", key, &item)
+	assert.EqualError(t, err, trxn.KeyNotFound.Error()+" at GetItem in redis")
+
+	txn2 := NewTransactionWithSetup()
+	txn2.Start()
+	txn2.Write("redis", key, testutil.NewTestItem(key))
+	assert.NoError(t, txn2.Commit())
+
+	err = txn1.Commit()
+	assert.ErrorContains(t, err, trxn.ReadConflict.Error())
+}
+
+func TestConditionalWrites(t *testing.T) {
+	key := "TestConditionalWrites"
+	conn := newTestRedisConnection()
+	conn.Delete(key)
+
+	t.Run("update on absent key fails", func(t *testing.T) {
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		assert.NoError(t, txn.Update("redis", key, testutil.NewTestItem("item1")))
+		err := txn.Commit()
+		assert.True(t, trxn.IsConditionFailed(err))
+		assert.ErrorContains(t, err, trxn.KeyNotExists.Error())
+	})
+
+	t.Run("insert on absent key succeeds", func(t *testing.T) {
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		assert.NoError(t, txn.Insert("redis", key, testutil.NewTestItem("item1")))
+		assert.NoError(t, txn.Commit())
+	})
+
+	t.Run("insert on existing key fails", func(t *testing.T) {
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		assert.NoError(t, txn.Insert("redis", key, testutil.NewTestItem("item2")))
+		err := txn.Commit()
+		assert.True(t, trxn.IsConditionFailed(err))
+		assert.ErrorContains(t, err, trxn.KeyAlreadyExists.Error())
+	})
+
+	t.Run("compare-and-set with unexpected value fails", func(t *testing.T) {
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		err := txn.CompareAndSet("redis", key,
+			testutil.NewTestItem("item2"), testutil.NewTestItem("item3"))
+		assert.NoError(t, err)
+		err = txn.Commit()
+		assert.True(t, trxn.IsConditionFailed(err))
+		assert.ErrorContains(t, err, trxn.UnexpectedValue.Error())
+	})
+
+	t.Run("compare-and-set with expected value succeeds", func(t *testing.T) {
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		err := txn.CompareAndSet("redis", key,
+			testutil.NewTestItem("item1"), testutil.NewTestItem("item3"))
+		assert.NoError(t, err)
+		assert.NoError(t, txn.Commit())
+
+		txn = NewTransactionWithSetup()
+		txn.Start()
+		var item testutil.TestItem
+		assert.NoError(t, txn.Read("redis", key, &item))
+		assert.Equal(t, "item3", item.Value)
+	})
+
+	t.Run("condition on a key written in the same transaction", func(t *testing.T) {
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		assert.NoError(t, txn.Update("redis", key, testutil.NewTestItem("item4")))
+		err := txn.Insert("redis", key, testutil.NewTestItem("item5"))
+		assert.True(t, trxn.IsConditionFailed(err))
+		assert.NoError(t, txn.Commit())
+	})
+}
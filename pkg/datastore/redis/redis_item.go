@@ -11,6 +11,7 @@ import (
 )
 
 var _ txn.DataItem = (*RedisItem)(nil)
+var _ txn.ExpiringItem = (*RedisItem)(nil)
 
 type RedisItem struct {
 	RKey          string       `redis:"Key"          json:"Key"`
@@ -23,6 +24,7 @@ type RedisItem struct {
 	RLinkedLen    int          `redis:"LinkedLen"    json:"LinkedLen"`
 	RIsDeleted    bool         `redis:"IsDeleted"    json:"IsDeleted"`
 	RVersion      string       `redis:"Version"      json:"Version"`
+	RTExpire      time.Time    `redis:"TExpire"      json:"TExpire,omitzero"`
 }
 
 func NewRedisItem(options txn.ItemOptions) *RedisItem {
@@ -116,6 +118,14 @@ func (r *RedisItem) SetVersion(v string) {
 	r.RVersion = v
 }
 
+func (r *RedisItem) TExpire() time.Time {
+	return r.RTExpire
+}
+
+func (r *RedisItem) SetTExpire(t time.Time) {
+	r.RTExpire = t
+}
+
 func (r RedisItem) String() string {
 	return fmt.Sprintf(`RedisItem{
     Key:       %s,
@@ -138,7 +148,7 @@ func (r *RedisItem) Empty() bool {
 		r.RGroupKeyList == "" && r.RTxnState == config.State(0) &&
 		r.RTValid == 0 && r.RTLease.IsZero() &&
 		r.RPrev == "" && r.RLinkedLen == 0 &&
-		!r.RIsDeleted && r.RVersion == ""
+		!r.RIsDeleted && r.RVersion == "" && r.RTExpire.IsZero()
 }
 
 func (r *RedisItem) Equal(other txn.DataItem) bool {
@@ -151,7 +161,16 @@ func (r *RedisItem) Equal(other txn.DataItem) bool {
 		r.Prev() == other.Prev() &&
 		r.LinkedLen() == other.LinkedLen() &&
 		r.IsDeleted() == other.IsDeleted() &&
-		r.Version() == other.Version()
+		r.Version() == other.Version() &&
+		r.TExpire().Equal(expiryOf(other))
+}
+
+// expiryOf returns the expiry of item, or the zero time if it has none.
+func expiryOf(item txn.DataItem) time.Time {
+	if it, ok := item.(txn.ExpiringItem); ok {
+		return it.TExpire()
+	}
+	return time.Time{}
 }
 
 func (r RedisItem) MarshalBinary() (data []byte, err error) {
@@ -0,0 +1,489 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kkkzoz/oreo/pkg/config"
+)
+
+// AnomalyType is the type of an anomaly found in a history.
+type AnomalyType string
+
+const (
+	// G0 (dirty write) is a cycle of write dependencies.
+	G0 AnomalyType = "G0"
+	// G1a (aborted read) is a committed transaction reading a version
+	// written by an aborted transaction.
+	G1a AnomalyType = "G1a"
+	// G1b (intermediate read) is a committed transaction reading a version
+	// that is not the final write of its writer.
+	G1b AnomalyType = "G1b"
+	// G1c (circular information flow) is a cycle of write and read
+	// dependencies.
+	G1c AnomalyType = "G1c"
+	// LostUpdate is two committed transactions reading the same version of
+	// a record and both writing it.
+	LostUpdate AnomalyType = "lost-update"
+	// ReadSkew (G-single) is a cycle with exactly one anti-dependency.
+	ReadSkew AnomalyType = "read-skew"
+	// WriteSkew (G2) is a cycle with two or more anti-dependencies.
+	WriteSkew AnomalyType = "write-skew"
+)
+
+// ProhibitedBy reports whether the anomaly is prohibited by the isolation
+// level. Snapshot isolation allows write skew only, and serializability
+// allows none of the anomalies.
+func (a AnomalyType) ProhibitedBy(level config.IsolationLevel) bool {
+	if a == WriteSkew {
+		return level == config.Serializable
+	}
+	return true
+}
+
+// Anomaly is an anomaly found in a history.
+type Anomaly struct {
+	Type AnomalyType `json:"type"`
+	// Txns are the ids of the transactions involved,
+	// in the order of the cycle for the cycles.
+	Txns        []string `json:"txns"`
+	Description string   `json:"description"`
+}
+
+// Report is the result of checking a history.
+type Report struct {
+	Level config.IsolationLevel
+	// Txns is the number of transactions checked, i.e. the committed
+	// transactions and the transactions with an unknown outcome whose
+	// writes have been read.
+	Txns      int
+	Anomalies []Anomaly
+}
+
+// Violations returns the anomalies prohibited by the isolation level.
+func (r *Report) Violations() []Anomaly {
+	var violations []Anomaly
+	for _, a := range r.Anomalies {
+		if a.Type.ProhibitedBy(r.Level) {
+			violations = append(violations, a)
+		}
+	}
+	return violations
+}
+
+// Valid reports whether the history has no anomaly prohibited by the
+// isolation level.
+func (r *Report) Valid() bool {
+	return len(r.Violations()) == 0
+}
+
+// Check checks the history h for the anomalies, to be judged against the
+// isolation level.
+//
+// The versions are identified by the group key lists observed by the reads,
+// and ordered by the writes that follow reads: a transaction that read a
+// version of a record and then wrote the record installed the next version.
+// The blind writes are not ordered, so they only take part in the
+// dependencies through the reads of their versions.
+func Check(h History, level config.IsolationLevel) *Report {
+	c := newChecker(h)
+	c.checkReads()
+	c.buildGraph()
+	c.checkLostUpdates()
+	c.checkCycles()
+	return &Report{Level: level, Txns: len(c.nodes), Anomalies: c.anomalies}
+}
+
+type edgeKind uint8
+
+const (
+	ww edgeKind = 1 << iota
+	wr
+	rw
+)
+
+func (k edgeKind) String() string {
+	switch {
+	case k&ww != 0:
+		return "ww"
+	case k&wr != 0:
+		return "wr"
+	default:
+		return "rw"
+	}
+}
+
+type edge struct {
+	to   int
+	kind edgeKind
+}
+
+// read is an external read of a record.
+type read struct {
+	record string
+	op     Op
+}
+
+type checker struct {
+	h    History
+	byId map[string]int
+	// reads and writes are the external reads and the final writes
+	// of the transactions, keyed by the record.
+	reads  [][]read
+	writes []map[string]Op
+	// intermediate is the values written before the final writes.
+	intermediate []map[string][]string
+
+	// nodes are the transactions in the dependency graph.
+	nodes  []int
+	nodeOf map[string]int
+	out    [][]edge
+	// successors are the transactions installing the version following
+	// the version written by a transaction, keyed by the record and the
+	// id of the writer.
+	successors map[string]map[string][]int
+	// updated is the set of the pairs of nodes installing versions
+	// following the same version.
+	updated map[[2]int]bool
+
+	anomalies []Anomaly
+}
+
+func newChecker(h History) *checker {
+	c := &checker{
+		h:            h,
+		byId:         make(map[string]int, len(h)),
+		reads:        make([][]read, len(h)),
+		writes:       make([]map[string]Op, len(h)),
+		intermediate: make([]map[string][]string, len(h)),
+		nodeOf:       make(map[string]int),
+		successors:   make(map[string]map[string][]int),
+		updated:      make(map[[2]int]bool),
+	}
+	for i, t := range h {
+		c.byId[t.Id] = i
+		seen := make(map[string]bool)
+		c.writes[i] = make(map[string]Op)
+		c.intermediate[i] = make(map[string][]string)
+		for _, op := range t.Ops {
+			record := op.Datastore + "/" + op.Key
+			switch {
+			case op.Type == OpWrite:
+				if prev, ok := c.writes[i][record]; ok {
+					c.intermediate[i][record] = append(c.intermediate[i][record], prev.Value)
+				}
+				c.writes[i][record] = op
+			case !op.Internal && !seen[record]:
+				seen[record] = true
+				c.reads[i] = append(c.reads[i], read{record: record, op: op})
+			}
+		}
+	}
+	return c
+}
+
+// writer returns the index of the transaction that wrote the version
+// observed by r, or false if it is not in the history.
+func (c *checker) writer(r read) (int, bool) {
+	w, ok := c.byId[WriterOf(r.op.Version)]
+	return w, ok
+}
+
+// checkReads checks the reads of the committed transactions for G1a and G1b.
+func (c *checker) checkReads() {
+	for i, t := range c.h {
+		if t.Outcome != Committed {
+			continue
+		}
+		for _, r := range c.reads[i] {
+			w, ok := c.writer(r)
+			if !ok || w == i {
+				continue
+			}
+			writer := c.h[w]
+			if writer.Outcome == Aborted {
+				c.report(G1a, []string{t.Id, writer.Id},
+					"%s read %s from aborted %s", t.Id, r.record, writer.Id)
+				continue
+			}
+			final, ok := c.writes[w][r.record]
+			if !ok || r.op.Absent || final.Value == r.op.Value {
+				continue
+			}
+			for _, value := range c.intermediate[w][r.record] {
+				if value == r.op.Value {
+					c.report(G1b, []string{t.Id, writer.Id},
+						"%s read an intermediate value of %s written by %s", t.Id, r.record, writer.Id)
+					break
+				}
+			}
+		}
+	}
+}
+
+// buildGraph builds the dependency graph of the committed transactions,
+// and of the transactions with an unknown outcome whose writes have been
+// read by a committed transaction.
+func (c *checker) buildGraph() {
+	observed := make(map[int]bool)
+	for i, t := range c.h {
+		if t.Outcome != Committed {
+			continue
+		}
+		for _, r := range c.reads[i] {
+			if w, ok := c.writer(r); ok {
+				observed[w] = true
+			}
+		}
+	}
+	for i, t := range c.h {
+		if t.Outcome == Committed || (t.Outcome == Unknown && observed[i]) {
+			c.nodeOf[t.Id] = len(c.nodes)
+			c.nodes = append(c.nodes, i)
+		}
+	}
+	c.out = make([][]edge, len(c.nodes))
+	index := make(map[[2]int]int)
+	addEdge := func(from, to int, kind edgeKind) {
+		if from == to {
+			return
+		}
+		if j, ok := index[[2]int{from, to}]; ok {
+			c.out[from][j].kind |= kind
+			return
+		}
+		index[[2]int{from, to}] = len(c.out[from])
+		c.out[from] = append(c.out[from], edge{to: to, kind: kind})
+	}
+
+	// the writes that follow reads order the versions
+	for n, i := range c.nodes {
+		for _, r := range c.reads[i] {
+			if _, ok := c.writes[i][r.record]; !ok {
+				continue
+			}
+			writer := WriterOf(r.op.Version)
+			if c.successors[r.record] == nil {
+				c.successors[r.record] = make(map[string][]int)
+			}
+			c.successors[r.record][writer] = append(c.successors[r.record][writer], n)
+			if w, ok := c.nodeOf[writer]; ok {
+				addEdge(w, n, ww)
+			}
+		}
+	}
+	for n, i := range c.nodes {
+		for _, r := range c.reads[i] {
+			writer := WriterOf(r.op.Version)
+			if w, ok := c.nodeOf[writer]; ok {
+				addEdge(w, n, wr)
+			}
+			for _, s := range c.successors[r.record][writer] {
+				addEdge(n, s, rw)
+			}
+		}
+	}
+}
+
+// checkLostUpdates reports the transactions that installed versions
+// following the same version.
+func (c *checker) checkLostUpdates() {
+	for _, i := range c.nodes {
+		for _, r := range c.reads[i] {
+			successors := c.successors[r.record][WriterOf(r.op.Version)]
+			// report once, from the first successor
+			if len(successors) < 2 || c.nodes[successors[0]] != i {
+				continue
+			}
+			var txns []string
+			for _, a := range successors {
+				txns = append(txns, c.h[c.nodes[a]].Id)
+				for _, b := range successors {
+					c.updated[[2]int{a, b}] = true
+				}
+			}
+			c.report(LostUpdate, txns,
+				"%s read the same version of %s and wrote it", strings.Join(txns, ", "), r.record)
+		}
+	}
+}
+
+// checkCycles reports a cycle in each strongly connected component of the
+// dependency graph, of the strongest type found among G0, G1c, read skew
+// and write skew.
+func (c *checker) checkCycles() {
+	all := c.components(ww | wr | rw)
+	writes := c.components(ww)
+	flows := c.components(ww | wr)
+	for _, members := range groups(all) {
+		if len(members) < 2 {
+			continue
+		}
+		// an edge inside a component is on a cycle of the component
+		switch {
+		case c.findCycle(G0, members, ww, ww, func(a int, e edge) bool {
+			return e.kind&ww != 0 && writes[a] == writes[e.to]
+		}):
+		case c.findCycle(G1c, members, wr, ww|wr, func(a int, e edge) bool {
+			return e.kind&wr != 0 && flows[a] == flows[e.to]
+		}):
+		case c.findCycle(ReadSkew, members, rw, ww|wr, func(a int, e edge) bool {
+			return e.kind == rw
+		}):
+		default:
+			c.findCycle(WriteSkew, members, rw, ww|wr|rw, func(a int, e edge) bool {
+				// the anti-dependencies between the lost updates are reported already
+				return e.kind == rw && !c.updated[[2]int{a, e.to}]
+			})
+		}
+	}
+}
+
+// findCycle reports a cycle made of an edge of kind first accepted by
+// accept, followed by a path of the edges of the kinds in mask back to
+// its source.
+func (c *checker) findCycle(typ AnomalyType, members []int, first, mask edgeKind, accept func(int, edge) bool) bool {
+	for _, a := range members {
+		for _, e := range c.out[a] {
+			if !accept(a, e) {
+				continue
+			}
+			path := c.path(e.to, a, mask)
+			if path == nil {
+				continue
+			}
+			cycle := append([]int{a}, path...)
+			kinds := []edgeKind{first}
+			for i := 1; i < len(cycle)-1; i++ {
+				kinds = append(kinds, c.kind(cycle[i], cycle[i+1])&mask)
+			}
+			var txns []string
+			var desc strings.Builder
+			for i, n := range cycle[:len(cycle)-1] {
+				id := c.h[c.nodes[n]].Id
+				txns = append(txns, id)
+				fmt.Fprintf(&desc, "%s -%s-> ", id, kinds[i])
+			}
+			desc.WriteString(txns[0])
+			c.report(typ, txns, "%s", desc.String())
+			return true
+		}
+	}
+	return false
+}
+
+// path returns the nodes on a shortest path from src to dst with the edges
+// of the kinds in mask, or nil if there is none.
+func (c *checker) path(src, dst int, mask edgeKind) []int {
+	prev := map[int]int{src: -1}
+	queue := []int{src}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n == dst {
+			var path []int
+			for ; n != -1; n = prev[n] {
+				path = append([]int{n}, path...)
+			}
+			return path
+		}
+		for _, e := range c.out[n] {
+			if _, seen := prev[e.to]; !seen && e.kind&mask != 0 {
+				prev[e.to] = n
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *checker) kind(from, to int) edgeKind {
+	for _, e := range c.out[from] {
+		if e.to == to {
+			return e.kind
+		}
+	}
+	return 0
+}
+
+// components returns the strongly connected component of each node in the
+// graph made of the edges of the kinds in mask, with Tarjan's algorithm.
+func (c *checker) components(mask edgeKind) []int {
+	n := len(c.nodes)
+	index, low, comp := make([]int, n), make([]int, n), make([]int, n)
+	for i := range index {
+		index[i], comp[i] = -1, -1
+	}
+	onStack := make([]bool, n)
+	var stack []int
+	next, count := 0, 0
+	type frame struct{ node, edge int }
+	for root := range n {
+		if index[root] != -1 {
+			continue
+		}
+		visit := func(v int) {
+			index[v], low[v] = next, next
+			next++
+			stack = append(stack, v)
+			onStack[v] = true
+		}
+		visit(root)
+		calls := []frame{{node: root}}
+		for len(calls) > 0 {
+			f := &calls[len(calls)-1]
+			v := f.node
+			if f.edge < len(c.out[v]) {
+				e := c.out[v][f.edge]
+				f.edge++
+				switch {
+				case e.kind&mask == 0:
+				case index[e.to] == -1:
+					visit(e.to)
+					calls = append(calls, frame{node: e.to})
+				case onStack[e.to]:
+					low[v] = min(low[v], index[e.to])
+				}
+				continue
+			}
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				u := calls[len(calls)-1].node
+				low[u] = min(low[u], low[v])
+			}
+			if low[v] == index[v] {
+				for {
+					w := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[w] = false
+					comp[w] = count
+					if w == v {
+						break
+					}
+				}
+				count++
+			}
+		}
+	}
+	return comp
+}
+
+// groups returns the nodes of each component, in the order of the nodes.
+func groups(comp []int) [][]int {
+	var members [][]int
+	for n, id := range comp {
+		for len(members) <= id {
+			members = append(members, nil)
+		}
+		members[id] = append(members[id], n)
+	}
+	return members
+}
+
+func (c *checker) report(typ AnomalyType, txns []string, format string, args ...any) {
+	c.anomalies = append(c.anomalies, Anomaly{
+		Type:        typ,
+		Txns:        txns,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,153 @@
+package history
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func w(key string, value string) Op {
+	return Op{Type: OpWrite, Datastore: "ds", Key: key, Value: value}
+}
+
+// r is a read of the version of key written by writer,
+// or of the initial version if writer is empty.
+func r(key string, value string, writer string) Op {
+	op := Op{Type: OpRead, Datastore: "ds", Key: key, Value: value}
+	if writer != "" {
+		op.Version = "ds:" + writer
+	}
+	return op
+}
+
+func committed(id string, ops ...Op) Txn {
+	return Txn{Id: id, Ops: ops, Outcome: Committed}
+}
+
+func anomalyTypes(report *Report) []AnomalyType {
+	var types []AnomalyType
+	for _, a := range report.Anomalies {
+		types = append(types, a.Type)
+	}
+	return types
+}
+
+func TestCheckValidHistory(t *testing.T) {
+	h := History{
+		committed("t1", r("x", "0", ""), w("x", "1")),
+		committed("t2", r("x", "1", "t1"), w("x", "2"), r("y", "0", "")),
+		committed("t3", r("x", "2", "t2"), r("y", "0", "")),
+		{Id: "t4", Ops: []Op{w("y", "1")}, Outcome: Aborted},
+		{Id: "t5", Ops: []Op{w("z", "1")}, Outcome: Unknown},
+	}
+	report := Check(h, config.Serializable)
+	assert.Empty(t, report.Anomalies)
+	assert.True(t, report.Valid())
+	// the unknown transaction is not read
+	assert.Equal(t, 3, report.Txns)
+}
+
+func TestCheckG1a(t *testing.T) {
+	h := History{
+		{Id: "t1", Ops: []Op{w("x", "1")}, Outcome: Aborted},
+		committed("t2", r("x", "1", "t1")),
+	}
+	report := Check(h, config.SnapshotIsolation)
+	assert.Equal(t, []AnomalyType{G1a}, anomalyTypes(report))
+	assert.Equal(t, []string{"t2", "t1"}, report.Anomalies[0].Txns)
+	assert.False(t, report.Valid())
+}
+
+func TestCheckG1b(t *testing.T) {
+	h := History{
+		committed("t1", w("x", "1"), w("x", "2")),
+		committed("t2", r("x", "1", "t1")),
+	}
+	assert.Equal(t, []AnomalyType{G1b}, anomalyTypes(Check(h, config.SnapshotIsolation)))
+}
+
+func TestCheckG0(t *testing.T) {
+	h := History{
+		committed("t1", r("x", "2", "t2"), w("x", "1"), w("y", "1")),
+		committed("t2", r("y", "1", "t1"), w("y", "2"), w("x", "2")),
+	}
+	report := Check(h, config.SnapshotIsolation)
+	assert.Equal(t, []AnomalyType{G0}, anomalyTypes(report))
+	assert.Equal(t, "t1 -ww-> t2 -ww-> t1", report.Anomalies[0].Description)
+}
+
+func TestCheckG1c(t *testing.T) {
+	h := History{
+		committed("t1", w("x", "1"), r("y", "1", "t2")),
+		committed("t2", w("y", "1"), r("x", "1", "t1")),
+	}
+	report := Check(h, config.SnapshotIsolation)
+	assert.Equal(t, []AnomalyType{G1c}, anomalyTypes(report))
+	assert.Equal(t, "t1 -wr-> t2 -wr-> t1", report.Anomalies[0].Description)
+}
+
+func TestCheckLostUpdate(t *testing.T) {
+	h := History{
+		committed("t0", w("x", "0")),
+		committed("t1", r("x", "0", "t0"), w("x", "1")),
+		committed("t2", r("x", "0", "t0"), w("x", "2")),
+		// an aborted update of the same version is not lost
+		{Id: "t3", Ops: []Op{r("x", "0", "t0"), w("x", "3")}, Outcome: Aborted},
+	}
+	report := Check(h, config.SnapshotIsolation)
+	assert.Equal(t, []AnomalyType{LostUpdate}, anomalyTypes(report))
+	assert.Equal(t, []string{"t1", "t2"}, report.Anomalies[0].Txns)
+}
+
+func TestCheckReadSkew(t *testing.T) {
+	h := History{
+		committed("t1", r("x", "0", ""), w("x", "1"), r("y", "0", ""), w("y", "1")),
+		// reads x before t1 and y after t1
+		committed("t2", r("x", "0", ""), r("y", "1", "t1")),
+	}
+	report := Check(h, config.SnapshotIsolation)
+	assert.Equal(t, []AnomalyType{ReadSkew}, anomalyTypes(report))
+	assert.Equal(t, "t2 -rw-> t1 -wr-> t2", report.Anomalies[0].Description)
+}
+
+func TestCheckWriteSkew(t *testing.T) {
+	h := History{
+		committed("t1", r("x", "0", ""), r("y", "0", ""), w("x", "1")),
+		committed("t2", r("x", "0", ""), r("y", "0", ""), w("y", "1")),
+	}
+	report := Check(h, config.SnapshotIsolation)
+	assert.Equal(t, []AnomalyType{WriteSkew}, anomalyTypes(report))
+	assert.Equal(t, "t1 -rw-> t2 -rw-> t1", report.Anomalies[0].Description)
+	// allowed by snapshot isolation only
+	assert.True(t, report.Valid())
+	assert.False(t, Check(h, config.Serializable).Valid())
+}
+
+func TestCheckUnknownOutcome(t *testing.T) {
+	h := History{
+		{Id: "t1", Ops: []Op{r("x", "0", ""), w("x", "1")}, Outcome: Unknown},
+		committed("t2", r("x", "0", ""), w("x", "2")),
+		committed("t3", r("x", "1", "t1")),
+	}
+	// t1 is read, so it has committed along with t2
+	report := Check(h, config.SnapshotIsolation)
+	assert.Equal(t, 3, report.Txns)
+	assert.Equal(t, []AnomalyType{LostUpdate}, anomalyTypes(report))
+	assert.Equal(t, []string{"t1", "t2"}, report.Anomalies[0].Txns)
+}
+
+func TestHistoryEncoding(t *testing.T) {
+	h := History{
+		{Id: "t1", Start: 1, Commit: 2, Ops: []Op{w("x", `"1"`)}, Outcome: Committed},
+		{Id: "t2", Start: 3, Ops: []Op{r("x", `"1"`, "t1")}, Outcome: Unknown, Error: "timeout"},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, h.Encode(&buf))
+	decoded, err := Decode(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, h, decoded)
+	assert.Equal(t, "t1", WriterOf("ds1:t1,ds2:t1"))
+	assert.Equal(t, "", WriterOf(""))
+}
@@ -0,0 +1,126 @@
+// Package history records the transactions run by a client and checks the
+// recorded history for the anomalies prohibited by the isolation level.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+)
+
+// OpType is the type of an operation in a transaction.
+type OpType string
+
+const (
+	OpRead  OpType = "r"
+	OpWrite OpType = "w"
+)
+
+// Outcome is the outcome of a transaction.
+type Outcome string
+
+const (
+	// Committed is the outcome of a transaction that has committed.
+	Committed Outcome = "ok"
+	// Aborted is the outcome of a transaction that has aborted.
+	Aborted Outcome = "fail"
+	// Unknown is the outcome of a transaction that may or may not have
+	// committed, e.g. after a timeout.
+	Unknown Outcome = "info"
+)
+
+// Op is an operation of a transaction on a record.
+type Op struct {
+	Type      OpType `json:"type"`
+	Datastore string `json:"ds"`
+	Key       string `json:"key"`
+	// Value is the JSON encoding of the value read or written.
+	// It is empty if the record is absent or deleted.
+	Value string `json:"value,omitempty"`
+	// Absent is true for a read that did not find the record,
+	// and for a delete.
+	Absent bool `json:"absent,omitempty"`
+	// Version is the group key list of the version observed by a read.
+	// It is empty if the record has never been written.
+	Version string `json:"version,omitempty"`
+	// Internal is true for a read served by the writes of the transaction.
+	Internal bool `json:"internal,omitempty"`
+}
+
+// Txn is a transaction in a history.
+type Txn struct {
+	Id string `json:"id"`
+	// Start and Commit are the start and commit timestamps.
+	// Commit is zero if the transaction has not committed,
+	// or if the commit timestamp is not known.
+	Start   int64   `json:"start"`
+	Commit  int64   `json:"commit,omitempty"`
+	Ops     []Op    `json:"ops"`
+	Outcome Outcome `json:"outcome"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// History is a list of transactions, in the order they finished.
+type History []Txn
+
+// WriterOf returns the id of the transaction that wrote the version
+// identified by the group key list version, or "" if version is empty.
+func WriterOf(version string) string {
+	groupKey, _, _ := strings.Cut(version, ",")
+	_, txnId, _ := strings.Cut(groupKey, ":")
+	return txnId
+}
+
+// Encode writes the history to w, one JSON transaction per line.
+func (h History) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i := range h {
+		if err := enc.Encode(&h[i]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Decode reads a history written by Encode from r.
+func Decode(r io.Reader) (History, error) {
+	var h History
+	dec := json.NewDecoder(r)
+	for {
+		var t Txn
+		err := dec.Decode(&t)
+		if err == io.EOF {
+			return h, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		h = append(h, t)
+	}
+}
+
+// WriteFile writes the history to the file at path.
+func WriteFile(path string, h History) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := h.Encode(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadFile reads the history in the file at path.
+func ReadFile(path string) (History, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
@@ -0,0 +1,167 @@
+package history
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+// Recorder records the transactions wrapped by it into a history.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu   sync.Mutex
+	txns History
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Wrap returns t wrapped into a Transaction recording its operations.
+// The transaction is added to the history when it commits or aborts.
+func (r *Recorder) Wrap(t *txn.Transaction) *Transaction {
+	return &Transaction{
+		Transaction: t,
+		recorder:    r,
+		written:     make(map[string]bool),
+	}
+}
+
+// History returns a copy of the recorded history.
+func (r *Recorder) History() History {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := make(History, len(r.txns))
+	copy(h, r.txns)
+	return h
+}
+
+// WriteFile writes the recorded history to the file at path.
+func (r *Recorder) WriteFile(path string) error {
+	return WriteFile(path, r.History())
+}
+
+func (r *Recorder) add(t Txn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txns = append(r.txns, t)
+}
+
+// Transaction is a txn.Transaction whose operations are recorded.
+// The operations that are not overridden are not recorded.
+type Transaction struct {
+	*txn.Transaction
+	recorder *Recorder
+	ops      []Op
+	// written is the set of records written by the transaction,
+	// keyed by the datastore name and the key.
+	written  map[string]bool
+	finished bool
+}
+
+// Read reads the record and records the value and the version observed.
+func (t *Transaction) Read(dsName string, key string, value any) error {
+	err := t.Transaction.Read(dsName, key, value)
+	op := Op{
+		Type:      OpRead,
+		Datastore: dsName,
+		Key:       key,
+		Internal:  t.written[dsName+"/"+key],
+	}
+	switch {
+	case err == nil:
+		op.Value = encode(value)
+	case strings.Contains(err.Error(), txn.KeyNotFound.Error()):
+		op.Absent = true
+	default:
+		return err
+	}
+	if !op.Internal {
+		if version, ok := t.ReadVersionOf(dsName, key); ok {
+			op.Version = version.GroupKeyList
+		}
+	}
+	t.ops = append(t.ops, op)
+	return err
+}
+
+// Write writes the record and records the value written.
+func (t *Transaction) Write(dsName string, key string, value any) error {
+	if err := t.Transaction.Write(dsName, key, value); err != nil {
+		return err
+	}
+	t.addWrite(Op{Type: OpWrite, Datastore: dsName, Key: key, Value: encode(value)})
+	return nil
+}
+
+// Delete deletes the record and records the delete.
+func (t *Transaction) Delete(dsName string, key string) error {
+	if err := t.Transaction.Delete(dsName, key); err != nil {
+		return err
+	}
+	t.addWrite(Op{Type: OpWrite, Datastore: dsName, Key: key, Absent: true})
+	return nil
+}
+
+// Commit commits the transaction and records its outcome. If Commit fails,
+// the outcome is resolved with Status, and is Unknown if it is not decided.
+func (t *Transaction) Commit() error {
+	err := t.Transaction.Commit()
+	if err == nil {
+		t.finish(Committed, t.TxnCommitTime, nil)
+		return nil
+	}
+	outcome, serr := t.Status()
+	switch {
+	case serr != nil || !outcome.IsDecided():
+		t.finish(Unknown, 0, err)
+	case outcome.State == config.COMMITTED:
+		t.finish(Committed, outcome.TCommit, err)
+	default:
+		t.finish(Aborted, 0, err)
+	}
+	return err
+}
+
+// Abort aborts the transaction and records it as aborted.
+func (t *Transaction) Abort() error {
+	err := t.Transaction.Abort()
+	t.finish(Aborted, 0, nil)
+	return err
+}
+
+func (t *Transaction) addWrite(op Op) {
+	t.written[op.Datastore+"/"+op.Key] = true
+	t.ops = append(t.ops, op)
+}
+
+// finish adds the transaction to the history once.
+func (t *Transaction) finish(outcome Outcome, tCommit int64, err error) {
+	if t.finished {
+		return
+	}
+	t.finished = true
+	record := Txn{
+		Id:      t.TxnId,
+		Start:   t.TxnStartTime,
+		Commit:  tCommit,
+		Ops:     t.ops,
+		Outcome: outcome,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	t.recorder.add(record)
+}
+
+func encode(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,73 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conn := bolt.NewBoltConnection(&bolt.ConnectionOptions{
+		Path: filepath.Join(t.TempDir(), "oreo.db"),
+	})
+	assert.NoError(t, conn.Connect())
+	defer conn.Close()
+	rec := NewRecorder()
+	newTxn := func() *Transaction {
+		tx := txn.NewTransaction()
+		tx.AddDatastore(bolt.NewBoltDatastore("bolt", conn))
+		return rec.Wrap(tx)
+	}
+
+	txn1 := newTxn()
+	assert.NoError(t, txn1.Start())
+	var value string
+	assert.Error(t, txn1.Read("bolt", "x", &value))
+	assert.NoError(t, txn1.Write("bolt", "x", "1"))
+	assert.NoError(t, txn1.Commit())
+
+	txn2 := newTxn()
+	assert.NoError(t, txn2.Start())
+	assert.NoError(t, txn2.Read("bolt", "x", &value))
+	assert.NoError(t, txn2.Write("bolt", "x", "2"))
+	assert.NoError(t, txn2.Read("bolt", "x", &value))
+	assert.NoError(t, txn2.Commit())
+
+	txn3 := newTxn()
+	assert.NoError(t, txn3.Start())
+	assert.NoError(t, txn3.Delete("bolt", "x"))
+	assert.NoError(t, txn3.Abort())
+
+	h := rec.History()
+	assert.Len(t, h, 3)
+	assert.Equal(t, []Op{
+		{Type: OpRead, Datastore: "bolt", Key: "x", Absent: true},
+		{Type: OpWrite, Datastore: "bolt", Key: "x", Value: `"1"`},
+	}, h[0].Ops)
+	assert.Equal(t, Committed, h[0].Outcome)
+	assert.Equal(t, txn1.TxnId, h[0].Id)
+
+	assert.Equal(t, OpRead, h[1].Ops[0].Type)
+	assert.Equal(t, `"1"`, h[1].Ops[0].Value)
+	assert.Equal(t, txn1.TxnId, WriterOf(h[1].Ops[0].Version))
+	// the read of its own write
+	assert.True(t, h[1].Ops[2].Internal)
+	assert.Empty(t, h[1].Ops[2].Version)
+
+	assert.Equal(t, Aborted, h[2].Outcome)
+	assert.True(t, h[2].Ops[0].Absent)
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	assert.NoError(t, rec.WriteFile(path))
+	read, err := ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, h, read)
+	assert.True(t, Check(read, config.Serializable).Valid())
+}
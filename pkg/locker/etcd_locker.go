@@ -0,0 +1,151 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var _ FencedLocker = (*EtcdLocker)(nil)
+
+// EtcdLocker is a FencedLocker backed by etcd.
+//
+// A lock is a key attached to an etcd lease, so it disappears when the lease
+// expires. The fencing token is the revision at which the key was created,
+// which increases monotonically across the whole cluster.
+type EtcdLocker struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// NewEtcdLocker creates a new EtcdLocker using the given client.
+// All the keys used by the locker start with prefix.
+func NewEtcdLocker(client *clientv3.Client, prefix string) *EtcdLocker {
+	if prefix == "" {
+		prefix = "/oreo-lock/"
+	}
+	return &EtcdLocker{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// TryLock acquires the lock on key for owner without waiting.
+func (l *EtcdLocker) TryLock(
+	ctx context.Context,
+	key string,
+	owner string,
+	ttl time.Duration,
+) (*Lease, error) {
+	lockKey := l.prefix + key
+
+	// etcd leases have a granularity of one second
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	grant, err := l.client.Grant(ctx, seconds)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := l.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(lockKey), "=", 0)).
+		Then(clientv3.OpPut(lockKey, owner, clientv3.WithLease(grant.ID))).
+		Else(clientv3.OpGet(lockKey)).
+		Commit()
+	if err != nil {
+		_, _ = l.client.Revoke(context.Background(), grant.ID)
+		return nil, err
+	}
+	if resp.Succeeded {
+		return &Lease{
+			Key:     key,
+			Owner:   owner,
+			Token:   resp.Header.Revision,
+			TTL:     ttl,
+			leaseID: int64(grant.ID),
+		}, nil
+	}
+
+	kv := resp.Responses[0].GetResponseRange().Kvs[0]
+	if string(kv.Value) != owner {
+		_, _ = l.client.Revoke(context.Background(), grant.ID)
+		return nil, ErrLockHeld
+	}
+
+	// the owner re-acquires the lock: move the key to the new lease so that
+	// it lives for the requested TTL, keeping its create revision as the token
+	resp, err = l.client.Txn(ctx).
+		If(
+			clientv3.Compare(clientv3.CreateRevision(lockKey), "=", kv.CreateRevision),
+			clientv3.Compare(clientv3.Value(lockKey), "=", owner),
+		).
+		Then(clientv3.OpPut(lockKey, owner, clientv3.WithLease(grant.ID))).
+		Commit()
+	if err != nil || !resp.Succeeded {
+		_, _ = l.client.Revoke(context.Background(), grant.ID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrLockHeld
+	}
+	_, _ = l.client.Revoke(context.Background(), clientv3.LeaseID(kv.Lease))
+	return &Lease{
+		Key:     key,
+		Owner:   owner,
+		Token:   kv.CreateRevision,
+		TTL:     ttl,
+		leaseID: int64(grant.ID),
+	}, nil
+}
+
+// Lock acquires the lock on key for owner, polling until it is available or ctx is done.
+func (l *EtcdLocker) Lock(
+	ctx context.Context,
+	key string,
+	owner string,
+	ttl time.Duration,
+) (*Lease, error) {
+	return lockWithRetry(ctx, func() (*Lease, error) {
+		return l.TryLock(ctx, key, owner, ttl)
+	})
+}
+
+// Renew extends the lease by the TTL it was granted with.
+func (l *EtcdLocker) Renew(ctx context.Context, lease *Lease) error {
+	_, err := l.client.KeepAliveOnce(ctx, clientv3.LeaseID(lease.leaseID))
+	if err != nil {
+		if errors.Is(err, rpctypes.ErrLeaseNotFound) {
+			return ErrLeaseLost
+		}
+		return err
+	}
+
+	// the lease may be alive while the lock has been deleted
+	resp, err := l.client.Get(ctx, l.prefix+lease.Key)
+	if err != nil {
+		return err
+	}
+	if len(resp.Kvs) == 0 || resp.Kvs[0].CreateRevision != lease.Token {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Unlock releases the lease.
+func (l *EtcdLocker) Unlock(ctx context.Context, lease *Lease) error {
+	lockKey := l.prefix + lease.Key
+	resp, err := l.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(lockKey), "=", lease.Token)).
+		Then(clientv3.OpDelete(lockKey)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	_, _ = l.client.Revoke(ctx, clientv3.LeaseID(lease.leaseID))
+	if !resp.Succeeded {
+		return ErrLeaseLost
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package locker
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+func newTestEtcdClient(t *testing.T) *clientv3.Client {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	// short ticks let etcd grant and expire one-second leases
+	cfg.TickMs, cfg.ElectionMs = 10, 50
+	clientUrl, _ := url.Parse("http://127.0.0.1:0")
+	peerUrl, _ := url.Parse("http://127.0.0.1:0")
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{*clientUrl}, []url.URL{*clientUrl}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{*peerUrl}, []url.URL{*peerUrl}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	server, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatalf("Could not start etcd: %s", err)
+	}
+	t.Cleanup(server.Close)
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(30 * time.Second):
+		t.Fatalf("etcd took too long to start")
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{server.Clients[0].Addr().String()},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Could not connect to etcd: %s", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestEtcdLocker(t *testing.T) {
+	locker := NewEtcdLocker(newTestEtcdClient(t), "")
+	testFencedLockerBackend(t, locker, time.Second, 3*time.Second)
+}
@@ -0,0 +1,104 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrLockHeld is returned by TryLock when the lock is held by another owner.
+	ErrLockHeld = errors.New("lock is held by another owner")
+
+	// ErrLeaseLost is returned when a lease has expired or the lock
+	// has been acquired by another owner in the meantime.
+	ErrLeaseLost = errors.New("lease is lost")
+)
+
+// Lease represents a lock held by an owner.
+type Lease struct {
+	// Key is the locked resource.
+	Key string
+	// Owner is the ID of the lock holder.
+	Owner string
+	// Token is the fencing token of the lease.
+	// It increases monotonically every time the lock on Key changes hands,
+	// so a resource guarded by the lock can reject writes carrying a stale token.
+	Token int64
+	// TTL is the duration of the lease. It is extended by Renew.
+	TTL time.Duration
+
+	// leaseID is the backend-specific lease handle, if any.
+	leaseID int64
+}
+
+// FencedLocker is the lease-based successor of Locker.
+// Every acquisition returns a Lease carrying a fencing token, and a lease
+// has to be renewed before its TTL elapses to remain valid.
+type FencedLocker interface {
+	// TryLock acquires the lock on key for owner without waiting.
+	// It returns ErrLockHeld if the lock is held by another owner.
+	// Acquiring a lock already held by the same owner refreshes its TTL
+	// and returns the same fencing token.
+	TryLock(ctx context.Context, key string, owner string, ttl time.Duration) (*Lease, error)
+
+	// Lock acquires the lock on key for owner, waiting until it becomes
+	// available or ctx is done.
+	Lock(ctx context.Context, key string, owner string, ttl time.Duration) (*Lease, error)
+
+	// Renew extends the lease by its TTL.
+	// It returns ErrLeaseLost if the lease is no longer held.
+	Renew(ctx context.Context, lease *Lease) error
+
+	// Unlock releases the lease.
+	// It returns ErrLeaseLost if the lease is no longer held.
+	Unlock(ctx context.Context, lease *Lease) error
+}
+
+// retryInterval is the polling interval of the blocking Lock implementations.
+const retryInterval = 10 * time.Millisecond
+
+// lockWithRetry calls tryLock until the lock is acquired or ctx is done.
+func lockWithRetry(
+	ctx context.Context,
+	tryLock func() (*Lease, error),
+) (*Lease, error) {
+	for {
+		lease, err := tryLock()
+		if !errors.Is(err, ErrLockHeld) {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// KeepAlive renews the lease every third of its TTL until ctx is done.
+// The returned channel receives an error and is closed when a renewal fails,
+// which means the critical section guarded by the lease is no longer safe.
+// It is closed without an error when ctx is done.
+func KeepAlive(ctx context.Context, l FencedLocker, lease *Lease) <-chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		ticker := time.NewTicker(lease.TTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := l.Renew(ctx, lease); err != nil {
+					if ctx.Err() == nil {
+						errCh <- err
+					}
+					return
+				}
+			}
+		}
+	}()
+	return errCh
+}
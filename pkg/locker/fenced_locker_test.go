@@ -0,0 +1,82 @@
+package locker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testFencedLockerBackend runs the FencedLocker contract against a backend.
+// ttl is the shortest TTL the backend honours and expiry is how long it takes
+// the backend to drop a lease of that TTL.
+func testFencedLockerBackend(t *testing.T, locker FencedLocker, ttl time.Duration, expiry time.Duration) {
+	ctx := context.Background()
+
+	t.Run("Tokens", func(t *testing.T) {
+		lease, err := locker.TryLock(ctx, t.Name(), "owner-1", ttl)
+		assert.NoError(t, err)
+
+		_, err = locker.TryLock(ctx, t.Name(), "owner-2", ttl)
+		assert.ErrorIs(t, err, ErrLockHeld)
+
+		again, err := locker.TryLock(ctx, t.Name(), "owner-1", ttl)
+		assert.NoError(t, err)
+		assert.Equal(t, lease.Token, again.Token)
+
+		assert.NoError(t, locker.Unlock(ctx, again))
+		assert.ErrorIs(t, locker.Unlock(ctx, again), ErrLeaseLost)
+
+		next, err := locker.TryLock(ctx, t.Name(), "owner-2", ttl)
+		assert.NoError(t, err)
+		assert.Greater(t, next.Token, lease.Token)
+		assert.NoError(t, locker.Unlock(ctx, next))
+	})
+
+	t.Run("Renew", func(t *testing.T) {
+		lease, err := locker.TryLock(ctx, t.Name(), "owner-1", ttl)
+		assert.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			time.Sleep(ttl / 2)
+			assert.NoError(t, locker.Renew(ctx, lease))
+		}
+		_, err = locker.TryLock(ctx, t.Name(), "owner-2", ttl)
+		assert.ErrorIs(t, err, ErrLockHeld)
+		assert.NoError(t, locker.Unlock(ctx, lease))
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		lease, err := locker.TryLock(ctx, t.Name(), "owner-1", ttl)
+		assert.NoError(t, err)
+
+		time.Sleep(expiry)
+		next, err := locker.TryLock(ctx, t.Name(), "owner-2", ttl)
+		assert.NoError(t, err)
+		assert.Greater(t, next.Token, lease.Token)
+
+		// the expired lease can be neither renewed nor released
+		assert.ErrorIs(t, locker.Renew(ctx, lease), ErrLeaseLost)
+		assert.ErrorIs(t, locker.Unlock(ctx, lease), ErrLeaseLost)
+		assert.NoError(t, locker.Unlock(ctx, next))
+	})
+
+	// re-acquiring by the same owner holds the lock for the new TTL
+	t.Run("Reacquire", func(t *testing.T) {
+		lease, err := locker.TryLock(ctx, t.Name(), "owner-1", ttl)
+		assert.NoError(t, err)
+		again, err := locker.TryLock(ctx, t.Name(), "owner-1", 10*expiry)
+		assert.NoError(t, err)
+		assert.Equal(t, lease.Token, again.Token)
+
+		time.Sleep(expiry)
+		_, err = locker.TryLock(ctx, t.Name(), "owner-2", ttl)
+		assert.ErrorIs(t, err, ErrLockHeld)
+		assert.NoError(t, locker.Renew(ctx, again))
+		assert.NoError(t, locker.Unlock(ctx, again))
+	})
+}
+
+func TestMemoryFencedLockerBackend(t *testing.T) {
+	testFencedLockerBackend(t, NewMemoryFencedLocker(), 60*time.Millisecond, 100*time.Millisecond)
+}
@@ -1,7 +1,8 @@
 package locker
 
 import (
-	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 // HttpLocker represents a locker that uses HTTP requests to interact with an Oracle service.
 type HttpLocker struct {
 	oracleURL string
+	client    *http.Client
 }
 
 // NewHttpLocker creates a new instance of HttpLocker with the specified oracleURL.
@@ -18,21 +20,22 @@ type HttpLocker struct {
 func NewHttpLocker(oracleURL string) *HttpLocker {
 	return &HttpLocker{
 		oracleURL: oracleURL,
+		client:    &http.Client{Timeout: 10 * time.Second},
 	}
 }
 
 // Lock locks the specified key with the given ID for the specified duration.
 // It sends an HTTP GET request to the oracleURL with the key, ID, and duration as query parameters.
+// The duration is sent in milliseconds, which is the unit expected by the oracle.
 // If the lock request fails, it returns an error indicating the failure.
 func (l *HttpLocker) Lock(key string, id string, holdDuration time.Duration) error {
 	data := url.Values{}
 	data.Set("key", key)
 	data.Set("id", id)
-	data.Set("duration", strconv.Itoa(int(holdDuration)))
+	data.Set("duration", strconv.FormatInt(holdDuration.Milliseconds(), 10))
 
-	_, err := http.Get(l.oracleURL + "/lock?" + data.Encode())
-	if err != nil {
-		return errors.New("failed to lock")
+	if err := l.get("/lock?" + data.Encode()); err != nil {
+		return fmt.Errorf("failed to lock: %w", err)
 	}
 	return nil
 }
@@ -45,9 +48,26 @@ func (l *HttpLocker) Unlock(key string, id string) error {
 	data.Set("key", key)
 	data.Set("id", id)
 
-	_, err := http.Get(l.oracleURL + "/unlock?" + data.Encode())
+	if err := l.get("/unlock?" + data.Encode()); err != nil {
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	return nil
+}
+
+// get sends a GET request to the oracle and turns a non-200 response into an error.
+func (l *HttpLocker) get(path string) error {
+	resp, err := l.client.Get(l.oracleURL + path)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
 	return nil
 }
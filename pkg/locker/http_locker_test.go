@@ -0,0 +1,36 @@
+package locker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpLockerSendsMilliseconds(t *testing.T) {
+	var duration string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		duration = r.URL.Query().Get("duration")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	locker := NewHttpLocker(server.URL)
+	err := locker.Lock("key1", "txnId-1", 2*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "2000", duration)
+}
+
+func TestHttpLockerChecksStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Lock failed"))
+	}))
+	defer server.Close()
+
+	locker := NewHttpLocker(server.URL)
+	assert.Error(t, locker.Lock("key1", "txnId-1", time.Second))
+	assert.Error(t, locker.Unlock("key1", "txnId-1"))
+}
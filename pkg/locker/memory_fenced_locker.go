@@ -0,0 +1,158 @@
+package locker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var _ FencedLocker = (*MemoryFencedLocker)(nil)
+
+type memoryLease struct {
+	owner     string
+	token     int64
+	expiresAt time.Time
+}
+
+// MemoryFencedLocker is a process-local FencedLocker.
+// It is intended for tests and single-process deployments.
+type MemoryFencedLocker struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+	fences map[string]int64
+	// released is closed and removed whenever the lock on a key is released.
+	released map[string]chan struct{}
+}
+
+// NewMemoryFencedLocker creates a new instance of MemoryFencedLocker.
+func NewMemoryFencedLocker() *MemoryFencedLocker {
+	return &MemoryFencedLocker{
+		leases:   make(map[string]memoryLease),
+		fences:   make(map[string]int64),
+		released: make(map[string]chan struct{}),
+	}
+}
+
+// TryLock acquires the lock on key for owner without waiting.
+func (ml *MemoryFencedLocker) TryLock(
+	ctx context.Context,
+	key string,
+	owner string,
+	ttl time.Duration,
+) (*Lease, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	cur, ok := ml.getLease(key)
+	if ok && cur.owner != owner {
+		return nil, ErrLockHeld
+	}
+	if !ok {
+		ml.fences[key]++
+		cur = memoryLease{owner: owner, token: ml.fences[key]}
+	}
+	cur.expiresAt = time.Now().Add(ttl)
+	ml.leases[key] = cur
+	return &Lease{Key: key, Owner: owner, Token: cur.token, TTL: ttl}, nil
+}
+
+// Lock acquires the lock on key for owner, waiting until it is released,
+// its lease expires or ctx is done.
+func (ml *MemoryFencedLocker) Lock(
+	ctx context.Context,
+	key string,
+	owner string,
+	ttl time.Duration,
+) (*Lease, error) {
+	for {
+		lease, err := ml.TryLock(ctx, key, owner, ttl)
+		if err != ErrLockHeld {
+			return lease, err
+		}
+
+		ml.mu.Lock()
+		cur, ok := ml.getLease(key)
+		ch := ml.releasedChan(key)
+		ml.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		timer := time.NewTimer(time.Until(cur.expiresAt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-ch:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Renew extends the lease by its TTL.
+func (ml *MemoryFencedLocker) Renew(ctx context.Context, lease *Lease) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	cur, ok := ml.getLease(lease.Key)
+	if !ok || cur.owner != lease.Owner || cur.token != lease.Token {
+		return ErrLeaseLost
+	}
+	cur.expiresAt = time.Now().Add(lease.TTL)
+	ml.leases[lease.Key] = cur
+	return nil
+}
+
+// Unlock releases the lease and wakes up the waiters on its key.
+func (ml *MemoryFencedLocker) Unlock(ctx context.Context, lease *Lease) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	cur, ok := ml.getLease(lease.Key)
+	if !ok || cur.owner != lease.Owner || cur.token != lease.Token {
+		return ErrLeaseLost
+	}
+	ml.release(lease.Key)
+	return nil
+}
+
+// getLease returns the unexpired lease on key.
+// Expired leases are released lazily here. The caller must hold ml.mu.
+func (ml *MemoryFencedLocker) getLease(key string) (memoryLease, bool) {
+	cur, ok := ml.leases[key]
+	if !ok {
+		return memoryLease{}, false
+	}
+	if !cur.expiresAt.After(time.Now()) {
+		ml.release(key)
+		return memoryLease{}, false
+	}
+	return cur, true
+}
+
+// release removes the lease on key. The caller must hold ml.mu.
+func (ml *MemoryFencedLocker) release(key string) {
+	delete(ml.leases, key)
+	if ch, ok := ml.released[key]; ok {
+		close(ch)
+		delete(ml.released, key)
+	}
+}
+
+// releasedChan returns the channel closed on the next release of key.
+// The caller must hold ml.mu.
+func (ml *MemoryFencedLocker) releasedChan(key string) chan struct{} {
+	ch, ok := ml.released[key]
+	if !ok {
+		ch = make(chan struct{})
+		ml.released[key] = ch
+	}
+	return ch
+}
@@ -0,0 +1,154 @@
+package locker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFencedTryLock(t *testing.T) {
+	locker := NewMemoryFencedLocker()
+	ctx := context.Background()
+
+	lease, err := locker.TryLock(ctx, "key1", "owner-1", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), lease.Token)
+
+	_, err = locker.TryLock(ctx, "key1", "owner-2", time.Second)
+	assert.ErrorIs(t, err, ErrLockHeld)
+
+	// re-acquiring by the same owner keeps the token
+	again, err := locker.TryLock(ctx, "key1", "owner-1", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, lease.Token, again.Token)
+
+	assert.NoError(t, locker.Unlock(ctx, lease))
+	assert.ErrorIs(t, locker.Unlock(ctx, lease), ErrLeaseLost)
+}
+
+// TestFencedTokenIncreases tests that the fencing token increases every time the lock changes hands,
+// including when the previous lease has expired.
+func TestFencedTokenIncreases(t *testing.T) {
+	locker := NewMemoryFencedLocker()
+	ctx := context.Background()
+
+	lease1, err := locker.TryLock(ctx, "key1", "owner-1", 50*time.Millisecond)
+	assert.NoError(t, err)
+
+	time.Sleep(60 * time.Millisecond)
+	lease2, err := locker.TryLock(ctx, "key1", "owner-2", time.Second)
+	assert.NoError(t, err)
+	assert.Greater(t, lease2.Token, lease1.Token)
+
+	// the expired lease can be neither renewed nor released
+	assert.ErrorIs(t, locker.Renew(ctx, lease1), ErrLeaseLost)
+	assert.ErrorIs(t, locker.Unlock(ctx, lease1), ErrLeaseLost)
+
+	assert.NoError(t, locker.Unlock(ctx, lease2))
+	lease3, err := locker.TryLock(ctx, "key1", "owner-1", time.Second)
+	assert.NoError(t, err)
+	assert.Greater(t, lease3.Token, lease2.Token)
+}
+
+func TestFencedRenew(t *testing.T) {
+	locker := NewMemoryFencedLocker()
+	ctx := context.Background()
+
+	lease, err := locker.TryLock(ctx, "key1", "owner-1", 60*time.Millisecond)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		time.Sleep(30 * time.Millisecond)
+		assert.NoError(t, locker.Renew(ctx, lease))
+	}
+	_, err = locker.TryLock(ctx, "key1", "owner-2", time.Second)
+	assert.ErrorIs(t, err, ErrLockHeld)
+}
+
+func TestFencedLockWaitsForUnlock(t *testing.T) {
+	locker := NewMemoryFencedLocker()
+	ctx := context.Background()
+
+	lease, err := locker.TryLock(ctx, "key1", "owner-1", time.Second)
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = locker.Unlock(ctx, lease)
+	}()
+
+	startTime := time.Now()
+	lease2, err := locker.Lock(ctx, "key1", "owner-2", time.Second)
+	assert.NoError(t, err)
+	assert.Greater(t, lease2.Token, lease.Token)
+	assert.True(t, time.Since(startTime) > 40*time.Millisecond)
+	assert.True(t, time.Since(startTime) < 500*time.Millisecond)
+}
+
+func TestFencedLockContextCancelled(t *testing.T) {
+	locker := NewMemoryFencedLocker()
+
+	_, err := locker.TryLock(context.Background(), "key1", "owner-1", time.Second)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = locker.Lock(ctx, "key1", "owner-2", time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestKeepAlive(t *testing.T) {
+	locker := NewMemoryFencedLocker()
+
+	lease, err := locker.TryLock(context.Background(), "key1", "owner-1", 60*time.Millisecond)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := KeepAlive(ctx, locker, lease)
+
+	time.Sleep(200 * time.Millisecond)
+	_, err = locker.TryLock(context.Background(), "key1", "owner-2", time.Second)
+	assert.ErrorIs(t, err, ErrLockHeld)
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
+
+func TestKeepAliveReportsLostLease(t *testing.T) {
+	locker := NewMemoryFencedLocker()
+	ctx := context.Background()
+
+	lease, err := locker.TryLock(ctx, "key1", "owner-1", 60*time.Millisecond)
+	assert.NoError(t, err)
+	errCh := KeepAlive(ctx, locker, lease)
+	assert.NoError(t, locker.Unlock(ctx, lease))
+
+	assert.ErrorIs(t, <-errCh, ErrLeaseLost)
+}
+
+// TestFencedConcurrentLock tests that the lock provides mutual exclusion
+// and every holder sees a larger token than the previous one.
+func TestFencedConcurrentLock(t *testing.T) {
+	locker := NewMemoryFencedLocker()
+	ctx := context.Background()
+
+	var lastToken int64
+	counter := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			lease, err := locker.Lock(ctx, "key1", "owner-"+string(rune('a'+id)), time.Second)
+			assert.NoError(t, err)
+			assert.Greater(t, lease.Token, lastToken)
+			lastToken = lease.Token
+			counter++
+			assert.NoError(t, locker.Unlock(ctx, lease))
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 20, counter)
+}
@@ -0,0 +1,49 @@
+package locker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func newTestRedisClient(t *testing.T) *redis.Client {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	req := testcontainers.ContainerRequest{
+		Image:        "redis:7-alpine",
+		ExposedPorts: []string{"6379/tcp"},
+		WaitingFor:   wait.ForListeningPort("6379/tcp"),
+	}
+	redisContainer, err := testcontainers.GenericContainer(
+		ctx,
+		testcontainers.GenericContainerRequest{
+			ContainerRequest: req,
+			Started:          true,
+		},
+	)
+	if err != nil {
+		t.Fatalf("Could not start redis container: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := redisContainer.Terminate(ctx); err != nil {
+			t.Errorf("Could not stop redis container: %s", err)
+		}
+	})
+
+	mappedPort, _ := redisContainer.MappedPort(ctx, "6379")
+	host, _ := redisContainer.Host(ctx)
+	rdb := redis.NewClient(&redis.Options{Addr: fmt.Sprintf("%s:%s", host, mappedPort.Port())})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return rdb
+}
+
+func TestRedisLocker(t *testing.T) {
+	locker := NewRedisLocker(newTestRedisClient(t), "")
+	testFencedLockerBackend(t, locker, 100*time.Millisecond, 200*time.Millisecond)
+}
@@ -3,11 +3,13 @@ package network
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/kkkzoz/oreo/pkg/config"
 	"github.com/kkkzoz/oreo/pkg/discovery"
 	"github.com/kkkzoz/oreo/pkg/logger" // Use provided logger for client ops
+	"github.com/kkkzoz/oreo/pkg/timesource"
 	"github.com/kkkzoz/oreo/pkg/txn"
 	"github.com/valyala/fasthttp"
 )
@@ -23,6 +25,10 @@ var _ txn.RemoteClient = (*Client)(nil)
 type Client struct {
 	httpClient       *fasthttp.Client
 	serviceDiscovery discovery.ServiceDiscovery
+	// skewDetector compares the lease clocks of the executors with the local one.
+	skewDetector *timesource.SkewDetector
+	// namespace is sent with the requests, see WithNamespace.
+	namespace string
 }
 
 // Constants
@@ -51,9 +57,14 @@ func NewClient(config *discovery.ServiceDiscoveryConfig) (*Client, error) {
 	return &Client{
 		httpClient:       &fasthttp.Client{},
 		serviceDiscovery: sd,
+		skewDetector:     newSkewDetector(),
 	}, nil
 }
 
+func newSkewDetector() *timesource.SkewDetector {
+	return timesource.NewSkewDetector(config.Config.ClockSkewThreshold)
+}
+
 // GetServerAddr returns a server address for the given datastore name
 func (rc *Client) GetServerAddr(dsName string) (string, error) {
 	if rc == nil {
@@ -65,6 +76,15 @@ func (rc *Client) GetServerAddr(dsName string) (string, error) {
 	return rc.serviceDiscovery.GetService(dsName)
 }
 
+// WithNamespace returns a client sending the requests of namespace ns,
+// so the executors route them to the connectors of the namespace.
+// It shares the connections of rc.
+func (rc *Client) WithNamespace(ns string) txn.RemoteClient {
+	c := *rc
+	c.namespace = ns
+	return &c
+}
+
 // Helper to get timeout value (can be extended to read from config)
 func getRequestTimeout() time.Duration {
 	// TODO: Read this value from config.System.NetworkRequestTimeout if available
@@ -98,7 +118,7 @@ func (rc *Client) Read(
 	reqUrl := "http://" + addr + "/read"
 	logger.Log.Debugw("Executing Read request", "url", reqUrl, "dsName", dsName, "key", key)
 
-	reqData := ReadRequest{DsName: dsName, Key: key, StartTime: ts, Config: cfg}
+	reqData := ReadRequest{DsName: dsName, Namespace: rc.namespace, Key: key, StartTime: ts, Config: cfg}
 	jsonData, err := json2.Marshal(reqData)
 	if err != nil {
 		logger.Log.Errorw("Failed to marshal Read request body", "error", err)
@@ -172,14 +192,37 @@ func (rc *Client) Read(
 	} else {
 		errMsg := response.ErrMsg
 		logger.Log.Warnw("Read operation failed on executor (application error)", "url", reqUrl, "error", errMsg)
-		return nil, txn.Normal, "", errors.New(errMsg)
+		return nil, txn.Normal, "", remoteError(errMsg)
 	}
 }
 
+// remoteSentinels are the errors of txn that the coordinator matches with
+// errors.Is, and that reach it as the messages of the executors.
+var remoteSentinels = []error{
+	txn.UncertainRead,
+	txn.KeyAlreadyExists,
+	txn.KeyNotExists,
+	txn.UnexpectedValue,
+}
+
+// remoteError returns the error of an executor with the message errMsg,
+// wrapping the sentinel error it reports, if any.
+func remoteError(errMsg string) error {
+	for _, sentinel := range remoteSentinels {
+		if before, after, ok := strings.Cut(errMsg, sentinel.Error()); ok {
+			return fmt.Errorf("%s%w%s", before, sentinel, after)
+		}
+	}
+	return errors.New(errMsg)
+}
+
 // Prepare sends a prepare request with timeout.
 func (rc *Client) Prepare(dsName string, itemList []txn.DataItem,
 	startTime int64, cfg txn.RecordConfig,
 	validationMap map[string]txn.PredicateInfo,
+	readSet map[string]txn.ReadVersion,
+	conds map[string]txn.WriteCondition,
+	merges map[string][]txn.MergeDelta,
 ) (map[string]string, int64, error) {
 	debugStart := time.Now()
 	if config.Debug.DebugMode {
@@ -207,11 +250,15 @@ func (rc *Client) Prepare(dsName string, itemList []txn.DataItem,
 
 	reqData := PrepareRequest{
 		DsName:        dsName,
+		Namespace:     rc.namespace,
 		ItemType:      GetItemType(dsName),
 		ItemList:      itemList,
 		StartTime:     startTime,
 		Config:        cfg,
 		ValidationMap: validationMap,
+		ReadSet:       readSet,
+		Conditions:    conds,
+		Merges:        merges,
 	}
 	jsonData, err := json2.Marshal(reqData)
 	if err != nil {
@@ -241,7 +288,9 @@ func (rc *Client) Prepare(dsName string, itemList []txn.DataItem,
 		"Timeout",
 		timeout,
 	)
+	sent := config.Config.LeaseClock.Now()
 	err = rc.httpClient.DoTimeout(req, resp, timeout)
+	received := config.Config.LeaseClock.Now()
 	logger.Log.Debugw(
 		"After "+debugMsg,
 		"LatencyInFunc",
@@ -291,13 +340,16 @@ func (rc *Client) Prepare(dsName string, itemList []txn.DataItem,
 		)
 		return nil, 0, fmt.Errorf("unmarshal prepare response error: %w", err)
 	}
+	if response.LeaseNow != 0 && rc.skewDetector != nil {
+		rc.skewDetector.Observe(addr, time.UnixMicro(response.LeaseNow), sent, received)
+	}
 
 	if response.Status == "OK" {
 		return response.VerMap, response.TCommit, nil
 	} else {
 		errMsg := response.ErrMsg
 		logger.Log.Warnw("Prepare operation failed on executor (application error)", "url", reqUrl, "error", errMsg)
-		return nil, 0, errors.New(errMsg)
+		return nil, 0, remoteError(errMsg)
 	}
 }
 
@@ -322,7 +374,7 @@ func (rc *Client) Commit(dsName string, infoList []txn.CommitInfo, tCommit int64
 		len(infoList),
 	)
 
-	reqData := CommitRequest{DsName: dsName, List: infoList, TCommit: tCommit}
+	reqData := CommitRequest{DsName: dsName, Namespace: rc.namespace, List: infoList, TCommit: tCommit}
 	jsonData, err := json2.Marshal(reqData)
 	if err != nil {
 		logger.Log.Errorw("Failed to marshal Commit request body", "error", err)
@@ -389,6 +441,167 @@ func (rc *Client) Commit(dsName string, infoList []txn.CommitInfo, tCommit int64
 	}
 }
 
+// Renew sends a lease renewal request with timeout.
+// It returns the new versions of the renewed records.
+func (rc *Client) Renew(
+	dsName string,
+	infoList []txn.CommitInfo,
+	tLease time.Time,
+) (map[string]string, error) {
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.HTTPAdditionalLatency)
+	}
+
+	addr, err := rc.GetServerAddr(dsName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executor address for renew dsName '%s': %w", dsName, err)
+	}
+	reqUrl := "http://" + addr + "/renew"
+	logger.Log.Debugw(
+		"Executing Renew request",
+		"url",
+		reqUrl,
+		"dsName",
+		dsName,
+		"infoCount",
+		len(infoList),
+	)
+
+	reqData := RenewRequest{DsName: dsName, Namespace: rc.namespace, List: infoList, TLease: tLease}
+	jsonData, err := json2.Marshal(reqData)
+	if err != nil {
+		logger.Log.Errorw("Failed to marshal Renew request body", "error", err)
+		return nil, fmt.Errorf("failed to marshal renew request: %w", err)
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(reqUrl)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/json")
+	req.SetBody(jsonData)
+
+	// Execute with timeout
+	timeout := getRequestTimeout()
+	err = rc.httpClient.DoTimeout(req, resp, timeout)
+	if err != nil {
+		if errors.Is(err, fasthttp.ErrTimeout) {
+			logger.Log.Errorw(
+				"Renew HTTP request timed out",
+				"url",
+				reqUrl,
+				"timeout",
+				timeout,
+				"error",
+				err,
+			)
+			return nil, fmt.Errorf("request to executor %s timed out after %v: %w", reqUrl, timeout, err)
+		}
+		logger.Log.Errorw("Failed to execute Renew HTTP request", "url", reqUrl, "error", err)
+		return nil, fmt.Errorf("http request to executor %s failed: %w", reqUrl, err)
+	}
+
+	if resp.StatusCode() != fasthttp.StatusOK {
+		errMsg := fmt.Sprintf("executor %s returned status %d for renew", addr, resp.StatusCode())
+		logger.Log.Warnw(errMsg, "url", reqUrl, "responseBody", string(resp.Body()))
+		return nil, errors.New(errMsg)
+	}
+
+	var response Response[map[string]string]
+	err = json2.Unmarshal(resp.Body(), &response)
+	if err != nil {
+		logger.Log.Errorw(
+			"Failed to unmarshal Renew response body",
+			"url",
+			reqUrl,
+			"body",
+			string(resp.Body()),
+			"error",
+			err,
+		)
+		return nil, fmt.Errorf("unmarshal renew response error: %w", err)
+	}
+
+	if response.Status == "OK" {
+		return response.Data, nil
+	} else {
+		errMsg := response.ErrMsg
+		logger.Log.Warnw("Renew operation failed on executor (application error)", "url", reqUrl, "error", errMsg)
+		return nil, errors.New(errMsg)
+	}
+}
+
+// QueryOutcome asks the executor of the first datastore in dsNames for the
+// outcome of txnId, whose keys in each datastore are given by keys.
+// The executor must serve all the datastores in dsNames.
+func (rc *Client) QueryOutcome(
+	txnId string,
+	dsNames []string,
+	keys map[string][]string,
+) (txn.TxnOutcome, error) {
+	if len(dsNames) == 0 {
+		return txn.TxnOutcome{}, errors.New("no datastores given")
+	}
+	addr, err := rc.GetServerAddr(dsNames[0])
+	if err != nil {
+		return txn.TxnOutcome{}, fmt.Errorf("failed to get executor address for status dsName '%s': %w", dsNames[0], err)
+	}
+	reqUrl := "http://" + addr + "/status"
+	logger.Log.Debugw("Executing Status request", "url", reqUrl, "txnId", txnId, "dsNames", dsNames)
+
+	reqData := StatusRequest{TxnId: txnId, Namespace: rc.namespace, DsNames: dsNames, Keys: keys}
+	jsonData, err := json2.Marshal(reqData)
+	if err != nil {
+		logger.Log.Errorw("Failed to marshal Status request body", "error", err)
+		return txn.TxnOutcome{}, fmt.Errorf("failed to marshal status request: %w", err)
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(reqUrl)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/json")
+	req.SetBody(jsonData)
+
+	timeout := getRequestTimeout()
+	err = rc.httpClient.DoTimeout(req, resp, timeout)
+	if err != nil {
+		if errors.Is(err, fasthttp.ErrTimeout) {
+			logger.Log.Errorw("Status HTTP request timed out", "url", reqUrl, "timeout", timeout, "error", err)
+			return txn.TxnOutcome{}, fmt.Errorf("request to executor %s timed out after %v: %w", reqUrl, timeout, err)
+		}
+		logger.Log.Errorw("Failed to execute Status HTTP request", "url", reqUrl, "error", err)
+		return txn.TxnOutcome{}, fmt.Errorf("http request to executor %s failed: %w", reqUrl, err)
+	}
+
+	if resp.StatusCode() != fasthttp.StatusOK {
+		errMsg := fmt.Sprintf("executor %s returned status %d for status", addr, resp.StatusCode())
+		logger.Log.Warnw(errMsg, "url", reqUrl, "responseBody", string(resp.Body()))
+		return txn.TxnOutcome{}, errors.New(errMsg)
+	}
+
+	var response Response[txn.TxnOutcome]
+	err = json2.Unmarshal(resp.Body(), &response)
+	if err != nil {
+		logger.Log.Errorw("Failed to unmarshal Status response body", "url", reqUrl, "body", string(resp.Body()), "error", err)
+		return txn.TxnOutcome{}, fmt.Errorf("unmarshal status response error: %w", err)
+	}
+
+	if response.Status == "OK" {
+		return response.Data, nil
+	} else {
+		errMsg := response.ErrMsg
+		logger.Log.Warnw("Status operation failed on executor (application error)", "url", reqUrl, "error", errMsg)
+		return txn.TxnOutcome{}, errors.New(errMsg)
+	}
+}
+
 // Abort sends an abort request with timeout.
 func (rc *Client) Abort(dsName string, keyList []string, groupKeyList string) error {
 	if config.Debug.DebugMode {
@@ -410,7 +623,7 @@ func (rc *Client) Abort(dsName string, keyList []string, groupKeyList string) er
 		len(keyList),
 	)
 
-	reqData := AbortRequest{DsName: dsName, KeyList: keyList, GroupKeyList: groupKeyList}
+	reqData := AbortRequest{DsName: dsName, Namespace: rc.namespace, KeyList: keyList, GroupKeyList: groupKeyList}
 	jsonData, err := json2.Marshal(reqData)
 	if err != nil {
 		logger.Log.Errorw("Failed to marshal Abort request body", "error", err)
@@ -0,0 +1,18 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteError(t *testing.T) {
+	err := remoteError("prepare failed: " + txn.KeyAlreadyExists.Error() + ", key: x")
+	assert.ErrorIs(t, err, txn.KeyAlreadyExists)
+	assert.True(t, txn.IsConditionFailed(err))
+	assert.Equal(t, "prepare failed: "+txn.KeyAlreadyExists.Error()+", key: x", err.Error())
+
+	assert.True(t, txn.IsUncertainRead(remoteError(txn.UncertainRead.Error())))
+	assert.False(t, txn.IsConditionFailed(remoteError("version mismatch")))
+}
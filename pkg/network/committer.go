@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/alitto/pond/v2"
-	"github.com/kkkzoz/oreo/internal/util"
 	"github.com/kkkzoz/oreo/pkg/config"
 	"github.com/kkkzoz/oreo/pkg/logger"
 	"github.com/kkkzoz/oreo/pkg/serializer"
@@ -66,7 +65,7 @@ func (c *Committer) validate(dsName string, cfg txn.RecordConfig,
 			if err != nil {
 				// For AssumeAbort
 				if cfg.ReadStrategy == config.AssumeAbort {
-					if pred.LeaseTime.Before(time.Now()) {
+					if pred.LeaseTime.Before(config.Config.LeaseClock.Now()) {
 						key := pred.ItemKey
 						err := c.rollbackFromConn(dsName, key)
 						if err != nil {
@@ -118,6 +117,9 @@ func (c *Committer) validate(dsName string, cfg txn.RecordConfig,
 func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 	startTime int64, cfg txn.RecordConfig,
 	validateMap map[string]txn.PredicateInfo,
+	readSet map[string]txn.ReadVersion,
+	conds map[string]txn.WriteCondition,
+	merges map[string][]txn.MergeDelta,
 ) (map[string]string, int64, error) {
 	debugStart := time.Now()
 
@@ -137,6 +139,12 @@ func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 		return nil, 0, err
 	}
 
+	// the read set is validated on its own,
+	// after the writes of the transaction are prepared
+	if len(itemList) == 0 {
+		return nil, 0, c.validateReadSet(dsName, cfg, readSet)
+	}
+
 	var tCommit int64
 
 	if cfg.AblationLevel >= 3 {
@@ -153,9 +161,23 @@ func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 	subPool := c.pool.NewSubpool(5)
 	taskGroup := subPool.NewGroup()
 
+	// with a batch connector, the conditional updates are collected
+	// and sent at once after the records are read
+	batchConn, isBatch := c.connMap[dsName].(txn.BatchConnector)
+	var ops []txn.UpdateOp
+
 	for _, it := range itemList {
 		item := it
 		taskGroup.SubmitErr(func() error {
+			if deltas, ok := merges[item.Key()]; ok {
+				ver, err := c.mergeUpdate(dsName, item, deltas, tCommit, cfg)
+
+				mu.Lock()
+				defer mu.Unlock()
+				versionMap[item.Key()] = ver
+				return err
+			}
+
 			var doCreate bool
 			// if this item follows the read-modify-write pattern
 			if item.Version() != "" {
@@ -167,17 +189,34 @@ func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 					logger.Log.Errorw("Read error", "error", err)
 					return err
 				}
+				if cond, ok := conds[item.Key()]; ok {
+					if err := cond.Check(item.Key(), dbItem); err != nil {
+						return err
+					}
+				}
 				if dbItem == nil {
 					doCreate = true
 				} else {
 					doCreate = false
 				}
-				item, _ = c.updateMetadata(item, dbItem, 0, cfg)
+				item, err = c.updateMetadata(item, dbItem, tCommit, cfg)
+				if err != nil {
+					return err
+				}
 			}
 
 			// add TCommit to the item
 			item.SetTValid(tCommit)
+			if isBatch {
+				mu.Lock()
+				defer mu.Unlock()
+				ops = append(ops, txn.UpdateOp{Key: item.Key(), Value: item, DoCreate: doCreate})
+				return nil
+			}
 			ver, err := c.connMap[dsName].ConditionalUpdate(item.Key(), item, doCreate)
+			if cond, ok := conds[item.Key()]; ok && err != nil {
+				err = cond.Replaced(item.Key(), err)
+			}
 
 			mu.Lock()
 			defer mu.Unlock()
@@ -186,6 +225,17 @@ func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 		})
 	}
 	err = taskGroup.Wait()
+	if err == nil && len(ops) > 0 {
+		for i, res := range batchConn.ConditionalUpdateBatch(ops) {
+			versionMap[ops[i].Key] = res.Version
+			if res.Err != nil && err == nil {
+				err = res.Err
+				if cond, ok := conds[ops[i].Key]; ok {
+					err = cond.Replaced(ops[i].Key, err)
+				}
+			}
+		}
+	}
 	if err != nil {
 		if cfg.AblationLevel >= 4 {
 			_ = c.createGroupKey(dsName, itemList[0], config.ABORTED, tCommit)
@@ -200,7 +250,10 @@ func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 		"CheckPoint",
 	)
 
-	if cfg.AblationLevel >= 4 {
+	// in serializable mode, the reads are validated by a later call once
+	// all the datastores are prepared, so the group key is left to the
+	// coordinator
+	if cfg.AblationLevel >= 4 && cfg.IsolationLevel != config.Serializable {
 		// create the corresponding group key
 		if len(itemList) > 0 {
 			err = c.createGroupKey(dsName, itemList[0], config.COMMITTED, tCommit)
@@ -214,6 +267,66 @@ func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 	return versionMap, tCommit, nil
 }
 
+// mergeUpdate applies the deltas on top of the latest committed version
+// of the record and performs the conditional update.
+// It retries when the record is updated concurrently, since the deltas commute.
+func (c *Committer) mergeUpdate(dsName string, item txn.DataItem,
+	deltas []txn.MergeDelta, tCommit int64, cfg txn.RecordConfig,
+) (string, error) {
+	// the outcome of a prepared record has to be known before merging into it
+	resolveCfg := cfg
+	resolveCfg.ReadStrategy = config.Pessimistic
+
+	for i := 0; i < txn.MERGERETRY; i++ {
+		dbItem, err := c.connMap[dsName].GetItem(item.Key())
+		if err == nil {
+			dbItem, _, err = c.reader.basicVisibilityProcessor(dsName, dbItem, 0, resolveCfg)
+		}
+		if err != nil {
+			// the record is being prepared or resolved by another transaction
+			if err.Error() == ReadFailed ||
+				strings.Contains(err.Error(), txn.VersionMismatch.Error()) {
+				time.Sleep(txn.RETRYINTERVAL)
+				continue
+			}
+			if !strings.Contains(err.Error(), "key not found") {
+				return "", err
+			}
+			dbItem = nil
+		}
+
+		value, err := txn.ApplyMerges(c.se, dbItem, deltas)
+		if err != nil {
+			return "", err
+		}
+		item.SetValue(value)
+		item.SetIsDeleted(false)
+		item.SetPrev("")
+		item.SetVersion("")
+		newItem, err := c.updateMetadata(item, dbItem, tCommit, cfg)
+		if err != nil {
+			return "", err
+		}
+		newItem.SetTValid(tCommit)
+		ver, err := c.connMap[dsName].ConditionalUpdate(newItem.Key(), newItem, dbItem == nil)
+		if err == nil || !strings.Contains(err.Error(), txn.VersionMismatch.Error()) {
+			return ver, err
+		}
+		time.Sleep(txn.RETRYINTERVAL)
+	}
+	return "", fmt.Errorf("%v, key: %s", txn.MergeConflict, item.Key())
+}
+
+// validateReadSet validates the read set of a transaction in serializable mode.
+func (c *Committer) validateReadSet(dsName string, cfg txn.RecordConfig,
+	readSet map[string]txn.ReadVersion,
+) error {
+	if cfg.IsolationLevel != config.Serializable || len(readSet) == 0 {
+		return nil
+	}
+	return txn.ValidateReadSet(c.connMap[dsName], readSet)
+}
+
 func (c *Committer) createGroupKey(
 	dsName string,
 	item txn.DataItem,
@@ -248,111 +361,142 @@ func (c *Committer) Abort(dsName string, keyList []string, groupKeyList string)
 	return taskGroup.Wait()
 }
 
+// Commit commits the records of infoList in dsName at tCommit.
+// The records written with a TTL are then set to expire natively,
+// see txn.ExpireNatively.
 func (c *Committer) Commit(dsName string, infoList []txn.CommitInfo, tCommit int64) error {
+	conn := c.connMap[dsName]
 	// var eg errgroup.Group
+	if batchConn, ok := conn.(txn.BatchConnector); ok {
+		for i, res := range batchConn.ConditionalCommitBatch(infoList, tCommit) {
+			if res.Err != nil {
+				return res.Err
+			}
+			err := txn.ExpireNatively(conn, infoList[i].Key, res.Version, infoList[i].TExpire)
+			logger.CheckAndLogError("failed to expire the record natively", err)
+		}
+		return nil
+	}
+
 	subPool := c.pool.NewSubpool(5)
 	taskGroup := subPool.NewGroup()
 	for _, info := range infoList {
 		item := info
 		taskGroup.SubmitErr(func() error {
-			_, err := c.connMap[dsName].ConditionalCommit(item.Key, item.Version, tCommit)
-			return err
+			ver, err := conn.ConditionalCommit(item.Key, item.Version, tCommit)
+			if err != nil {
+				return err
+			}
+			err = txn.ExpireNatively(conn, item.Key, ver, item.TExpire)
+			logger.CheckAndLogError("failed to expire the record natively", err)
+			return nil
 		})
 	}
 	return taskGroup.Wait()
 }
 
-// truncate truncates the linked list of DataItems
-// if the length exceeds the maximum record length defined in the configuration.
-//
-// It takes a pointer to a DataItem as input and returns the truncated DataItem and an error, if any.
-// If the length of the linked list is greater than the maximum record length, it creates a stack of DataItems and pops the items from the stack until the length is reduced to the maximum record length.
-//
-// It then updates the Prev and LinkedLen fields of the DataItems in the stack accordingly.
-// Finally, it returns the last popped DataItem as the truncated DataItem.
-//
-// If the length of the linked list is less than or equal to the maximum record length, it returns the input DataItem as is.
-func (c *Committer) truncate(newItem txn.DataItem, cfg txn.RecordConfig) (txn.DataItem, error) {
-	maxLen := cfg.MaxRecordLen
-
-	if newItem.LinkedLen() > maxLen {
-		stack := util.NewStack[txn.DataItem]()
-		stack.Push(newItem)
-		curItem := &newItem
-		for i := 1; i <= maxLen-1; i++ {
-			preItem, err := c.getPrevItem(*curItem)
+// Renew sets the TLease of the prepared records to tLease
+// and returns their new versions.
+// It fails if any of them is no longer the version prepared by the transaction.
+func (c *Committer) Renew(
+	dsName string,
+	infoList []txn.CommitInfo,
+	tLease time.Time,
+) (map[string]string, error) {
+	var mu sync.Mutex
+	versionMap := make(map[string]string)
+	subPool := c.pool.NewSubpool(5)
+	taskGroup := subPool.NewGroup()
+	for _, info := range infoList {
+		info := info
+		taskGroup.SubmitErr(func() error {
+			item, err := c.connMap[dsName].GetItem(info.Key)
 			if err != nil {
-				return nil, errors.New("Unmarshal error: " + err.Error())
+				return err
 			}
-			curItem = &preItem
-			stack.Push(*curItem)
-		}
-
-		tarItem, err := stack.Pop()
-		if err != nil {
-			return nil, errors.New("Pop error: " + err.Error())
-		}
-		tarItem.SetPrev("")
-		tarItem.SetLinkedLen(1)
-
-		for !stack.IsEmpty() {
-			item, err := stack.Pop()
-			if err != nil {
-				return nil, errors.New("Pop error: " + err.Error())
+			if item.Version() != info.Version || item.TxnState() != config.PREPARED {
+				return fmt.Errorf("failed to renew the lease of %s: %v", info.Key, txn.VersionMismatch)
 			}
-			bs, err := c.se.Serialize(tarItem)
+			item.SetTLease(tLease)
+			ver, err := c.connMap[dsName].ConditionalUpdate(info.Key, item, false)
 			if err != nil {
-				return nil, errors.New("Serialize error: " + err.Error())
+				return fmt.Errorf("failed to renew the lease of %s: %v", info.Key, err)
 			}
-			item.SetPrev(string(bs))
-			item.SetLinkedLen(tarItem.LinkedLen() + 1)
-			tarItem = item
+			mu.Lock()
+			defer mu.Unlock()
+			versionMap[info.Key] = ver
+			return nil
+		})
+	}
+	if err := taskGroup.Wait(); err != nil {
+		return nil, err
+	}
+	return versionMap, nil
+}
+
+// QueryOutcome resolves the outcome of txnId from its group keys in the
+// datastores dsNames, aborting it if it is undecided and the leases of its
+// records at keys have expired. See txn.QueryOutcome.
+func (c *Committer) QueryOutcome(
+	txnId string,
+	dsNames []string,
+	keys map[string][]string,
+) (txn.TxnOutcome, error) {
+	dss := make([]txn.Datastorer, 0, len(dsNames))
+	for _, dsName := range dsNames {
+		conn, ok := c.connMap[dsName]
+		if !ok {
+			return txn.TxnOutcome{}, fmt.Errorf("datastore %s not found", dsName)
 		}
-		return tarItem, nil
-	} else {
-		return newItem, nil
+		dss = append(dss, txn.NewDatastore(dsName, conn, c.itemFactory))
 	}
+	return txn.QueryOutcome(txnId, keys, dss...)
 }
 
 // updateMetadata updates the metadata of a DataItem by comparing it with the oldItem.
 //
 // If the oldItem is empty, it sets the LinkedLen of the newItem to 1.
-// Otherwise, it increments the LinkedLen of the newItem by 1 and sets the Prev and Version fields based on the oldItem.
+// Otherwise, it truncates the oldItem to the versions kept by the retention policy,
+// and sets the Prev, LinkedLen and Version fields of the newItem based on it.
 //
-// It then truncates the record using the truncate method and sets the TxnState, TValid, and TLease fields of the newItem.
+// It then sets the TxnState, TValid, and TLease fields of the newItem.
 // Finally, it returns the updated newItem and any error that occurred during the process.
 func (c *Committer) updateMetadata(newItem txn.DataItem,
 	oldItem txn.DataItem, commitTime int64, cfg txn.RecordConfig,
 ) (txn.DataItem, error) {
-	if oldItem == nil {
-		newItem.SetLinkedLen(1)
-	} else {
-		newItem.SetLinkedLen(oldItem.LinkedLen() + 1)
-		bs, err := c.se.Serialize(oldItem)
+	newItem.SetLinkedLen(1)
+	if oldItem != nil {
+		version := oldItem.Version()
+		truncatedOld, err := txn.TruncateHistory(txn.RetentionPolicyOr(cfg.MaxRecordLen),
+			oldItem, commitTime, cfg.OldestSnapshot, c.getPrevItem, serializer.Unencrypted(c.se))
 		if err != nil {
 			return nil, err
 		}
-		newItem.SetPrev(string(bs))
-		newItem.SetVersion(oldItem.Version())
-	}
-
-	// truncate the record
-	newItem, err := c.truncate(newItem, cfg)
-	if err != nil {
-		return nil, err
+		if truncatedOld != nil {
+			bs, err := serializer.Unencrypted(c.se).Serialize(truncatedOld)
+			if err != nil {
+				return nil, err
+			}
+			newItem.SetPrev(string(bs))
+			newItem.SetLinkedLen(truncatedOld.LinkedLen() + 1)
+		}
+		newItem.SetVersion(version)
 	}
 
 	newItem.SetTxnState(config.PREPARED)
 	newItem.SetTValid(commitTime)
-	// TODO: time.Now() is temporary
-	newItem.SetTLease(time.Now().Add(config.Config.LeaseTime))
+	leaseTime := cfg.LeaseTime
+	if leaseTime == 0 {
+		leaseTime = config.Config.LeaseTime
+	}
+	newItem.SetTLease(config.Config.LeaseClock.Now().Add(leaseTime))
 	return newItem, nil
 }
 
 func (c *Committer) getPrevItem(item txn.DataItem) (txn.DataItem, error) {
 	// WTF??
 	preItem := c.itemFactory.NewDataItem(txn.ItemOptions{})
-	err := c.se.Deserialize([]byte(item.Prev()), &preItem)
+	err := serializer.Unencrypted(c.se).Deserialize([]byte(item.Prev()), &preItem)
 	if err != nil {
 		return nil, err
 	}
@@ -405,7 +549,7 @@ func (c *Committer) rollbackFromConn(dsName string, key string) error {
 	// 	return errors.New("rollback failed due to wrong txnId")
 	// }
 
-	if item.TLease().Before(time.Now()) {
+	if item.TLease().Before(config.Config.LeaseClock.Now()) {
 		successNum := c.reader.createGroupKey(
 			strings.Split(item.GroupKeyList(), ","),
 			config.ABORTED,
@@ -0,0 +1,82 @@
+package network
+
+import (
+	"sync"
+
+	"github.com/kkkzoz/oreo/pkg/serializer"
+	"github.com/kkkzoz/oreo/pkg/timesource"
+	"github.com/kkkzoz/oreo/pkg/txn"
+)
+
+// Namespaces keeps a Reader and a Committer per namespace, whose connectors
+// are routed to the namespace, see txn.Namespacer. The keys of the requests
+// are already prefixed with the namespace by the clients, so the namespaces
+// share the default Reader and Committer if no connector is a Namespacer.
+type Namespaces struct {
+	connMap     map[string]txn.Connector
+	itemFactory txn.DataItemFactory
+	se          serializer.Serializer
+	timeSource  timesource.TimeSourcer
+
+	reader    *Reader
+	committer *Committer
+	routed    bool
+
+	mu         sync.Mutex
+	readers    map[string]*Reader
+	committers map[string]*Committer
+}
+
+// NewNamespaces returns the namespaces of the executor serving connMap,
+// with reader and committer serving the requests without a namespace.
+func NewNamespaces(
+	connMap map[string]txn.Connector,
+	reader *Reader,
+	committer *Committer,
+	se serializer.Serializer,
+	itemFactory txn.DataItemFactory,
+	timeSource timesource.TimeSourcer,
+) *Namespaces {
+	n := &Namespaces{
+		connMap:     connMap,
+		itemFactory: itemFactory,
+		se:          se,
+		timeSource:  timeSource,
+		reader:      reader,
+		committer:   committer,
+		readers:     make(map[string]*Reader),
+		committers:  make(map[string]*Committer),
+	}
+	for _, conn := range connMap {
+		if _, ok := conn.(txn.Namespacer); ok {
+			n.routed = true
+		}
+	}
+	return n
+}
+
+// Get returns the Reader and the Committer of namespace ns.
+func (n *Namespaces) Get(ns string) (*Reader, *Committer, error) {
+	if ns == "" || !n.routed {
+		return n.reader, n.committer, nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if reader, ok := n.readers[ns]; ok {
+		return reader, n.committers[ns], nil
+	}
+	connMap := make(map[string]txn.Connector, len(n.connMap))
+	for dsName, conn := range n.connMap {
+		nsConn, err := txn.NamespacedConnector(conn, ns)
+		if err != nil {
+			return nil, nil, err
+		}
+		connMap[dsName] = nsConn
+	}
+	reader := NewReader(connMap, n.itemFactory, n.se, NewCacher())
+	committer := NewCommitter(connMap, *reader, n.se, n.itemFactory, n.timeSource)
+	n.readers[ns] = reader
+	n.committers[ns] = committer
+	return reader, committer, nil
+}
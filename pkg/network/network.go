@@ -2,12 +2,16 @@ package network
 
 import (
 	"fmt"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
 	"github.com/kkkzoz/oreo/pkg/datastore/cassandra"
 	"github.com/kkkzoz/oreo/pkg/datastore/couchdb"
 	"github.com/kkkzoz/oreo/pkg/datastore/dynamodb"
+	"github.com/kkkzoz/oreo/pkg/datastore/etcd"
 	"github.com/kkkzoz/oreo/pkg/datastore/mongo"
+	"github.com/kkkzoz/oreo/pkg/datastore/postgres"
 	"github.com/kkkzoz/oreo/pkg/datastore/redis"
 	"github.com/kkkzoz/oreo/pkg/datastore/tikv"
 	"github.com/kkkzoz/oreo/pkg/txn"
@@ -31,7 +35,9 @@ type ReadResponse struct {
 }
 
 type ReadRequest struct {
-	DsName    string
+	DsName string
+	// Namespace is the namespace of the transaction, see txn.Namespacer.
+	Namespace string
 	Key       string
 	StartTime int64
 	Config    txn.RecordConfig
@@ -39,7 +45,11 @@ type ReadRequest struct {
 
 type PrepareRequest struct {
 	DsName        string
+	Namespace     string
 	ValidationMap map[string]txn.PredicateInfo
+	ReadSet       map[string]txn.ReadVersion
+	Conditions    map[string]txn.WriteCondition
+	Merges        map[string][]txn.MergeDelta
 	ItemType      txn.ItemType
 	ItemList      []txn.DataItem
 	StartTime     int64
@@ -51,17 +61,37 @@ type PrepareResponse struct {
 	ErrMsg  string
 	TCommit int64
 	VerMap  map[string]string
+	// LeaseNow is the time of the lease clock of the executor in microseconds,
+	// which is compared with the lease clock of the client to detect clock skew.
+	LeaseNow int64
 }
 
 type CommitRequest struct {
-	DsName  string
-	List    []txn.CommitInfo
-	TCommit int64
+	DsName    string
+	Namespace string
+	List      []txn.CommitInfo
+	TCommit   int64
+}
+
+type RenewRequest struct {
+	DsName    string
+	Namespace string
+	List      []txn.CommitInfo
+	TLease    time.Time
+}
+
+type StatusRequest struct {
+	TxnId     string
+	Namespace string
+	DsNames   []string
+	// Keys maps each datastore to the keys written by the transaction.
+	Keys map[string][]string
 }
 
 type AbortRequest struct {
-	DsName  string
-	KeyList []string
+	DsName    string
+	Namespace string
+	KeyList   []string
 	// TxnId   string
 	GroupKeyList string
 }
@@ -122,6 +152,24 @@ func (r *ReadResponse) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		r.Data = &tikvItem
+	case txn.BoltItem:
+		var boltItem bolt.BoltItem
+		if err := json2.Unmarshal(aux.Data, &boltItem); err != nil {
+			return err
+		}
+		r.Data = &boltItem
+	case txn.PostgresItem:
+		var postgresItem postgres.PostgresItem
+		if err := json2.Unmarshal(aux.Data, &postgresItem); err != nil {
+			return err
+		}
+		r.Data = &postgresItem
+	case txn.EtcdItem:
+		var etcdItem etcd.EtcdItem
+		if err := json2.Unmarshal(aux.Data, &etcdItem); err != nil {
+			return err
+		}
+		r.Data = &etcdItem
 	case txn.NoneItem:
 		r.Data = nil
 	default:
@@ -133,12 +181,16 @@ func (r *ReadResponse) UnmarshalJSON(data []byte) error {
 
 func (p *PrepareRequest) UnmarshalJSON(data []byte) error {
 	type TempRequest struct {
-		DsName        string                       `json:"DsName"`
-		ValidationMap map[string]txn.PredicateInfo `json:"ValidationMap"`
-		ItemType      txn.ItemType                 `json:"ItemType"`
-		StartTime     int64                        `json:"StartTime"`
-		Config        txn.RecordConfig             `json:"Config"`
-		ItemList      jsoniter.RawMessage          `json:"ItemList"`
+		DsName        string                        `json:"DsName"`
+		Namespace     string                        `json:"Namespace"`
+		ValidationMap map[string]txn.PredicateInfo  `json:"ValidationMap"`
+		ReadSet       map[string]txn.ReadVersion    `json:"ReadSet"`
+		Conditions    map[string]txn.WriteCondition `json:"Conditions"`
+		Merges        map[string][]txn.MergeDelta   `json:"Merges"`
+		ItemType      txn.ItemType                  `json:"ItemType"`
+		StartTime     int64                         `json:"StartTime"`
+		Config        txn.RecordConfig              `json:"Config"`
+		ItemList      jsoniter.RawMessage           `json:"ItemList"`
 	}
 
 	var aux TempRequest
@@ -147,7 +199,11 @@ func (p *PrepareRequest) UnmarshalJSON(data []byte) error {
 	}
 
 	p.DsName = aux.DsName
+	p.Namespace = aux.Namespace
 	p.ValidationMap = aux.ValidationMap
+	p.ReadSet = aux.ReadSet
+	p.Conditions = aux.Conditions
+	p.Merges = aux.Merges
 	p.ItemType = aux.ItemType
 	p.StartTime = aux.StartTime
 	p.Config = aux.Config
@@ -216,6 +272,36 @@ func (p *PrepareRequest) UnmarshalJSON(data []byte) error {
 			item := it
 			p.ItemList[i] = &item
 		}
+	case txn.BoltItem:
+		var boltItemList []bolt.BoltItem
+		if err := json2.Unmarshal(aux.ItemList, &boltItemList); err != nil {
+			return err
+		}
+		p.ItemList = make([]txn.DataItem, len(boltItemList))
+		for i, it := range boltItemList {
+			item := it
+			p.ItemList[i] = &item
+		}
+	case txn.PostgresItem:
+		var postgresItemList []postgres.PostgresItem
+		if err := json2.Unmarshal(aux.ItemList, &postgresItemList); err != nil {
+			return err
+		}
+		p.ItemList = make([]txn.DataItem, len(postgresItemList))
+		for i, it := range postgresItemList {
+			item := it
+			p.ItemList[i] = &item
+		}
+	case txn.EtcdItem:
+		var etcdItemList []etcd.EtcdItem
+		if err := json2.Unmarshal(aux.ItemList, &etcdItemList); err != nil {
+			return err
+		}
+		p.ItemList = make([]txn.DataItem, len(etcdItemList))
+		for i, it := range etcdItemList {
+			item := it
+			p.ItemList[i] = &item
+		}
 	case txn.NoneItem:
 		p.ItemList = nil
 	default:
@@ -241,6 +327,12 @@ func GetItemType(dsName string) txn.ItemType {
 		return txn.DynamoDBItem
 	case "TiKV":
 		return txn.TiKVItem
+	case "Bolt":
+		return txn.BoltItem
+	case "Postgres":
+		return txn.PostgresItem
+	case "Etcd":
+		return txn.EtcdItem
 	default:
 		return ""
 	}
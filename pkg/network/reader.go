@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/kkkzoz/oreo/internal/util"
 	"github.com/kkkzoz/oreo/pkg/config"
@@ -41,9 +40,10 @@ func NewReader(
 	}
 }
 
-// If the record is marked as IsDeleted, this function will return it.
+// If the record is marked as IsDeleted, or has expired, this function will return it.
 //
-// Let the upper layer decide what to do with it
+// Let the upper layer decide what to do with it: the write conditions
+// and the merges treat the expired records as absent, like the deleted ones.
 func (r *Reader) Read(dsName string, key string, ts int64, cfg txn.RecordConfig,
 	isRemoteCall bool,
 ) (txn.DataItem, txn.RemoteDataStrategy, string, error) {
@@ -145,7 +145,7 @@ func (r *Reader) basicVisibilityProcessor(dsName string, item txn.DataItem,
 		// and if t_lease has expired
 		// that is, item's TLease < current time
 		// we should roll back the record
-		if item.TLease().Before(time.Now()) {
+		if item.TLease().Before(config.Config.LeaseClock.Now()) {
 			successNum := r.createGroupKey(
 				strings.Split(item.GroupKeyList(), ","),
 				config.ABORTED,
@@ -253,7 +253,7 @@ func (r *Reader) rollForward(dsName string, item txn.DataItem) (txn.DataItem, er
 
 func (r *Reader) getPrevItem(item txn.DataItem) (txn.DataItem, error) {
 	preItem := r.itemFactory.NewDataItem(txn.ItemOptions{})
-	err := r.se.Deserialize([]byte(item.Prev()), &preItem)
+	err := serializer.Unencrypted(r.se).Deserialize([]byte(item.Prev()), &preItem)
 	if err != nil {
 		return nil, err
 	}
@@ -408,19 +408,26 @@ func (r *Reader) createSingleGroupKey(url string, state config.State, tCommit in
 
 // treatAsCommitted treats a DataItem as committed, finds a corresponding version
 // according to its timestamp, and performs the given logic function on it.
+// It looks as deep as the retention policy keeps the versions of the item.
 func (r *Reader) treatAsCommitted(item txn.DataItem,
 	startTime int64, logicFunc func(txn.DataItem, bool) (txn.DataItem, error),
 	cfg txn.RecordConfig,
 ) (txn.DataItem, error) {
+	maxLen := txn.RetentionPolicyOr(cfg.MaxRecordLen).MaxVersions(item.Key())
 	curItem := item
-	for i := 1; i <= cfg.MaxRecordLen; i++ {
+	for i := 1; maxLen == 0 || i <= maxLen; i++ {
 
 		if curItem.TValid() < startTime {
 			// find the corresponding version,
 			// do some business logic.
 			return logicFunc(curItem, true)
 		}
-		if i == cfg.MaxRecordLen {
+		// the version may have committed before the transaction started
+		// on a node whose clock is ahead of the coordinator's
+		if cfg.ClockUncertainty != 0 && curItem.TValid() < startTime+cfg.ClockUncertainty {
+			return nil, txn.UncertainRead
+		}
+		if i == maxLen {
 			break
 		}
 		// if prev is empty
@@ -0,0 +1,134 @@
+package serializer
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is a compression algorithm of CompressedSerializer.
+type Codec byte
+
+const (
+	// NoCompression never compresses, but still decodes compressed values.
+	NoCompression Codec = 0
+	Zstd          Codec = 'z'
+	Snappy        Codec = 's'
+)
+
+// DefaultCompressionThreshold is the size in bytes
+// below which the values are not compressed.
+const DefaultCompressionThreshold = 256
+
+// compressedHeader starts every compressed value. Neither JSON nor gob
+// output starts with it, so the values written before compression was
+// enabled are told apart and passed to the inner serializer as they are.
+const compressedHeader byte = 0x01
+
+// ParseCodec returns the codec named name: "zstd", "snappy",
+// or "" and "none" for NoCompression.
+func ParseCodec(name string) (Codec, error) {
+	switch name {
+	case "", "none":
+		return NoCompression, nil
+	case "zstd":
+		return Zstd, nil
+	case "snappy":
+		return Snappy, nil
+	default:
+		return NoCompression, fmt.Errorf("unknown compression codec: %s", name)
+	}
+}
+
+// CompressedSerializer compresses the output of another serializer.
+//
+// A value of at least threshold bytes is stored as the header byte,
+// the codec and the base64 encoding of the compressed value. The values
+// are stored as strings in the records, which the JSON based datastores
+// cannot hold as arbitrary bytes, hence the base64 encoding.
+// Smaller values, and values that do not shrink, are stored uncompressed.
+//
+// Deserialize decodes the values of any codec,
+// so the codec can be changed without rewriting the records.
+type CompressedSerializer struct {
+	inner     Serializer
+	codec     Codec
+	threshold int
+
+	// the zstd encoder and decoder are safe for concurrent use
+	// with EncodeAll and DecodeAll
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// NewCompressedSerializer wraps inner with codec. A threshold of zero
+// means DefaultCompressionThreshold.
+func NewCompressedSerializer(inner Serializer, codec Codec, threshold int) *CompressedSerializer {
+	if threshold <= 0 {
+		threshold = DefaultCompressionThreshold
+	}
+	s := &CompressedSerializer{
+		inner:     inner,
+		codec:     codec,
+		threshold: threshold,
+	}
+	// the options are valid, so the errors are nil
+	s.encoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	s.decoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	return s
+}
+
+func (s *CompressedSerializer) Serialize(data any) ([]byte, error) {
+	bs, err := s.inner.Serialize(data)
+	if err != nil || s.codec == NoCompression || len(bs) < s.threshold {
+		return bs, err
+	}
+
+	var compressed []byte
+	switch s.codec {
+	case Zstd:
+		compressed = s.encoder.EncodeAll(bs, nil)
+	case Snappy:
+		compressed = snappy.Encode(nil, bs)
+	default:
+		return nil, fmt.Errorf("unknown compression codec: %d", s.codec)
+	}
+
+	out := make([]byte, 2+base64.RawStdEncoding.EncodedLen(len(compressed)))
+	if len(out) >= len(bs) {
+		return bs, nil
+	}
+	out[0] = compressedHeader
+	out[1] = byte(s.codec)
+	base64.RawStdEncoding.Encode(out[2:], compressed)
+	return out, nil
+}
+
+func (s *CompressedSerializer) Deserialize(bs []byte, tar any) error {
+	if len(bs) < 2 || bs[0] != compressedHeader {
+		return s.inner.Deserialize(bs, tar)
+	}
+
+	compressed := make([]byte, base64.RawStdEncoding.DecodedLen(len(bs)-2))
+	n, err := base64.RawStdEncoding.Decode(compressed, bs[2:])
+	if err != nil {
+		return fmt.Errorf("failed to decode the compressed value: %v", err)
+	}
+	compressed = compressed[:n]
+
+	var raw []byte
+	switch Codec(bs[1]) {
+	case Zstd:
+		raw, err = s.decoder.DecodeAll(compressed, nil)
+	case Snappy:
+		raw, err = snappy.Decode(nil, compressed)
+	default:
+		return fmt.Errorf("unknown compression codec: %d", bs[1])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decompress the value: %v", err)
+	}
+	return s.inner.Deserialize(raw, tar)
+}
@@ -0,0 +1,105 @@
+package serializer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type largeStruct struct {
+	Number int
+	Text   string
+}
+
+func newLargeStruct() largeStruct {
+	return largeStruct{Number: 42, Text: strings.Repeat("compressible ", 100)}
+}
+
+func TestCompressedSerializer_RoundTrip(t *testing.T) {
+	for _, codec := range []Codec{Zstd, Snappy} {
+		s := NewCompressedSerializer(NewJSON2Serializer(), codec, 0)
+
+		data := newLargeStruct()
+		bs, err := s.Serialize(data)
+		if err != nil {
+			t.Fatalf("Serialize() error = %v", err)
+		}
+		if bs[0] != compressedHeader || Codec(bs[1]) != codec {
+			t.Errorf("Serialize() should compress a large value with codec %c", codec)
+		}
+		raw, _ := NewJSON2Serializer().Serialize(data)
+		if len(bs) >= len(raw) {
+			t.Errorf("compressed size %d should be smaller than %d", len(bs), len(raw))
+		}
+
+		var got largeStruct
+		if err := s.Deserialize(bs, &got); err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		if !reflect.DeepEqual(data, got) {
+			t.Errorf("Deserialize() = %v, want %v", got, data)
+		}
+	}
+}
+
+func TestCompressedSerializer_SmallValue(t *testing.T) {
+	s := NewCompressedSerializer(NewJSON2Serializer(), Zstd, 0)
+
+	data := TestStruct{Number: 123, String: "abc"}
+	bs, err := s.Serialize(data)
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	raw, _ := NewJSON2Serializer().Serialize(data)
+	if string(bs) != string(raw) {
+		t.Errorf("Serialize() = %q, a small value should be stored as %q", bs, raw)
+	}
+}
+
+func TestCompressedSerializer_UncompressedRecords(t *testing.T) {
+	for _, inner := range []Serializer{NewJSON2Serializer(), NewGobSerializer()} {
+		data := newLargeStruct()
+		raw, err := inner.Serialize(data)
+		if err != nil {
+			t.Fatalf("Serialize() error = %v", err)
+		}
+
+		var got largeStruct
+		s := NewCompressedSerializer(inner, Snappy, 0)
+		if err := s.Deserialize(raw, &got); err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		if !reflect.DeepEqual(data, got) {
+			t.Errorf("Deserialize() = %v, want %v", got, data)
+		}
+	}
+}
+
+func TestCompressedSerializer_ChangeCodec(t *testing.T) {
+	data := newLargeStruct()
+	bs, _ := NewCompressedSerializer(NewJSON2Serializer(), Zstd, 0).Serialize(data)
+
+	// the records written with another codec are still decoded
+	for _, codec := range []Codec{Snappy, NoCompression} {
+		var got largeStruct
+		s := NewCompressedSerializer(NewJSON2Serializer(), codec, 0)
+		if err := s.Deserialize(bs, &got); err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		if !reflect.DeepEqual(data, got) {
+			t.Errorf("Deserialize() = %v, want %v", got, data)
+		}
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	for name, want := range map[string]Codec{"": NoCompression, "none": NoCompression, "zstd": Zstd, "snappy": Snappy} {
+		codec, err := ParseCodec(name)
+		if err != nil || codec != want {
+			t.Errorf("ParseCodec(%q) = %v, %v, want %v", name, codec, err, want)
+		}
+	}
+	if _, err := ParseCodec("lz4"); err == nil {
+		t.Error("ParseCodec() should return an error for an unknown codec")
+	}
+}
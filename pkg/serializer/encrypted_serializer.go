@@ -0,0 +1,182 @@
+package serializer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sync"
+)
+
+// encryptedHeader starts every encrypted value. Like compressedHeader,
+// it never starts the output of the other serializers.
+const encryptedHeader byte = 0x02
+
+// EncryptedSerializer encrypts the output of another serializer with
+// AES-GCM, under the data key of one datastore from a KeyProvider.
+//
+// An encrypted value is stored as the header byte and the base64 encoding
+// of the key id, the nonce and the ciphertext. The datastore name is
+// authenticated with the value, so a value copied to another datastore
+// fails to decrypt. Values stored before encryption was enabled are
+// passed to the inner serializer as they are.
+//
+// Only the record values are encrypted; see Unencrypted for the metadata.
+// The executors have no keys, so in the network mode, the merges and the
+// compare-and-sets of the records not read by the transaction are rejected.
+// To combine it with CompressedSerializer, wrap the compressing serializer,
+// since the ciphertext does not compress.
+type EncryptedSerializer struct {
+	inner     Serializer
+	keys      KeyProvider
+	datastore string
+
+	mu    sync.Mutex
+	aeads map[string]cipher.AEAD
+}
+
+// NewEncryptedSerializer wraps inner with the data keys of datastore.
+func NewEncryptedSerializer(inner Serializer, keys KeyProvider, datastore string) *EncryptedSerializer {
+	return &EncryptedSerializer{
+		inner:     inner,
+		keys:      keys,
+		datastore: datastore,
+		aeads:     make(map[string]cipher.AEAD),
+	}
+}
+
+// Unencrypted returns the serializer wrapped by the encryption layers of se,
+// or se itself if it does not encrypt.
+//
+// The previous versions embedded in the Prev field of a record are
+// serialized with it: their values are already encrypted, and their
+// metadata must stay readable by the executors, which have no keys.
+func Unencrypted(se Serializer) Serializer {
+	for {
+		encrypted, ok := se.(*EncryptedSerializer)
+		if !ok {
+			return se
+		}
+		se = encrypted.inner
+	}
+}
+
+// Encrypts reports whether se encrypts the values.
+func Encrypts(se Serializer) bool {
+	_, ok := se.(*EncryptedSerializer)
+	return ok
+}
+
+// Decrypt returns the output of Unencrypted(se) that bs was encrypted from.
+// Since the encryption is randomized, the values are compared in this form.
+func Decrypt(se Serializer, bs []byte) ([]byte, error) {
+	for {
+		encrypted, ok := se.(*EncryptedSerializer)
+		if !ok {
+			return bs, nil
+		}
+		var err error
+		bs, err = encrypted.decrypt(bs)
+		if err != nil {
+			return nil, err
+		}
+		se = encrypted.inner
+	}
+}
+
+func (s *EncryptedSerializer) aead(key DataKey) (cipher.AEAD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if aead, ok := s.aeads[key.ID]; ok {
+		return aead, nil
+	}
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s.aeads[key.ID] = aead
+	return aead, nil
+}
+
+func (s *EncryptedSerializer) Serialize(data any) ([]byte, error) {
+	bs, err := s.inner.Serialize(data)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.keys.CurrentKey(s.datastore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the data key of %s: %v", s.datastore, err)
+	}
+	if len(key.ID) > 255 {
+		return nil, fmt.Errorf("the key id %q is longer than 255 bytes", key.ID)
+	}
+	aead, err := s.aead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// key id length | key id | nonce | ciphertext
+	payload := make([]byte, 1+len(key.ID)+aead.NonceSize(), 1+len(key.ID)+aead.NonceSize()+len(bs)+aead.Overhead())
+	payload[0] = byte(len(key.ID))
+	copy(payload[1:], key.ID)
+	nonce := payload[1+len(key.ID):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	payload = aead.Seal(payload, nonce, bs, []byte(s.datastore))
+
+	out := make([]byte, 1+base64.RawStdEncoding.EncodedLen(len(payload)))
+	out[0] = encryptedHeader
+	base64.RawStdEncoding.Encode(out[1:], payload)
+	return out, nil
+}
+
+func (s *EncryptedSerializer) Deserialize(bs []byte, tar any) error {
+	raw, err := s.decrypt(bs)
+	if err != nil {
+		return err
+	}
+	return s.inner.Deserialize(raw, tar)
+}
+
+// decrypt returns the plaintext of an encrypted value,
+// or the value itself if it is not encrypted.
+func (s *EncryptedSerializer) decrypt(bs []byte) ([]byte, error) {
+	if len(bs) == 0 || bs[0] != encryptedHeader {
+		return bs, nil
+	}
+
+	payload := make([]byte, base64.RawStdEncoding.DecodedLen(len(bs)-1))
+	n, err := base64.RawStdEncoding.Decode(payload, bs[1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the encrypted value: %v", err)
+	}
+	payload = payload[:n]
+	if len(payload) < 1 || len(payload) < 1+int(payload[0]) {
+		return nil, fmt.Errorf("the encrypted value is truncated")
+	}
+	id := string(payload[1 : 1+payload[0]])
+	payload = payload[1+payload[0]:]
+
+	key, err := s.keys.Key(s.datastore, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the data key %q of %s: %v", id, s.datastore, err)
+	}
+	aead, err := s.aead(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < aead.NonceSize() {
+		return nil, fmt.Errorf("the encrypted value is truncated")
+	}
+	raw, err := aead.Open(nil, payload[:aead.NonceSize()], payload[aead.NonceSize():], []byte(s.datastore))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the value: %v", err)
+	}
+	return raw, nil
+}
@@ -0,0 +1,179 @@
+package serializer
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newTestKeyProvider(t *testing.T) *FileKeyProvider {
+	p, err := NewFileKeyProvider(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider() error = %v", err)
+	}
+	return p
+}
+
+func TestEncryptedSerializer_RoundTrip(t *testing.T) {
+	s := NewEncryptedSerializer(NewJSON2Serializer(), newTestKeyProvider(t), "redis1")
+
+	data := TestStruct{Number: 123, String: "secret"}
+	bs, err := s.Serialize(data)
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	if bs[0] != encryptedHeader {
+		t.Errorf("Serialize() = %q, should be encrypted", bs)
+	}
+	plain := mustSerialize(t, NewJSON2Serializer(), data)
+	if reflect.DeepEqual(bs, plain) {
+		t.Error("Serialize() should not store the plaintext")
+	}
+	if got, err := Decrypt(s, bs); err != nil || !reflect.DeepEqual(got, plain) {
+		t.Errorf("Decrypt() = %q, %v, want %q", got, err, plain)
+	}
+
+	var got TestStruct
+	if err := s.Deserialize(bs, &got); err != nil {
+		t.Fatalf("Deserialize() error = %v", err)
+	}
+	if !reflect.DeepEqual(data, got) {
+		t.Errorf("Deserialize() = %v, want %v", got, data)
+	}
+}
+
+func TestEncryptedSerializer_Rotation(t *testing.T) {
+	keys := newTestKeyProvider(t)
+	s := NewEncryptedSerializer(NewJSON2Serializer(), keys, "redis1")
+
+	old := TestStruct{Number: 1, String: "old"}
+	oldBs, _ := s.Serialize(old)
+	if _, err := keys.Rotate(); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	cur := TestStruct{Number: 2, String: "new"}
+	curBs, _ := s.Serialize(cur)
+
+	// another process picks up the rotated keys from the file
+	reloaded, err := NewFileKeyProvider(keys.path)
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider() error = %v", err)
+	}
+	s = NewEncryptedSerializer(NewJSON2Serializer(), reloaded, "redis1")
+	for want, bs := range map[TestStruct][]byte{old: oldBs, cur: curBs} {
+		var got TestStruct
+		if err := s.Deserialize(bs, &got); err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("Deserialize() = %v, want %v", got, want)
+		}
+	}
+}
+
+// TestFileKeyProvider_ConcurrentRotate tests that the keys rotated by two
+// providers sharing the key file are all kept.
+func TestFileKeyProvider_ConcurrentRotate(t *testing.T) {
+	first := newTestKeyProvider(t)
+	second, err := NewFileKeyProvider(first.path)
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	ids := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		p := first
+		if i%2 == 1 {
+			p = second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := p.Rotate()
+			if err != nil {
+				t.Errorf("Rotate() error = %v", err)
+			}
+			ids <- id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	reloaded, err := NewFileKeyProvider(first.path)
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider() error = %v", err)
+	}
+	if got := len(reloaded.file.Keys); got != 11 {
+		t.Errorf("len(Keys) = %d, want 11", got)
+	}
+	for id := range ids {
+		if _, err := reloaded.Key("redis1", id); err != nil {
+			t.Errorf("Key(%q) error = %v", id, err)
+		}
+	}
+}
+
+func TestEncryptedSerializer_PerDatastoreKeys(t *testing.T) {
+	keys := newTestKeyProvider(t)
+	bs, _ := NewEncryptedSerializer(NewJSON2Serializer(), keys, "redis1").Serialize("secret")
+
+	var got string
+	s := NewEncryptedSerializer(NewJSON2Serializer(), keys, "mongo1")
+	if err := s.Deserialize(bs, &got); err == nil {
+		t.Error("Deserialize() should not decrypt a value of another datastore")
+	}
+}
+
+func TestEncryptedSerializer_PlaintextRecords(t *testing.T) {
+	for _, inner := range []Serializer{NewJSON2Serializer(), NewGobSerializer()} {
+		data := TestStruct{Number: 123, String: "abc"}
+		raw := mustSerialize(t, inner, data)
+
+		var got TestStruct
+		s := NewEncryptedSerializer(inner, newTestKeyProvider(t), "redis1")
+		if err := s.Deserialize(raw, &got); err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		if !reflect.DeepEqual(data, got) {
+			t.Errorf("Deserialize() = %v, want %v", got, data)
+		}
+	}
+}
+
+func TestEncryptedSerializer_Compressed(t *testing.T) {
+	compressed := NewCompressedSerializer(NewJSON2Serializer(), Zstd, 0)
+	s := NewEncryptedSerializer(compressed, newTestKeyProvider(t), "redis1")
+
+	data := newLargeStruct()
+	bs, err := s.Serialize(data)
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	if len(bs) >= len(mustSerialize(t, NewJSON2Serializer(), data)) {
+		t.Error("Serialize() should compress before encrypting")
+	}
+	var got largeStruct
+	if err := s.Deserialize(bs, &got); err != nil {
+		t.Fatalf("Deserialize() error = %v", err)
+	}
+	if !reflect.DeepEqual(data, got) {
+		t.Errorf("Deserialize() = %v, want %v", got, data)
+	}
+
+	if Unencrypted(s) != compressed {
+		t.Error("Unencrypted() should return the compressing serializer")
+	}
+	if Unencrypted(compressed) != compressed {
+		t.Error("Unencrypted() should return a serializer without encryption as it is")
+	}
+}
+
+func mustSerialize(t *testing.T, s Serializer, data any) []byte {
+	bs, err := s.Serialize(data)
+	if err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	return bs
+}
@@ -0,0 +1,223 @@
+package serializer
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// DataKeySize is the size in bytes of the data keys, which select AES-256.
+const DataKeySize = 32
+
+// DataKey is a key encrypting the values of one datastore.
+type DataKey struct {
+	// ID is stored with every value encrypted by the key,
+	// so the value can be decrypted after the key is rotated.
+	ID  string
+	Key []byte
+}
+
+// KeyProvider supplies the data keys of EncryptedSerializer.
+type KeyProvider interface {
+	// CurrentKey returns the key encrypting the new values of datastore.
+	CurrentKey(datastore string) (DataKey, error)
+	// Key returns the key id of datastore,
+	// which decrypts the values written before a rotation.
+	Key(datastore string, id string) (DataKey, error)
+}
+
+// keyFile is the content of the file of FileKeyProvider.
+type keyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string][]byte `json:"keys"`
+}
+
+// FileKeyProvider keeps the master keys in a JSON file and derives
+// a data key per datastore from each of them with HKDF, so the
+// values of one datastore cannot be decrypted with the key of another.
+//
+// Rotate adds a new master key and makes it current.
+// The older master keys are kept to decrypt the values written with them.
+type FileKeyProvider struct {
+	path string
+
+	mu       sync.Mutex
+	file     keyFile
+	dataKeys map[string]DataKey
+}
+
+var _ KeyProvider = (*FileKeyProvider)(nil)
+
+// NewFileKeyProvider loads the master keys from path.
+// If the file does not exist, it is created with a new master key.
+func NewFileKeyProvider(path string) (*FileKeyProvider, error) {
+	p := &FileKeyProvider{path: path}
+	err := p.Reload()
+	if os.IsNotExist(err) {
+		_, err = p.Rotate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload reads the master keys from the file again,
+// picking up the keys rotated by another process.
+func (p *FileKeyProvider) Reload() error {
+	file, err := readKeyFile(p.path)
+	if err != nil {
+		return err
+	}
+	if _, ok := file.Keys[file.Current]; !ok {
+		return fmt.Errorf("the current key %q is not in the key file %s", file.Current, p.path)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.file = file
+	p.dataKeys = make(map[string]DataKey)
+	return nil
+}
+
+// Rotate adds a new master key to the file and makes it current.
+// It returns the id of the new key.
+//
+// The file is read again under the key file lock before the key is added,
+// so the keys rotated concurrently by other processes are kept.
+func (p *FileKeyProvider) Rotate() (string, error) {
+	master := make([]byte, DataKeySize)
+	if _, err := rand.Read(master); err != nil {
+		return "", err
+	}
+
+	unlock, err := lockKeyFile(p.path)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	file, err := readKeyFile(p.path)
+	if os.IsNotExist(err) {
+		file, err = keyFile{}, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if file.Keys == nil {
+		file.Keys = make(map[string][]byte, 1)
+	}
+	n := len(file.Keys) + 1
+	for file.Keys[strconv.Itoa(n)] != nil {
+		n++
+	}
+	id := strconv.Itoa(n)
+	file.Keys[id] = master
+	file.Current = id
+
+	if err := writeKeyFile(p.path, file); err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.file = file
+	p.dataKeys = make(map[string]DataKey)
+	return id, nil
+}
+
+// staleLockAge is the age after which the lock file of a key file is
+// considered left behind by a crashed process and removed.
+const staleLockAge = 10 * time.Second
+
+// lockKeyFile takes the lock of the key file at path, which is held by
+// creating a lock file next to it. The returned function releases the lock.
+func lockKeyFile(path string) (func(), error) {
+	lockPath := path + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// readKeyFile reads and parses the key file at path.
+func readKeyFile(path string) (keyFile, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return keyFile{}, err
+	}
+	var file keyFile
+	if err := json.Unmarshal(bs, &file); err != nil {
+		return keyFile{}, fmt.Errorf("failed to parse the key file %s: %v", path, err)
+	}
+	return file, nil
+}
+
+// writeKeyFile replaces the key file at once, so a concurrent
+// Reload never sees a partially written file.
+func writeKeyFile(path string, file keyFile) error {
+	bs, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (p *FileKeyProvider) CurrentKey(datastore string) (DataKey, error) {
+	p.mu.Lock()
+	id := p.file.Current
+	p.mu.Unlock()
+	return p.Key(datastore, id)
+}
+
+func (p *FileKeyProvider) Key(datastore string, id string) (DataKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cacheKey := datastore + "\x00" + id
+	if key, ok := p.dataKeys[cacheKey]; ok {
+		return key, nil
+	}
+	master, ok := p.file.Keys[id]
+	if !ok {
+		return DataKey{}, fmt.Errorf("key %q not found in the key file %s", id, p.path)
+	}
+	key := DataKey{ID: id, Key: make([]byte, DataKeySize)}
+	_, err := io.ReadFull(hkdf.New(sha256.New, master, nil, []byte(datastore)), key.Key)
+	if err != nil {
+		return DataKey{}, err
+	}
+	p.dataKeys[cacheKey] = key
+	return key, nil
+}
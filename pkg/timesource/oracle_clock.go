@@ -0,0 +1,115 @@
+package timesource
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/logger"
+	"github.com/valyala/fasthttp"
+)
+
+var _ config.Clock = (*OracleClock)(nil)
+
+// OracleClock is a config.Clock following the wall clock of the time oracle.
+//
+// It periodically reads the clock of the oracle and applies the estimated
+// offset to the local clock, so the leases stamped and checked by different
+// nodes are on the same timeline up to half of the round-trip time to the oracle.
+type OracleClock struct {
+	Url      string
+	interval time.Duration
+	detector *SkewDetector
+	// offset is the estimated offset of the oracle clock in nanoseconds.
+	offset atomic.Int64
+	// last is the latest time returned by Now in Unix nanoseconds.
+	last atomic.Int64
+	done chan struct{}
+}
+
+// NewOracleClock creates a new OracleClock synchronizing with the oracle at url
+// every interval. It returns an error if the first synchronization fails.
+// The offsets above config.Config.ClockSkewThreshold are logged as warnings.
+func NewOracleClock(url string, interval time.Duration) (*OracleClock, error) {
+	c := &OracleClock{
+		Url:      url,
+		interval: interval,
+		detector: NewSkewDetector(config.Config.ClockSkewThreshold),
+		done:     make(chan struct{}),
+	}
+	if err := c.Sync(); err != nil {
+		return nil, err
+	}
+	go c.syncPeriodically()
+	return c, nil
+}
+
+// Now returns the current time of the oracle clock.
+// It never goes back, even if the estimated offset shrinks,
+// so the leases it stamps do not move backwards.
+func (c *OracleClock) Now() time.Time {
+	now := time.Now().Add(time.Duration(c.offset.Load())).UnixNano()
+	for {
+		last := c.last.Load()
+		if now <= last {
+			return time.Unix(0, last)
+		}
+		if c.last.CompareAndSwap(last, now) {
+			return time.Unix(0, now)
+		}
+	}
+}
+
+// Offset returns the estimated offset of the oracle clock to the local clock.
+func (c *OracleClock) Offset() time.Duration {
+	return time.Duration(c.offset.Load())
+}
+
+// Sync reads the clock of the oracle and updates the offset.
+func (c *OracleClock) Sync() error {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(c.Url + "/clock")
+
+	sent := time.Now()
+	err := fasthttp.DoTimeout(req, resp, c.interval)
+	received := time.Now()
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() == fasthttp.StatusNotFound {
+		return fmt.Errorf("the time oracle at %s does not serve /clock", c.Url)
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return errors.New("non-200 response code")
+	}
+	remote := time.UnixMicro(util.ToInt(string(resp.Body())))
+	offset := c.detector.Observe(c.Url, remote, sent, received)
+	c.offset.Store(int64(offset))
+	return nil
+}
+
+// Stop stops the periodic synchronization.
+func (c *OracleClock) Stop() {
+	close(c.done)
+}
+
+func (c *OracleClock) syncPeriodically() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.Sync(); err != nil {
+				logger.Log.Warnw("failed to read the oracle clock", "url", c.Url, "cause", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package timesource
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkewDetector(t *testing.T) {
+	d := NewSkewDetector(50 * time.Millisecond)
+	sent := time.Now()
+	received := sent.Add(20 * time.Millisecond)
+
+	offset := d.Observe("peer", sent.Add(10*time.Millisecond+time.Second), sent, received)
+	assert.Equal(t, time.Second, offset)
+	last, ok := d.Offset("peer")
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, last)
+
+	offset = d.Observe("peer", sent.Add(10*time.Millisecond-time.Second), sent, received)
+	assert.Equal(t, -time.Second, offset)
+
+	_, ok = d.Offset("other")
+	assert.False(t, ok)
+}
+
+func TestOracleClock(t *testing.T) {
+	skew := 5 * time.Second
+	var oracleSkew atomic.Int64
+	oracleSkew.Store(int64(skew))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/clock", r.URL.Path)
+		fmt.Fprintf(w, "%d", time.Now().Add(time.Duration(oracleSkew.Load())).UnixMicro())
+	}))
+	defer server.Close()
+
+	clock, err := NewOracleClock(server.URL, time.Second)
+	assert.NoError(t, err)
+	defer clock.Stop()
+	assert.InDelta(t, skew, clock.Offset(), float64(100*time.Millisecond))
+	assert.InDelta(t, time.Now().Add(skew).UnixMicro(), clock.Now().UnixMicro(),
+		float64(100*time.Millisecond/time.Microsecond))
+
+	// the clock does not go back when the offset shrinks
+	before := clock.Now()
+	oracleSkew.Store(0)
+	assert.NoError(t, clock.Sync())
+	assert.InDelta(t, 0, clock.Offset(), float64(100*time.Millisecond))
+	assert.False(t, clock.Now().Before(before))
+
+	_, err = NewOracleClock("http://127.0.0.1:1", time.Second)
+	assert.Error(t, err)
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	_, err = NewOracleClock(notFound.URL, time.Second)
+	assert.ErrorContains(t, err, "does not serve /clock")
+}
@@ -0,0 +1,64 @@
+package timesource
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kkkzoz/oreo/pkg/logger"
+)
+
+// skewWarningInterval is the minimum interval between two warnings about the same peer.
+const skewWarningInterval = 10 * time.Second
+
+// SkewDetector estimates the offset between the local clock and the clocks
+// of its peers, and logs a warning when it exceeds a threshold.
+type SkewDetector struct {
+	threshold time.Duration
+
+	mu       sync.Mutex
+	offsets  map[string]time.Duration
+	lastWarn map[string]time.Time
+}
+
+// NewSkewDetector creates a new SkewDetector warning about the offsets above threshold.
+// A zero threshold disables the warnings.
+func NewSkewDetector(threshold time.Duration) *SkewDetector {
+	return &SkewDetector{
+		threshold: threshold,
+		offsets:   make(map[string]time.Duration),
+		lastWarn:  make(map[string]time.Time),
+	}
+}
+
+// Observe records a reading of the clock of peer, taken during a request
+// sent at sent and answered at received by the local clock.
+// It returns the estimated offset of the peer clock, which is accurate
+// within half of the round-trip time.
+//
+// A warning is logged if the offset exceeds the threshold
+// even after accounting for the round-trip time.
+func (d *SkewDetector) Observe(peer string, remote time.Time, sent time.Time, received time.Time) time.Duration {
+	rtt := received.Sub(sent)
+	offset := remote.Sub(sent.Add(rtt / 2))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.offsets[peer] = offset
+	if d.threshold == 0 || offset.Abs()-rtt/2 <= d.threshold {
+		return offset
+	}
+	if time.Since(d.lastWarn[peer]) >= skewWarningInterval {
+		d.lastWarn[peer] = time.Now()
+		logger.Log.Warnw("clock skew exceeds the threshold",
+			"peer", peer, "offset", offset, "rtt", rtt, "threshold", d.threshold)
+	}
+	return offset
+}
+
+// Offset returns the last estimated offset of the clock of peer.
+func (d *SkewDetector) Offset(peer string) (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	offset, ok := d.offsets[peer]
+	return offset, ok
+}
@@ -0,0 +1,129 @@
+package txn
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// Codec converts the values of a Collection to the strings stored in
+// the datastore, for the values the datastore serializer cannot handle
+// or a representation shared with other applications.
+type Codec[T any] interface {
+	Encode(value T) (string, error)
+	Decode(data string) (T, error)
+}
+
+// JSONCodec stores the values as JSON strings.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(value T) (string, error) {
+	bs, err := json.Marshal(value)
+	return string(bs), err
+}
+
+func (JSONCodec[T]) Decode(data string) (T, error) {
+	var value T
+	err := json.Unmarshal([]byte(data), &value)
+	return value, err
+}
+
+// CollectionOptions are the optional settings of a Collection.
+type CollectionOptions[T any] struct {
+	// KeyPrefix is prepended to the ids to build the keys of the records.
+	KeyPrefix string
+	// Codec converts the values, which are otherwise
+	// serialized by the datastore serializer as they are.
+	Codec Codec[T]
+	// Validate checks a value before it is written.
+	Validate func(id string, value T) error
+}
+
+// Collection is a typed view of the records of type T in a datastore.
+//
+// It is bound to a datastore name instead of a transaction,
+// so it is created once and shared by all the transactions.
+type Collection[T any] struct {
+	dsName string
+	opts   CollectionOptions[T]
+}
+
+// NewCollection returns a collection of the records in the datastore dsName.
+// The options may be nil.
+func NewCollection[T any](dsName string, opts *CollectionOptions[T]) *Collection[T] {
+	c := &Collection[T]{dsName: dsName}
+	if opts != nil {
+		c.opts = *opts
+	}
+	return c
+}
+
+// DatastoreName returns the name of the datastore of the collection.
+func (c *Collection[T]) DatastoreName() string {
+	return c.dsName
+}
+
+// Key returns the key of the record of id.
+func (c *Collection[T]) Key(id string) string {
+	return c.opts.KeyPrefix + id
+}
+
+// Get reads the value of id in tx.
+func (c *Collection[T]) Get(tx *Transaction, id string) (T, error) {
+	var value T
+	if c.opts.Codec == nil {
+		err := tx.Read(c.dsName, c.Key(id), &value)
+		return value, err
+	}
+
+	var data string
+	if err := tx.Read(c.dsName, c.Key(id), &data); err != nil {
+		return value, err
+	}
+	value, err := c.opts.Codec.Decode(data)
+	if err != nil {
+		return value, errors.Errorf("failed to decode the value of %s: %v", c.Key(id), err)
+	}
+	return value, nil
+}
+
+// GetMany reads the values of ids in tx.
+// The ids without a record are left out of the result.
+func (c *Collection[T]) GetMany(tx *Transaction, ids []string) (map[string]T, error) {
+	values := make(map[string]T, len(ids))
+	for _, id := range ids {
+		value, err := c.Get(tx, id)
+		if err != nil {
+			if strings.Contains(err.Error(), KeyNotFound.Error()) {
+				continue
+			}
+			return nil, err
+		}
+		values[id] = value
+	}
+	return values, nil
+}
+
+// Put validates value and writes it as the value of id in tx.
+func (c *Collection[T]) Put(tx *Transaction, id string, value T) error {
+	if c.opts.Validate != nil {
+		if err := c.opts.Validate(id, value); err != nil {
+			return err
+		}
+	}
+	if c.opts.Codec == nil {
+		return tx.Write(c.dsName, c.Key(id), value)
+	}
+
+	data, err := c.opts.Codec.Encode(value)
+	if err != nil {
+		return errors.Errorf("failed to encode the value of %s: %v", c.Key(id), err)
+	}
+	return tx.Write(c.dsName, c.Key(id), data)
+}
+
+// Delete deletes the record of id in tx.
+func (c *Collection[T]) Delete(tx *Transaction, id string) error {
+	return tx.Delete(c.dsName, c.Key(id))
+}
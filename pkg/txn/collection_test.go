@@ -0,0 +1,62 @@
+package txn_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollection(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	people := txn.NewCollection("mem", &txn.CollectionOptions[testutil.Person]{
+		KeyPrefix: "person:",
+		Validate: func(id string, p testutil.Person) error {
+			if p.Name == "" {
+				return fmt.Errorf("person %s has no name", id)
+			}
+			return nil
+		},
+	})
+	raw := txn.NewCollection("mem", &txn.CollectionOptions[testutil.Person]{
+		KeyPrefix: "raw:",
+		Codec:     txn.JSONCodec[testutil.Person]{},
+	})
+
+	person := testutil.NewDefaultPerson()
+	txn1 := newTestTransaction(conns)
+	assert.NoError(t, txn1.Start())
+	assert.NoError(t, people.Put(txn1, "1", person))
+	assert.NoError(t, people.Put(txn1, "2", person))
+	assert.Error(t, people.Put(txn1, "3", testutil.Person{}))
+	assert.NoError(t, raw.Put(txn1, "1", person))
+	assert.NoError(t, txn1.Commit())
+
+	txn2 := newTestTransaction(conns)
+	assert.NoError(t, txn2.Start())
+	got, err := people.Get(txn2, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, person, got)
+	got, err = raw.Get(txn2, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, person, got)
+	var data string
+	assert.NoError(t, txn2.Read("mem", "raw:1", &data))
+	assert.Contains(t, data, person.Name)
+	assert.NoError(t, people.Delete(txn2, "2"))
+	assert.NoError(t, txn2.Commit())
+
+	txn3 := newTestTransaction(conns)
+	assert.NoError(t, txn3.Start())
+	many, err := people.GetMany(txn3, []string{"1", "2", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]testutil.Person{"1": person}, many)
+	_, err = people.Get(txn3, "2")
+	assert.Error(t, err)
+	assert.NoError(t, txn3.Commit())
+}
@@ -1,5 +1,7 @@
 package txn
 
+import "sync"
+
 type Connector interface {
 	// Connect establishes and verifies the connection to the datastore.
 	// This operation should be idempotent.
@@ -59,3 +61,67 @@ type Connector interface {
 	// and a `txn.KeyExists` error.
 	AtomicCreate(name string, value any) (string, error)
 }
+
+// UpdateOp is a conditional update in a batch,
+// with the same meaning as the arguments of ConditionalUpdate.
+type UpdateOp struct {
+	Key      string
+	Value    DataItem
+	DoCreate bool
+}
+
+// BatchResult is the outcome of an operation in a batch,
+// with the same meaning as the results of ConditionalUpdate and ConditionalCommit.
+type BatchResult struct {
+	Version string
+	Err     error
+}
+
+// BatchConnector is implemented by the connectors that can send
+// several conditional operations to the datastore in one round trip.
+//
+// The operations of a batch are independent: each of them succeeds or fails
+// on its own, as if it were issued alone, and the results are in the order
+// of the operations. The batch is not a transaction.
+type BatchConnector interface {
+	Connector
+
+	// ConditionalUpdateBatch performs the ConditionalUpdate of every op.
+	ConditionalUpdateBatch(ops []UpdateOp) []BatchResult
+
+	// ConditionalCommitBatch performs the ConditionalCommit of every info at tCommit.
+	ConditionalCommitBatch(infos []CommitInfo, tCommit int64) []BatchResult
+}
+
+// ConditionalUpdateEach performs the ConditionalUpdate of every op in parallel.
+// It is the fallback of the connectors that cannot batch some of the operations.
+func ConditionalUpdateEach(conn Connector, ops []UpdateOp) []BatchResult {
+	results := make([]BatchResult, len(ops))
+	var wg sync.WaitGroup
+	for i, op := range ops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ver, err := conn.ConditionalUpdate(op.Key, op.Value, op.DoCreate)
+			results[i] = BatchResult{Version: ver, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// ConditionalCommitEach performs the ConditionalCommit of every info in parallel.
+func ConditionalCommitEach(conn Connector, infos []CommitInfo, tCommit int64) []BatchResult {
+	results := make([]BatchResult, len(infos))
+	var wg sync.WaitGroup
+	for i, info := range infos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ver, err := conn.ConditionalCommit(info.Key, info.Version, tCommit)
+			results[i] = BatchResult{Version: ver, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
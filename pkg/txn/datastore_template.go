@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"github.com/go-errors/errors"
-	"github.com/kkkzoz/oreo/internal/util"
 	"github.com/kkkzoz/oreo/pkg/config"
 	"github.com/kkkzoz/oreo/pkg/logger"
 	"github.com/kkkzoz/oreo/pkg/serializer"
@@ -33,6 +32,51 @@ type PredicateInfo struct {
 	LeaseTime time.Time
 }
 
+// ReadVersion identifies the version of a record read by a transaction.
+//
+// A version is identified by the group key list of the transaction that
+// wrote it, which is kept when the record is rolled forward and restored
+// when a newer version is rolled back.
+type ReadVersion struct {
+	GroupKeyList string
+	// IsAbsent is true if the record was not found.
+	IsAbsent bool
+}
+
+// Matches reports whether item, the latest record in the datastore,
+// is still the version described by v. A nil item means the record is not found.
+func (v ReadVersion) Matches(item DataItem) bool {
+	if v.IsAbsent {
+		return item == nil || (item.TxnState() == config.COMMITTED &&
+			(item.IsDeleted() || isExpired(item, config.Config.LeaseClock.Now())))
+	}
+	return item != nil && item.GroupKeyList() == v.GroupKeyList
+}
+
+// ValidateReadSet checks that every record in readSet is still at the version
+// that has been read. A record that has been overwritten, or is being
+// overwritten by a prepared transaction, fails the validation.
+func ValidateReadSet(conn Connector, readSet map[string]ReadVersion) error {
+	var eg errgroup.Group
+	for k, v := range readSet {
+		key, ver := k, v
+		eg.Go(func() error {
+			item, err := conn.GetItem(key)
+			if err != nil {
+				if !errors.Is(err, KeyNotFound) {
+					return err
+				}
+				item = nil
+			}
+			if !ver.Matches(item) {
+				return errors.Errorf("%v, key: %s", ReadConflict, key)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
 // Datastore represents a datastorer implementation using the underlying connector.
 type Datastore struct {
 	// Name is the name of the datastore.
@@ -44,6 +88,10 @@ type Datastore struct {
 	// conn is the connector interface used by the datastore.
 	conn Connector
 
+	// baseConn is the connector the datastore is created with,
+	// before it is routed to the namespace of the transaction.
+	baseConn Connector
+
 	// readCache is the cache for read operations in Datastore.
 	// readCache util.ConcurrentMap[string, DataItem]
 	readCache map[string]DataItem
@@ -60,6 +108,20 @@ type Datastore struct {
 	// validationSet util.ConcurrentMap[string, PredicateInfo]
 	validationSet map[string]PredicateInfo
 
+	// absentSet is the set of keys that are read but not found.
+	absentSet map[string]bool
+
+	// writeConds is the set of conditions attached to the writes,
+	// which are checked against the records in the database during Prepare.
+	writeConds map[string]WriteCondition
+
+	// merges is the set of pending deltas of the records written by Merge.
+	merges map[string][]MergeDelta
+
+	// prepared is the set of keys that have been prepared in the data store,
+	// whose leases are renewed by the heartbeat of the transaction.
+	prepared map[string]bool
+
 	// se is the serializer used for serializing and deserializing data in Datastore.
 	se serializer.Serializer
 
@@ -76,11 +138,16 @@ func NewDatastore(name string, conn Connector, factory DataItemFactory) *Datasto
 	return &Datastore{
 		Name:       name,
 		conn:       conn,
+		baseConn:   conn,
 		readCache:  make(map[string]DataItem),
 		writeCache: make(map[string]DataItem),
 		// writtenSet:    util.NewConcurrentMap[bool](),
 		invisibleSet:  make(map[string]bool),
 		validationSet: make(map[string]PredicateInfo),
+		absentSet:     make(map[string]bool),
+		writeConds:    make(map[string]WriteCondition),
+		merges:        make(map[string][]MergeDelta),
+		prepared:      make(map[string]bool),
 		se:            config.Config.Serializer,
 		itemFactory:   factory,
 	}
@@ -104,6 +171,10 @@ func (r *Datastore) Start() error {
 
 // Read reads a record from the Datastore.
 func (r *Datastore) Read(key string, value any) error {
+	// if the record has pending deltas
+	if deltas, ok := r.merges[key]; ok {
+		return r.readMerged(key, deltas, value)
+	}
 	// if the record is in the writeCache
 	if item, ok := r.writeCache[key]; ok {
 		// if the record is marked as deleted
@@ -114,13 +185,24 @@ func (r *Datastore) Read(key string, value any) error {
 	}
 	// if the record is in the readCache
 	if item, ok := r.readCache[key]; ok {
+		if r.isExpired(item) {
+			return errors.New(KeyNotFound)
+		}
 		return r.getValue(item, value)
 	}
+	var err error
 	if r.Txn.isRemote {
-		return r.readFromRemote(key, value)
+		err = r.readFromRemote(key, value)
 	} else {
-		return r.readFromConn(key, value)
+		err = r.readFromConn(key, value)
+	}
+	// remember the keys that are not found for the read set validation
+	if err != nil && strings.Contains(err.Error(), KeyNotFound.Error()) {
+		r.mu.Lock()
+		r.absentSet[key] = true
+		r.mu.Unlock()
 	}
+	return err
 }
 
 func (r *Datastore) readFromRemote(key string, value any) error {
@@ -146,9 +228,39 @@ func (r *Datastore) readFromRemote(key string, value any) error {
 		return errors.New(KeyNotFound)
 	}
 	r.readCache[item.Key()] = item
+	if r.isExpired(item) {
+		return errors.New(KeyNotFound)
+	}
+	if value == nil {
+		return nil
+	}
 	return r.getValue(item, value)
 }
 
+// readMerged reads a record with pending deltas
+// by applying the deltas on top of the version visible to the transaction.
+func (r *Datastore) readMerged(key string, deltas []MergeDelta, value any) error {
+	if _, ok := r.readCache[key]; !ok {
+		var err error
+		if r.Txn.isRemote {
+			err = r.readFromRemote(key, nil)
+		} else {
+			err = r.readFromConn(key, nil)
+		}
+		if err != nil && !strings.Contains(err.Error(), KeyNotFound.Error()) {
+			return err
+		}
+	}
+	r.mu.Lock()
+	base := r.readCache[key]
+	r.mu.Unlock()
+	merged, err := ApplyMerges(r.se, base, deltas)
+	if err != nil {
+		return err
+	}
+	return r.se.Deserialize([]byte(merged), value)
+}
+
 func (r *Datastore) readFromConn(key string, value any) error {
 	item, err := r.conn.GetItem(key)
 	if err != nil {
@@ -190,6 +302,14 @@ func (r *Datastore) readFromConn(key string, value any) error {
 			}
 			return errors.New(KeyNotFound)
 		}
+		// the record has expired as of the snapshot, which is
+		// handled as a delete, see the comment above
+		if r.isExpired(curItem) {
+			r.mu.Lock()
+			r.readCache[curItem.Key()] = curItem
+			r.mu.Unlock()
+			return errors.New("key not found because item has expired in " + r.Name)
+		}
 		if value != nil {
 			err := r.getValue(curItem, value)
 			if err != nil {
@@ -219,7 +339,7 @@ func (r *Datastore) dirtyReadChecker(item DataItem) (DataItem, error) {
 func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 	// function to perform the rollback operation
 	rollbackFunc := func() (DataItem, error) {
-		item, err := r.rollback(item)
+		item, err := r.RollbackItem(item)
 		if err != nil {
 			return nil, err
 		}
@@ -232,7 +352,7 @@ func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 
 	// function to perform the rollforward operation
 	rollforwardFunc := func() (DataItem, error) {
-		item, err := r.rollForward(item)
+		item, err := r.RollForwardItem(item)
 		if err != nil {
 			return nil, err
 		}
@@ -269,7 +389,7 @@ func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 		// and if t_lease has expired
 		// that is, item's TLease < current time
 		// we should roll back the record
-		if item.TLease().Before(time.Now()) {
+		if item.TLease().Before(config.Config.LeaseClock.Now()) {
 			successNum := r.Txn.CreateGroupKeyFromItem(item, config.ABORTED)
 			if successNum == 0 {
 				return nil, fmt.Errorf(
@@ -311,7 +431,7 @@ func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 			if item.Prev() == "" {
 				return nil, errors.New(KeyNotFound)
 			}
-			return r.getPrevItem(item)
+			return r.PrevItem(item)
 		}
 
 		if config.Config.ReadStrategy == config.Pessimistic {
@@ -333,7 +453,7 @@ func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 				if item.Prev() == "" {
 					return nil, errors.New("key not found in AssumeAbort")
 				}
-				return r.getPrevItem(item)
+				return r.PrevItem(item)
 			}
 		}
 	}
@@ -342,16 +462,23 @@ func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 
 // treatAsCommitted treats a DataItem as committed, finds a corresponding version
 // according to its timestamp, and performs the given logic function on it.
+// It looks as deep as the retention policy keeps the versions of the item.
 func (r *Datastore) treatAsCommitted(item DataItem, logicFunc func(DataItem, bool) error) error {
+	maxLen := RetentionPolicyOr(config.Config.MaxRecordLength).MaxVersions(item.Key())
 	curItem := item
-	for i := 1; i <= config.Config.MaxRecordLength; i++ {
+	for i := 1; maxLen == 0 || i <= maxLen; i++ {
 
 		if curItem.TValid() < r.Txn.TxnStartTime {
 			// find the corresponding version,
 			// do some business logic.
 			return logicFunc(curItem, true)
 		}
-		if i == config.Config.MaxRecordLength {
+		// the version may have committed before the transaction started
+		// on a node whose clock is ahead of ours
+		if r.Txn.inUncertaintyWindow(curItem.TValid()) {
+			return errors.New(UncertainRead)
+		}
+		if i == maxLen {
 			break
 		}
 		// if prev is empty
@@ -360,7 +487,7 @@ func (r *Datastore) treatAsCommitted(item DataItem, logicFunc func(DataItem, boo
 		}
 
 		// get the previous record
-		preItem, err := r.getPrevItem(curItem)
+		preItem, err := r.PrevItem(curItem)
 		if err != nil {
 			return err
 		}
@@ -377,10 +504,16 @@ func (r *Datastore) Write(key string, value any) error {
 		return err
 	}
 	str := string(bs)
+	// the value overwrites the pending deltas
+	delete(r.merges, key)
 	// if the record is in the writeCache
 	if item, ok := r.writeCache[key]; ok {
 		item.SetValue(str)
 		item.SetIsDeleted(false)
+		// the expiry of an earlier WriteWithTTL is overwritten as well
+		if it, ok := item.(ExpiringItem); ok {
+			it.SetTExpire(time.Time{})
+		}
 		r.writeCache[key] = item
 		return nil
 	}
@@ -394,6 +527,130 @@ func (r *Datastore) Write(key string, value any) error {
 	return r.writeToCache(cacheItem)
 }
 
+// Insert writes a record to the cache.
+// The record must not exist when the transaction commits.
+func (r *Datastore) Insert(key string, value any) error {
+	return r.conditionalWrite(key, value, WriteCondition{Kind: ConditionInsert})
+}
+
+// Update writes a record to the cache.
+// The record must exist when the transaction commits.
+func (r *Datastore) Update(key string, value any) error {
+	return r.conditionalWrite(key, value, WriteCondition{Kind: ConditionUpdate})
+}
+
+// CompareAndSet writes a record to the cache.
+// The record must hold the expected value when the transaction commits.
+func (r *Datastore) CompareAndSet(key string, expected any, value any) error {
+	if r.Txn.isRemote && serializer.Encrypts(r.se) && !r.isResolvedLocally(key) {
+		return errors.Errorf("compare-and-set of an encrypted value needs a read of key %s first", key)
+	}
+	// the encryption is randomized, so the plaintexts are compared
+	bs, err := serializer.Unencrypted(r.se).Serialize(expected)
+	if err != nil {
+		return err
+	}
+	return r.conditionalWrite(key, value, WriteCondition{
+		Kind:     ConditionCompareAndSet,
+		Expected: string(bs),
+	})
+}
+
+// conditionalWrite writes a record to the cache with the given condition.
+// If the record has been written by the transaction, the condition is checked
+// against the cached record right away, since the record in the database
+// is already covered by the earlier write.
+func (r *Datastore) conditionalWrite(key string, value any, cond WriteCondition) error {
+	if item, ok := r.writeCache[key]; ok {
+		if err := r.checkCondition(cond, key, item); err != nil {
+			return err
+		}
+		return r.Write(key, value)
+	}
+	if err := r.Write(key, value); err != nil {
+		return err
+	}
+	r.writeConds[key] = cond
+	return nil
+}
+
+// Merge records a delta of the given merge operator for a record.
+// If the record has been overwritten by the transaction, the delta is merged
+// into the cached value right away.
+func (r *Datastore) Merge(key string, operator string, delta any) error {
+	op, ok := GetMergeOperator(operator)
+	if !ok {
+		return errors.Errorf("unknown merge operator: %s", operator)
+	}
+	if r.Txn.isRemote && serializer.Encrypts(r.se) && r.writeCache[key] == nil {
+		return errors.Errorf("the executors cannot merge the encrypted value of key %s", key)
+	}
+	bs, err := r.se.Serialize(delta)
+	if err != nil {
+		return err
+	}
+
+	if deltas, ok := r.merges[key]; ok {
+		r.merges[key] = append(deltas, MergeDelta{Operator: operator, Delta: string(bs)})
+		return nil
+	}
+	if item, ok := r.writeCache[key]; ok {
+		var existing []byte
+		if !item.IsDeleted() {
+			existing = []byte(item.Value())
+		}
+		value, err := op.Merge(r.se, existing, bs)
+		if err != nil {
+			return err
+		}
+		item.SetValue(string(value))
+		item.SetIsDeleted(false)
+		return nil
+	}
+
+	// the version is resolved during Prepare
+	r.writeCache[key] = r.itemFactory.NewDataItem(ItemOptions{Key: key})
+	r.merges[key] = []MergeDelta{{Operator: operator, Delta: string(bs)}}
+	return nil
+}
+
+// checkWriteCondition checks the condition of the write to key, if any,
+// against dbItem, the record the write replaces.
+func (r *Datastore) checkWriteCondition(key string, dbItem DataItem) error {
+	if cond, ok := r.writeConds[key]; ok {
+		return r.checkCondition(cond, key, dbItem)
+	}
+	return nil
+}
+
+// checkCondition checks cond against item. If the values are encrypted,
+// the expected value is compared with the decrypted value of item.
+func (r *Datastore) checkCondition(cond WriteCondition, key string, item DataItem) error {
+	if cond.Kind == ConditionCompareAndSet && serializer.Encrypts(r.se) &&
+		isLive(item, config.Config.LeaseClock.Now()) {
+		plain, err := serializer.Decrypt(r.se, []byte(item.Value()))
+		if err != nil {
+			return err
+		}
+		item = r.itemFactory.NewDataItem(ItemOptions{
+			Key:       item.Key(),
+			Value:     string(plain),
+			IsDeleted: item.IsDeleted(),
+		})
+	}
+	return cond.Check(key, item)
+}
+
+// isResolvedLocally reports whether the transaction has read or written key,
+// so the condition of a write to it is checked without the executors.
+func (r *Datastore) isResolvedLocally(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, read := r.readCache[key]
+	_, written := r.writeCache[key]
+	return read || written
+}
+
 // writeToCache writes the given DataItem to the cache.
 // It will find the corresponding version of the item.
 //   - If the item already exists in the read cache, it follows the read-modified-commit pattern
@@ -416,6 +673,7 @@ func (r *Datastore) writeToCache(cacheItem DataItem) error {
 // Delete deletes a record from the Datastore.
 // It will return an error if the record is not found.
 func (r *Datastore) Delete(key string) error {
+	delete(r.merges, key)
 	// if the record is in the writeCache
 	if item, ok := r.writeCache[key]; ok {
 		if item.IsDeleted() {
@@ -450,6 +708,7 @@ func (r *Datastore) doConditionalUpdate(cacheItem DataItem, dbItem DataItem) err
 
 	r.mu.Lock()
 	r.writeCache[newItem.Key()] = newItem
+	r.prepared[newItem.Key()] = true
 	r.mu.Unlock()
 	return nil
 }
@@ -469,110 +728,220 @@ func (r *Datastore) conditionalUpdate(cacheItem DataItem) error {
 		)
 	}()
 
+	if deltas, ok := r.merges[cacheItem.Key()]; ok {
+		return r.mergeUpdate(cacheItem, deltas)
+	}
+
+	dbItem, err := r.replacedItem(cacheItem)
+	if err != nil {
+		return err
+	}
+	return r.replacedError(cacheItem.Key(), r.doConditionalUpdate(cacheItem, dbItem))
+}
+
+// replacedError maps err, the failure of the conditional update of key,
+// to the error of the condition of the write to key, if any.
+func (r *Datastore) replacedError(key string, err error) error {
+	if cond, ok := r.writeConds[key]; ok && err != nil {
+		return cond.Replaced(key, err)
+	}
+	return err
+}
+
+// replacedItem returns the record in the datastore replaced by cacheItem,
+// or nil if there is none, after checking the condition of the write.
+func (r *Datastore) replacedItem(cacheItem DataItem) (DataItem, error) {
 	// if the cacheItem follows read-modified-write pattern,
 	// it already has a valid version, we can skip the read step.
 	if cacheItem.Version() != "" {
+		r.mu.Lock()
 		dbItem := r.readCache[cacheItem.Key()]
-		return r.doConditionalUpdate(cacheItem, dbItem)
+		r.mu.Unlock()
+		if err := r.checkWriteCondition(cacheItem.Key(), dbItem); err != nil {
+			return nil, err
+		}
+		return dbItem, nil
 	}
 
 	// else we read from connection
 	err := r.readFromConn(cacheItem.Key(), nil)
 	if err != nil {
 		if !strings.Contains(err.Error(), "key not found") {
-			return err
+			return nil, err
 		}
 	}
 	r.mu.Lock()
 	dbItem := r.readCache[cacheItem.Key()]
-	r.mu.Unlock()
 	// if the record is dropped by the repeatable read rule
 	if res, ok := r.invisibleSet[cacheItem.Key()]; ok && res {
 		dbItem = nil
 	}
-	return r.doConditionalUpdate(cacheItem, dbItem)
+	r.mu.Unlock()
+	if err := r.checkWriteCondition(cacheItem.Key(), dbItem); err != nil {
+		return nil, err
+	}
+	return dbItem, nil
 }
 
-// truncate truncates the linked list of DataItems
-// if the length exceeds the maximum record length defined in the configuration.
-//
-// It takes a pointer to a DataItem as input and returns the truncated DataItem and an error, if any.
-// If the length of the linked list is greater than the maximum record length, it creates a stack of DataItems and pops the items from the stack until the length is reduced to the maximum record length.
-//
-// It then updates the Prev and LinkedLen fields of the DataItems in the stack accordingly.
-// Finally, it returns the last popped DataItem as the truncated DataItem.
-//
-// If the length of the linked list is less than or equal to the maximum record length, it returns the input DataItem as is.
-func (r *Datastore) truncate(item DataItem, targetLen int) (DataItem, error) {
-	if item.LinkedLen() <= targetLen {
-		return item, nil
+// prepareInBatch prepares the items with a single ConditionalUpdateBatch.
+// The records replaced by the items are read in parallel beforehand,
+// while the merged items are prepared one by one since they are retried
+// on conflicts.
+func (r *Datastore) prepareInBatch(conn BatchConnector, items []DataItem) error {
+	ops := make([]UpdateOp, len(items))
+	var eg errgroup.Group
+	for i, item := range items {
+		eg.Go(func() error {
+			if deltas, ok := r.merges[item.Key()]; ok {
+				return r.mergeUpdate(item, deltas)
+			}
+			dbItem, err := r.replacedItem(item)
+			if err != nil {
+				return err
+			}
+			newItem, err := r.updateMetadata(item, dbItem)
+			if err != nil {
+				return err
+			}
+			ops[i] = UpdateOp{
+				Key:      newItem.Key(),
+				Value:    newItem,
+				DoCreate: dbItem == nil || dbItem.Empty(),
+			}
+			return nil
+		})
 	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	// drop the slots of the merged items
+	ops = slices.DeleteFunc(ops, func(op UpdateOp) bool { return op.Value == nil })
+	if len(ops) == 0 {
+		return nil
+	}
+
+	var firstErr error
+	results := conn.ConditionalUpdateBatch(ops)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, res := range results {
+		if res.Err != nil {
+			if firstErr == nil {
+				firstErr = r.replacedError(ops[i].Key, res.Err)
+			}
+			continue
+		}
+		// the prepared records are rolled back by Abort
+		ops[i].Value.SetVersion(res.Version)
+		r.writeCache[ops[i].Key] = ops[i].Value
+		r.prepared[ops[i].Key] = true
+	}
+	return firstErr
+}
 
-	stack := util.NewStack[DataItem]()
-	stack.Push(item)
-	curItem := &item
+// mergeUpdate applies the deltas on top of the latest committed version
+// of the record and performs the conditional update.
+// It retries when the record is updated concurrently, since the deltas commute.
+func (r *Datastore) mergeUpdate(cacheItem DataItem, deltas []MergeDelta) error {
+	for i := 0; i < MERGERETRY; i++ {
+		dbItem, err := r.latestCommitted(cacheItem.Key())
+		if err != nil {
+			// the record is being prepared or resolved by another transaction
+			if !errors.Is(err, ReadFailed) && !errors.Is(err, VersionMismatch) {
+				return err
+			}
+			time.Sleep(RETRYINTERVAL)
+			continue
+		}
 
-	for i := 1; i <= targetLen-1; i++ {
-		preItem, err := r.getPrevItem(*curItem)
+		value, err := ApplyMerges(r.se, dbItem, deltas)
 		if err != nil {
-			return nil, errors.New("Unmarshal error: " + err.Error())
+			return err
+		}
+		cacheItem.SetValue(value)
+		cacheItem.SetIsDeleted(false)
+		cacheItem.SetPrev("")
+		cacheItem.SetVersion("")
+		err = r.doConditionalUpdate(cacheItem, dbItem)
+		if !errors.Is(err, VersionMismatch) {
+			return err
 		}
-		curItem = &preItem
-		stack.Push(*curItem)
+		time.Sleep(RETRYINTERVAL)
 	}
+	return errors.Errorf("%v, key: %s", MergeConflict, cacheItem.Key())
+}
 
-	tarItem, err := stack.Pop()
+// latestCommitted returns the latest committed version of a record,
+// or nil if the record is not found.
+// A record prepared by a finished transaction is rolled forward or back,
+// and ReadFailed is returned if the transaction is still ongoing.
+func (r *Datastore) latestCommitted(key string) (DataItem, error) {
+	item, err := r.conn.GetItem(key)
 	if err != nil {
-		return nil, errors.New("Pop error: " + err.Error())
+		if errors.Is(err, KeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if item.TxnState() != config.PREPARED {
+		return item, nil
 	}
-	tarItem.SetPrev("")
-	tarItem.SetLinkedLen(1)
 
-	for !stack.IsEmpty() {
-		item, err := stack.Pop()
-		if err != nil {
-			return nil, errors.New("Pop error: " + err.Error())
+	groupKeyList, err := r.Txn.GetGroupKeyFromItem(item)
+	if err == nil {
+		if CommittedForAll(groupKeyList) {
+			tCommit := int64(math.MinInt64)
+			for _, gk := range groupKeyList {
+				tCommit = max(tCommit, gk.TCommit)
+			}
+			item.SetTValid(tCommit)
+			return r.RollForwardItem(item)
 		}
-		bs, err := r.se.Serialize(tarItem)
-		if err != nil {
-			return nil, errors.New("Serialize error: " + err.Error())
+		return r.RollbackItem(item)
+	}
+	if item.TLease().Before(config.Config.LeaseClock.Now()) {
+		if r.Txn.CreateGroupKeyFromItem(item, config.ABORTED) == 0 {
+			return nil, errors.New(ReadFailed)
 		}
-		item.SetPrev(string(bs))
-		item.SetLinkedLen(tarItem.LinkedLen() + 1)
-		tarItem = item
+		return r.RollbackItem(item)
 	}
-	return tarItem, nil
+	return nil, errors.New(ReadFailed)
 }
 
 // updateMetadata updates the metadata of a DataItem by comparing it with the oldItem.
 func (r *Datastore) updateMetadata(newItem DataItem, oldItem DataItem) (DataItem, error) {
-	maxLen := config.Config.MaxRecordLength
-
 	if oldItem == nil {
 		newItem.SetLinkedLen(1)
 		newItem.SetTxnState(config.PREPARED)
 		newItem.SetTValid(r.Txn.TxnCommitTime)
-		// TODO: time.Now() is temporary
-		newItem.SetTLease(time.Now().Add(config.Config.LeaseTime))
+		newItem.SetTLease(config.Config.LeaseClock.Now().Add(r.Txn.getLeaseTime()))
 		return newItem, nil
 	}
-	// 提前将 oldItem 截断到 maxLen-1
-	truncatedOld, err := r.truncate(oldItem, maxLen-1)
+	// 提前按保留策略截断 oldItem
+	version := oldItem.Version()
+	truncatedOld, err := TruncateHistory(RetentionPolicyOr(config.Config.MaxRecordLength),
+		oldItem, r.Txn.TxnCommitTime, r.Txn.oldestSnapshot, r.PrevItem, serializer.Unencrypted(r.se))
 	if err != nil {
 		return nil, err
 	}
 
 	// 直接合并截断后的 oldItem
-	newItem.SetLinkedLen(truncatedOld.LinkedLen() + 1)
-	bs, err := r.se.Serialize(truncatedOld)
-	if err != nil {
-		return nil, err
+	if truncatedOld == nil {
+		newItem.SetLinkedLen(1)
+		newItem.SetPrev("")
+	} else {
+		newItem.SetLinkedLen(truncatedOld.LinkedLen() + 1)
+		bs, err := serializer.Unencrypted(r.se).Serialize(truncatedOld)
+		if err != nil {
+			return nil, err
+		}
+		newItem.SetPrev(string(bs))
 	}
-	newItem.SetPrev(string(bs))
-	newItem.SetVersion(truncatedOld.Version())
+	newItem.SetVersion(version)
 	newItem.SetTxnState(config.PREPARED)
 	newItem.SetTValid(r.Txn.TxnCommitTime)
-	newItem.SetTLease(time.Now().Add(config.Config.LeaseTime))
+	newItem.SetTLease(config.Config.LeaseClock.Now().Add(r.Txn.getLeaseTime()))
 	return newItem, nil
 }
 
@@ -589,14 +958,14 @@ func (r *Datastore) rollbackFromConn(key string) error {
 	// 	return errors.New("rollback failed due to wrong txnId")
 	// }
 
-	if item.TLease().Before(time.Now()) {
+	if item.TLease().Before(config.Config.LeaseClock.Now()) {
 		successNum := r.Txn.CreateGroupKeyFromItem(item, config.ABORTED)
 		if successNum == 0 {
 			return fmt.Errorf(
 				"failed to rollback the record because none of the group keys are created",
 			)
 		}
-		_, err = r.rollback(item)
+		_, err = r.RollbackItem(item)
 		return err
 	}
 	return nil
@@ -621,7 +990,7 @@ func (r *Datastore) validate() error {
 			// curState, err := r.Txn.tsrMaintainer.ReadTSR(txnId)
 			if err != nil {
 				if config.Config.ReadStrategy == config.AssumeAbort {
-					if pred.LeaseTime.Before(time.Now()) {
+					if pred.LeaseTime.Before(config.Config.LeaseClock.Now()) {
 						key := pred.ItemKey
 						err := r.rollbackFromConn(key)
 						if err != nil {
@@ -690,6 +1059,10 @@ func (r *Datastore) Prepare() (int64, error) {
 		return 0, nil
 	}
 
+	if conn, ok := r.conn.(BatchConnector); ok {
+		return 0, r.prepareInBatch(conn, items)
+	}
+
 	var eg errgroup.Group
 	// eg.SetLimit(config.Config.MaxOutstandingRequest)
 	for _, item := range items {
@@ -701,6 +1074,45 @@ func (r *Datastore) Prepare() (int64, error) {
 	return 0, eg.Wait()
 }
 
+// readSet returns the versions of the records that are read
+// but not written by the transaction.
+func (r *Datastore) readSet() map[string]ReadVersion {
+	readSet := make(map[string]ReadVersion)
+	for key := range r.absentSet {
+		if _, ok := r.writeCache[key]; !ok {
+			readSet[key] = ReadVersion{IsAbsent: true}
+		}
+	}
+	for key, item := range r.readCache {
+		if _, ok := r.writeCache[key]; !ok {
+			readSet[key] = ReadVersion{GroupKeyList: item.GroupKeyList()}
+		}
+	}
+	return readSet
+}
+
+// validateReadSet validates the read set in serializable mode.
+// It is called by the transaction once the writes of all the datastores
+// are prepared, so a concurrent transaction that reads the written records
+// sees them in PREPARED state, whichever datastore they are in.
+func (r *Datastore) validateReadSet() error {
+	if config.Config.IsolationLevel != config.Serializable {
+		return nil
+	}
+	readSet := r.readSet()
+	if len(readSet) == 0 {
+		return nil
+	}
+	if r.Txn.isRemote {
+		_, _, err := r.Txn.RemotePrepare(r.Name, nil, r.validationSet, readSet, nil, nil)
+		if err != nil {
+			return errors.Join(errors.New("Remote prepare failed"), err)
+		}
+		return nil
+	}
+	return ValidateReadSet(r.conn, readSet)
+}
+
 func (r *Datastore) prepareInNative(items []DataItem) (int64, error) {
 	var err error
 	for _, itemm := range items {
@@ -726,15 +1138,33 @@ func (r *Datastore) prepareInNative(items []DataItem) (int64, error) {
 }
 
 func (r *Datastore) prepareInRemote(items []DataItem) (int64, error) {
+	// the conditions of the writes without a version
+	// are checked by the executor after reading the records
+	var conds map[string]WriteCondition
+	// the deltas are applied by the executor as well
+	var merges map[string][]MergeDelta
 	// for those whose version is clear, update their metadata
 	for _, item := range items {
 		if item.Version() != "" {
 			dbItem := r.readCache[item.Key()]
+			if err := r.checkWriteCondition(item.Key(), dbItem); err != nil {
+				return 0, err
+			}
 			newItem, err := r.updateMetadata(item, dbItem)
 			if err != nil {
 				return 0, errors.Errorf("UpdateMetadata failed: %v", err)
 			}
 			r.writeCache[item.Key()] = newItem
+		} else if deltas, ok := r.merges[item.Key()]; ok {
+			if merges == nil {
+				merges = make(map[string][]MergeDelta)
+			}
+			merges[item.Key()] = deltas
+		} else if cond, ok := r.writeConds[item.Key()]; ok {
+			if conds == nil {
+				conds = make(map[string]WriteCondition)
+			}
+			conds[item.Key()] = cond
 		}
 	}
 
@@ -742,7 +1172,8 @@ func (r *Datastore) prepareInRemote(items []DataItem) (int64, error) {
 		config.Debug.AssumptionCount++
 	}
 
-	verMap, tCommit, err := r.Txn.RemotePrepare(r.Name, items, r.validationSet)
+	// the read set is validated separately, once all the datastores are prepared
+	verMap, tCommit, err := r.Txn.RemotePrepare(r.Name, items, r.validationSet, nil, conds, merges)
 	logger.Log.Debugw(
 		"Remote prepare Result",
 		"TxnId",
@@ -759,9 +1190,12 @@ func (r *Datastore) prepareInRemote(items []DataItem) (int64, error) {
 	if err != nil {
 		return 0, errors.Join(errors.New("Remote prepare failed"), err)
 	}
+	r.mu.Lock()
 	for k, v := range verMap {
 		r.writeCache[k].SetVersion(v)
+		r.prepared[k] = true
 	}
+	r.mu.Unlock()
 	return tCommit, nil
 }
 
@@ -780,6 +1214,23 @@ func (r *Datastore) Commit() error {
 	}
 
 	// update record's state to the COMMITTED state in the data store
+	if conn, ok := r.conn.(BatchConnector); ok {
+		ops := make([]UpdateOp, 0, len(r.writeCache))
+		for _, item := range r.writeCache {
+			item.SetTxnState(config.COMMITTED)
+			ops = append(ops, UpdateOp{Key: item.Key(), Value: item})
+		}
+		// a VersionMismatch indicates that the record has been rolled forward
+		// by another transaction, and the other errors are ignored as well
+		for i, res := range conn.ConditionalUpdateBatch(ops) {
+			if res.Err == nil {
+				r.expireNatively(ops[i].Value, res.Version)
+			}
+		}
+		logger.Log.Debugw("Datastore.Commit() finishes", "TxnId", r.Txn.TxnId)
+		return nil
+	}
+
 	var eg errgroup.Group
 	// eg.SetLimit(config.Config.MaxOutstandingRequest)
 	for _, item := range r.writeCache {
@@ -787,12 +1238,15 @@ func (r *Datastore) Commit() error {
 		eg.Go(func() error {
 			it.SetTxnState(config.COMMITTED)
 
-			_, err := r.conn.ConditionalUpdate(it.Key(), it, false)
+			ver, err := r.conn.ConditionalUpdate(it.Key(), it, false)
 			if errors.Is(err, VersionMismatch) {
 				// this indicates that the record has been rolled forward
 				// by another transaction.
 				return nil
 			}
+			if err == nil {
+				r.expireNatively(it, ver)
+			}
 			return err
 		})
 	}
@@ -801,10 +1255,21 @@ func (r *Datastore) Commit() error {
 	return nil
 }
 
+// expireNatively makes the committed item, at version, expire natively.
+// The records it fails to expire are left to CollectExpired.
+func (r *Datastore) expireNatively(item DataItem, version string) {
+	err := ExpireNatively(r.conn, item.Key(), version, expiryOf(item))
+	logger.CheckAndLogError("failed to expire the record natively", err)
+}
+
 func (r *Datastore) commitInRemote() error {
 	infoList := make([]CommitInfo, 0, len(r.writeCache))
 	for _, item := range r.writeCache {
-		infoList = append(infoList, CommitInfo{Key: item.Key(), Version: item.Version()})
+		infoList = append(infoList, CommitInfo{
+			Key:     item.Key(),
+			Version: item.Version(),
+			TExpire: expiryOf(item),
+		})
 	}
 
 	err := r.Txn.RemoteCommit(r.Name, infoList)
@@ -845,7 +1310,7 @@ func (r *Datastore) Abort(hasCommitted bool) error {
 		if item.GroupKeyList() == curGroupKeyList {
 			// we don't care whether the rollback is successful or not
 			// if rollback fails, it means there is another txn is rolling back for us
-			_, _ = r.rollback(item)
+			_, _ = r.RollbackItem(item)
 		}
 	}
 	r.clear()
@@ -863,10 +1328,10 @@ func (r *Datastore) OnePhaseCommit() error {
 	return nil
 }
 
-// rollback overwrites the record with the application data
+// RollbackItem overwrites the record with the application data
 // and metadata that found in field Prev.
 // if the `Prev` is empty, it simply deletes the record
-func (r *Datastore) rollback(item DataItem) (DataItem, error) {
+func (r *Datastore) RollbackItem(item DataItem) (DataItem, error) {
 	if item.Prev() == "" {
 		item.SetIsDeleted(true)
 		item.SetTxnState(config.COMMITTED)
@@ -878,7 +1343,7 @@ func (r *Datastore) rollback(item DataItem) (DataItem, error) {
 		return item, err
 	}
 
-	newItem, err := r.getPrevItem(item)
+	newItem, err := r.PrevItem(item)
 	if err != nil {
 		return nil, errors.Join(errors.New("rollback failed"), err)
 	}
@@ -895,8 +1360,8 @@ func (r *Datastore) rollback(item DataItem) (DataItem, error) {
 	return newItem, err
 }
 
-// rollForward makes the record metadata with COMMITTED state
-func (r *Datastore) rollForward(item DataItem) (DataItem, error) {
+// RollForwardItem makes the record metadata with COMMITTED state
+func (r *Datastore) RollForwardItem(item DataItem) (DataItem, error) {
 	item.SetTxnState(config.COMMITTED)
 	newVer, err := r.conn.ConditionalUpdate(item.Key(), item, false)
 	if err != nil {
@@ -906,12 +1371,12 @@ func (r *Datastore) rollForward(item DataItem) (DataItem, error) {
 	return item, err
 }
 
-// getPrevItem retrieves the previous item of the given DataItem.
+// PrevItem retrieves the previous item of the given DataItem.
 // It deserializes the "Prev" field of the item and returns the deserialized DataItem.
 // If there is an error during deserialization, it returns an empty DataItem and the error.
-func (r *Datastore) getPrevItem(item DataItem) (DataItem, error) {
+func (r *Datastore) PrevItem(item DataItem) (DataItem, error) {
 	preItem := r.itemFactory.NewDataItem(ItemOptions{})
-	err := r.se.Deserialize([]byte(item.Prev()), &preItem)
+	err := serializer.Unencrypted(r.se).Deserialize([]byte(item.Prev()), &preItem)
 	if err != nil {
 		return nil, err
 	}
@@ -981,7 +1446,17 @@ func (r *Datastore) SetSerializer(se serializer.Serializer) {
 // Copy returns a new instance of Datastore with the same name and connection.
 // It is used to create a copy of the Datastore object.
 func (r *Datastore) Copy() Datastorer {
-	return NewDatastore(r.Name, r.conn, r.itemFactory)
+	return NewDatastore(r.Name, r.baseConn, r.itemFactory)
+}
+
+// setNamespace routes the connector of the Datastore to namespace ns.
+func (r *Datastore) setNamespace(ns string) error {
+	conn, err := NamespacedConnector(r.baseConn, ns)
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	return nil
 }
 
 func (r *Datastore) GetConn() Connector {
@@ -998,4 +1473,8 @@ func (r *Datastore) clear() {
 	// r.writtenSet = util.NewConcurrentMap[bool]()
 	r.invisibleSet = make(map[string]bool)
 	r.validationSet = make(map[string]PredicateInfo)
+	r.absentSet = make(map[string]bool)
+	r.writeConds = make(map[string]WriteCondition)
+	r.merges = make(map[string][]MergeDelta)
+	r.prepared = make(map[string]bool)
 }
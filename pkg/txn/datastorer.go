@@ -17,6 +17,20 @@ type Datastorer interface {
 	// Delete marks a record as deleted.
 	Delete(key string) error
 
+	// Insert writes a record that must not exist when the transaction commits.
+	Insert(key string, value any) error
+
+	// Update writes a record that must exist when the transaction commits.
+	Update(key string, value any) error
+
+	// CompareAndSet writes a record that must hold the expected value
+	// when the transaction commits.
+	CompareAndSet(key string, expected any, value any) error
+
+	// Merge records a delta of the given merge operator for a record.
+	// The delta is applied on top of the latest committed record during Prepare.
+	Merge(key string, operator string, delta any) error
+
 	// Prepare executes the prepare phase of transaction commit.
 	// In Oreo, it will return TCommit as well
 
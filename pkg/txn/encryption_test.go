@@ -0,0 +1,72 @@
+package txn_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/serializer"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionEncryptsValues(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conn := mock.NewMockMemoryConnection()
+	keys, err := serializer.NewFileKeyProvider(filepath.Join(t.TempDir(), "keys.json"))
+	assert.NoError(t, err)
+	newTxn := func() *txn.Transaction {
+		ds := txn.NewDatastore("mem", conn, &bolt.BoltItemFactory{})
+		ds.SetSerializer(serializer.NewEncryptedSerializer(config.Config.Serializer, keys, "mem"))
+		tx := txn.NewTransaction()
+		tx.AddDatastore(ds)
+		return tx
+	}
+
+	txn1 := newTxn()
+	assert.NoError(t, txn1.Start())
+	assert.NoError(t, txn1.Write("mem", "key", "secret-v1"))
+	assert.NoError(t, txn1.Commit())
+
+	_, err = keys.Rotate()
+	assert.NoError(t, err)
+
+	txn2 := newTxn()
+	assert.NoError(t, txn2.Start())
+	assert.NoError(t, txn2.Write("mem", "key", "secret-v2"))
+	assert.NoError(t, txn2.Commit())
+
+	// the values are encrypted, the metadata of the previous version is not
+	item, err := conn.GetItem("key")
+	assert.NoError(t, err)
+	assert.NotContains(t, item.Value(), "secret")
+	var prev bolt.BoltItem
+	assert.NoError(t, config.Config.Serializer.Deserialize([]byte(item.Prev()), &prev))
+	assert.Equal(t, config.COMMITTED, prev.TxnState())
+	assert.NotContains(t, prev.Value(), "secret")
+
+	// both versions stay readable after the rotation
+	var value string
+	se := serializer.NewEncryptedSerializer(config.Config.Serializer, keys, "mem")
+	assert.NoError(t, se.Deserialize([]byte(prev.Value()), &value))
+	assert.Equal(t, "secret-v1", value)
+	txn3 := newTxn()
+	assert.NoError(t, txn3.Start())
+	assert.NoError(t, txn3.Read("mem", "key", &value))
+	assert.Equal(t, "secret-v2", value)
+	assert.NoError(t, txn3.Commit())
+
+	// the expected values are compared with the decrypted values
+	txn4 := newTxn()
+	assert.NoError(t, txn4.Start())
+	assert.NoError(t, txn4.CompareAndSet("mem", "key", "secret-v2", "secret-v3"))
+	assert.NoError(t, txn4.Commit())
+	txn5 := newTxn()
+	assert.NoError(t, txn5.Start())
+	assert.NoError(t, txn5.CompareAndSet("mem", "key", "secret-v2", "secret-v4"))
+	assert.True(t, txn.IsConditionFailed(txn5.Commit()))
+}
@@ -0,0 +1,212 @@
+package txn
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/serializer"
+)
+
+// IndexOperator is the name of the built-in merge operator that adds
+// primary keys to and removes them from an index entry.
+const IndexOperator = "index"
+
+// indexKeyPrefix starts the keys of the records of the indexes.
+const indexKeyPrefix = "__idx:"
+
+// Index is a secondary index of the records of a datastore.
+//
+// For each indexed value, the index keeps an entry holding the primary keys
+// of the records with that value. The entries are written by the same
+// transactions as the records, so they commit atomically with them.
+// They are updated with IndexOperator, so the transactions indexing
+// different records under the same value do not conflict.
+type Index struct {
+	// Name identifies the index in the keys of its entries.
+	Name string
+	// Datastore is the name of the datastore of the indexed records.
+	Datastore string
+	// Target is the name of the datastore holding the index entries.
+	// It may be the same as Datastore.
+	Target string
+	// Extract returns the indexed values of the record key written with value.
+	// The value is the one passed to Transaction.Write, not a deserialized copy.
+	Extract func(key string, value any) []string
+}
+
+// entryKey returns the key of the entry of value.
+func (idx *Index) entryKey(value string) string {
+	return indexKeyPrefix + idx.Name + ":" + value
+}
+
+// reverseKey returns the key of the record holding
+// the indexed values of the record key, so they are
+// removed from the entries when the record changes.
+func (idx *Index) reverseKey(key string) string {
+	return indexKeyPrefix + idx.Name + "#" + key
+}
+
+// indexDelta is the delta of IndexOperator.
+type indexDelta struct {
+	Add    []string
+	Remove []string
+}
+
+var (
+	indexesMu sync.RWMutex
+	// indexes maps the datastores to their indexes
+	indexes = map[string][]*Index{}
+)
+
+// RegisterIndex registers idx, replacing any index registered under the same name.
+// The records written before idx is registered are not indexed.
+func RegisterIndex(idx Index) error {
+	if idx.Name == "" || idx.Datastore == "" || idx.Target == "" || idx.Extract == nil {
+		return errors.Errorf("index %q needs a name, a datastore, a target and an extractor", idx.Name)
+	}
+	if strings.ContainsAny(idx.Name, ":#") {
+		return errors.Errorf("index name %q contains ':' or '#'", idx.Name)
+	}
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+	removeIndex(idx.Name)
+	indexes[idx.Datastore] = append(indexes[idx.Datastore], &idx)
+	return nil
+}
+
+// UnregisterIndex stops maintaining the index name.
+// Its entries are left in the target datastore.
+func UnregisterIndex(name string) {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+	removeIndex(name)
+}
+
+func removeIndex(name string) {
+	for ds, list := range indexes {
+		list = slices.DeleteFunc(list, func(idx *Index) bool { return idx.Name == name })
+		if len(list) == 0 {
+			delete(indexes, ds)
+		} else {
+			indexes[ds] = list
+		}
+	}
+}
+
+// getIndex returns the index registered under name.
+func getIndex(name string) (*Index, bool) {
+	indexesMu.RLock()
+	defer indexesMu.RUnlock()
+	for _, list := range indexes {
+		for _, idx := range list {
+			if idx.Name == name {
+				return idx, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// indexesOf returns the indexes of the records of dsName.
+func indexesOf(dsName string) []*Index {
+	indexesMu.RLock()
+	defer indexesMu.RUnlock()
+	return indexes[dsName]
+}
+
+// updateIndexes writes the index entries of the record key of dsName,
+// written with value, or deleted if deleted is true.
+func (t *Transaction) updateIndexes(dsName string, key string, value any, deleted bool) error {
+	for _, idx := range indexesOf(dsName) {
+		target, ok := t.dataStoreMap[idx.Target]
+		if !ok {
+			return errors.New("index datastore not found: " + idx.Target)
+		}
+
+		var old []string
+		err := target.Read(t.namespacedKey(idx.reverseKey(key)), &old)
+		found := err == nil
+		if err != nil && !strings.Contains(err.Error(), KeyNotFound.Error()) {
+			return err
+		}
+		var cur []string
+		if !deleted {
+			cur = slices.Clone(idx.Extract(key, value))
+			slices.Sort(cur)
+			cur = slices.Compact(cur)
+		}
+
+		for _, v := range old {
+			if _, kept := slices.BinarySearch(cur, v); !kept {
+				err := target.Merge(t.namespacedKey(idx.entryKey(v)), IndexOperator, indexDelta{Remove: []string{key}})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, v := range cur {
+			if !slices.Contains(old, v) {
+				err := target.Merge(t.namespacedKey(idx.entryKey(v)), IndexOperator, indexDelta{Add: []string{key}})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err = nil
+		if deleted && found {
+			err = target.Delete(t.namespacedKey(idx.reverseKey(key)))
+		} else if !deleted && (!found || !slices.Equal(old, cur)) {
+			err = target.Write(t.namespacedKey(idx.reverseKey(key)), cur)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LookupIndex returns the primary keys of the records whose indexed value
+// is value, as seen by the transaction, including its own writes.
+func (t *Transaction) LookupIndex(name string, value string) ([]string, error) {
+	idx, ok := getIndex(name)
+	if !ok {
+		return nil, errors.New("index not found: " + name)
+	}
+	var keys []string
+	err := t.Read(idx.Target, idx.entryKey(value), &keys)
+	if err != nil && !strings.Contains(err.Error(), KeyNotFound.Error()) {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// mergeIndex applies an indexDelta to the sorted primary keys of an entry.
+func mergeIndex(se serializer.Serializer, existing []byte, delta []byte) ([]byte, error) {
+	var keys []string
+	if len(existing) != 0 {
+		if err := se.Deserialize(existing, &keys); err != nil {
+			return nil, err
+		}
+	}
+	var d indexDelta
+	if err := se.Deserialize(delta, &d); err != nil {
+		return nil, err
+	}
+	for _, key := range d.Add {
+		if i, found := slices.BinarySearch(keys, key); !found {
+			keys = slices.Insert(keys, i, key)
+		}
+	}
+	for _, key := range d.Remove {
+		if i, found := slices.BinarySearch(keys, key); found {
+			keys = slices.Delete(keys, i, i+1)
+		}
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	return se.Serialize(keys)
+}
@@ -0,0 +1,47 @@
+package txn
+
+import (
+	"testing"
+
+	"github.com/kkkzoz/oreo/pkg/serializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyMergesIndex(t *testing.T) {
+	se := serializer.NewJSON2Serializer()
+	delta := func(d indexDelta) MergeDelta {
+		bs, _ := se.Serialize(d)
+		return MergeDelta{Operator: IndexOperator, Delta: string(bs)}
+	}
+
+	value, err := ApplyMerges(se, nil, []MergeDelta{
+		delta(indexDelta{Add: []string{"k3", "k1"}}),
+		delta(indexDelta{Add: []string{"k2", "k1"}}),
+		delta(indexDelta{Remove: []string{"k3", "k4"}}),
+	})
+	assert.NoError(t, err)
+	var keys []string
+	assert.NoError(t, se.Deserialize([]byte(value), &keys))
+	assert.Equal(t, []string{"k1", "k2"}, keys)
+
+	// an entry without keys is kept empty
+	value, err = ApplyMerges(se, nil, []MergeDelta{delta(indexDelta{Remove: []string{"k1"}})})
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", value)
+}
+
+func TestRegisterIndex(t *testing.T) {
+	extract := func(key string, value any) []string { return nil }
+	assert.Error(t, RegisterIndex(Index{Name: "by-owner", Datastore: "ds"}))
+	assert.Error(t, RegisterIndex(Index{Name: "by:owner", Datastore: "ds", Target: "ds", Extract: extract}))
+
+	assert.NoError(t, RegisterIndex(Index{Name: "by-owner", Datastore: "ds", Target: "ds", Extract: extract}))
+	assert.NoError(t, RegisterIndex(Index{Name: "by-owner", Datastore: "ds2", Target: "ds2", Extract: extract}))
+	assert.Empty(t, indexesOf("ds"))
+	assert.Len(t, indexesOf("ds2"), 1)
+
+	UnregisterIndex("by-owner")
+	_, ok := getIndex("by-owner")
+	assert.False(t, ok)
+	assert.Empty(t, indexesOf("ds2"))
+}
@@ -0,0 +1,69 @@
+package txn_test
+
+import (
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionMaintainsIndex(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	type device struct{ Owner string }
+	assert.NoError(t, txn.RegisterIndex(txn.Index{
+		Name:      "device-by-owner",
+		Datastore: "devices",
+		Target:    "index",
+		Extract: func(key string, value any) []string {
+			return []string{value.(device).Owner}
+		},
+	}))
+	defer txn.UnregisterIndex("device-by-owner")
+
+	conns := map[string]*mock.MockMemoryConnection{
+		"devices": mock.NewMockMemoryConnection(),
+		"index":   mock.NewMockMemoryConnection(),
+	}
+	lookup := func(tx *txn.Transaction, owner string) []string {
+		keys, err := tx.LookupIndex("device-by-owner", owner)
+		assert.NoError(t, err)
+		return keys
+	}
+
+	txn1 := newTestTransaction(conns)
+	assert.NoError(t, txn1.Start())
+	assert.NoError(t, txn1.Write("devices", "d1", device{Owner: "alice"}))
+	assert.NoError(t, txn1.Write("devices", "d2", device{Owner: "alice"}))
+	assert.NoError(t, txn1.Write("devices", "d3", device{Owner: "bob"}))
+	assert.Equal(t, []string{"d1", "d2"}, lookup(txn1, "alice"))
+	assert.NoError(t, txn1.Commit())
+
+	txn2 := newTestTransaction(conns)
+	assert.NoError(t, txn2.Start())
+	assert.NoError(t, txn2.Write("devices", "d2", device{Owner: "bob"}))
+	assert.NoError(t, txn2.Delete("devices", "d1"))
+	assert.Empty(t, lookup(txn2, "alice"))
+	assert.Equal(t, []string{"d2", "d3"}, lookup(txn2, "bob"))
+	assert.NoError(t, txn2.Commit())
+
+	// the devices of the same owner are indexed without conflicts
+	txn3 := newTestTransaction(conns)
+	txn4 := newTestTransaction(conns)
+	assert.NoError(t, txn3.Start())
+	assert.NoError(t, txn4.Start())
+	assert.NoError(t, txn3.Write("devices", "d4", device{Owner: "carol"}))
+	assert.NoError(t, txn4.Write("devices", "d5", device{Owner: "carol"}))
+	assert.NoError(t, txn3.Commit())
+	assert.NoError(t, txn4.Commit())
+
+	txn5 := newTestTransaction(conns)
+	assert.NoError(t, txn5.Start())
+	assert.Empty(t, lookup(txn5, "alice"))
+	assert.Equal(t, []string{"d2", "d3"}, lookup(txn5, "bob"))
+	assert.Equal(t, []string{"d4", "d5"}, lookup(txn5, "carol"))
+	assert.Error(t, txn5.Increment("devices", "counter", 1))
+	assert.NoError(t, txn5.Commit())
+}
@@ -0,0 +1,129 @@
+package txn
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/logger"
+)
+
+// SetLeaseTime sets the lease duration of the records prepared by the
+// transaction, overriding config.Config.LeaseTime.
+// It has to be called before Commit.
+func (t *Transaction) SetLeaseTime(leaseTime time.Duration) {
+	t.leaseTime = leaseTime
+}
+
+// getLeaseTime returns the lease duration of the records prepared by the transaction.
+func (t *Transaction) getLeaseTime() time.Duration {
+	if t.leaseTime != 0 {
+		return t.leaseTime
+	}
+	return config.Config.LeaseTime
+}
+
+// extendLease records tLease as the lease of the prepared records
+// unless a later one has been recorded.
+func (t *Transaction) extendLease(tLease time.Time) {
+	for {
+		cur := t.tLease.Load()
+		if cur >= tLease.UnixNano() || t.tLease.CompareAndSwap(cur, tLease.UnixNano()) {
+			return
+		}
+	}
+}
+
+// startHeartbeat renews the leases of the records prepared by the transaction
+// every third of the lease time, so readers do not roll them back while the
+// coordinator is still alive. It stops at the first failed renewal, since
+// the transaction is then bound to abort.
+//
+// The returned function stops the heartbeat and waits for it to exit.
+func (t *Transaction) startHeartbeat() func() {
+	leaseTime := t.getLeaseTime()
+	t.extendLease(config.Config.LeaseClock.Now().Add(leaseTime))
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(leaseTime / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			tLease := config.Config.LeaseClock.Now().Add(leaseTime)
+			for _, ds := range t.dataStoreMap {
+				d, ok := ds.(*Datastore)
+				if !ok {
+					continue
+				}
+				if err := d.renewLeases(tLease); err != nil {
+					logger.Warnw("failed to renew the leases", "txnId", t.TxnId,
+						"ds", ds.GetName(), "cause", err)
+					return
+				}
+			}
+			t.extendLease(tLease)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// renewLeases sets the TLease of the prepared records to tLease.
+// It fails if any of them has been rolled back or forward by another transaction.
+func (r *Datastore) renewLeases(tLease time.Time) error {
+	r.mu.Lock()
+	items := make([]DataItem, 0, len(r.prepared))
+	for key := range r.prepared {
+		items = append(items, r.writeCache[key])
+	}
+	r.mu.Unlock()
+	if len(items) == 0 {
+		return nil
+	}
+
+	if r.Txn.isRemote {
+		infoList := make([]CommitInfo, 0, len(items))
+		for _, item := range items {
+			infoList = append(infoList, CommitInfo{Key: item.Key(), Version: item.Version()})
+		}
+		verMap, err := r.Txn.RemoteRenew(r.Name, infoList, tLease)
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, item := range items {
+			if ver, ok := verMap[item.Key()]; ok {
+				item.SetTLease(tLease)
+				item.SetVersion(ver)
+			}
+		}
+		return nil
+	}
+
+	for _, item := range items {
+		item.SetTLease(tLease)
+		ver, err := r.conn.ConditionalUpdate(item.Key(), item, false)
+		if err != nil {
+			return errors.Errorf("failed to renew the lease of %s: %v", item.Key(), err)
+		}
+		r.mu.Lock()
+		item.SetVersion(ver)
+		r.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package txn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowGroupKeyConnection delays the creation of group keys,
+// which widens the window between the prepare phase and the commit point.
+type slowGroupKeyConnection struct {
+	*mock.MockMemoryConnection
+	delay time.Duration
+}
+
+func (c *slowGroupKeyConnection) AtomicCreate(name string, value any) (string, error) {
+	time.Sleep(c.delay)
+	return c.MockMemoryConnection.AtomicCreate(name, value)
+}
+
+func TestLeaseRenewedUntilCommitPoint(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	tx := txn.NewTransaction()
+	tx.AddDatastore(bolt.NewBoltDatastore("mem", &slowGroupKeyConnection{
+		MockMemoryConnection: conns["mem"],
+		delay:                300 * time.Millisecond,
+	}))
+	tx.SetLeaseTime(60 * time.Millisecond)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Write("mem", "key", "v1"))
+
+	errCh := make(chan error)
+	go func() { errCh <- tx.Commit() }()
+
+	// the lease would have expired without the heartbeat
+	time.Sleep(200 * time.Millisecond)
+	item, err := conns["mem"].GetItem("key")
+	assert.NoError(t, err)
+	assert.Equal(t, config.PREPARED, item.TxnState())
+	assert.True(t, item.TLease().After(time.Now()))
+
+	// so a reader can not roll the record back
+	reader := newTestTransaction(conns)
+	assert.NoError(t, reader.Start())
+	var value string
+	assert.Error(t, reader.Read("mem", "key", &value))
+
+	assert.NoError(t, <-errCh)
+	postTxn := newTestTransaction(conns)
+	assert.NoError(t, postTxn.Start())
+	assert.NoError(t, postTxn.Read("mem", "key", &value))
+	assert.Equal(t, "v1", value)
+}
@@ -0,0 +1,105 @@
+package txn
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/serializer"
+)
+
+// IncrementOperator is the name of the built-in merge operator
+// that adds an int64 delta to an int64 value.
+const IncrementOperator = "increment"
+
+// MERGERETRY is the number of attempts to apply the deltas of a record
+// on top of its latest committed version during Prepare.
+const MERGERETRY = 20
+
+var MergeConflict = errors.Errorf("merge failed due to concurrent updates")
+
+// MergeOperator combines the value of a record with a delta.
+//
+// Deltas of the same operator must commute, so the outcome does not depend on
+// the order in which concurrent transactions apply them.
+// Operators are looked up by name, so the executors in remote mode
+// have to register the same operators as the clients.
+type MergeOperator interface {
+	// Merge returns the new serialized value of a record given its current
+	// serialized value and a serialized delta.
+	// existing is empty if the record does not exist.
+	Merge(se serializer.Serializer, existing []byte, delta []byte) ([]byte, error)
+}
+
+// MergeFunc is an adapter to use an ordinary function as a MergeOperator.
+type MergeFunc func(se serializer.Serializer, existing []byte, delta []byte) ([]byte, error)
+
+// Merge calls f(se, existing, delta).
+func (f MergeFunc) Merge(se serializer.Serializer, existing []byte, delta []byte) ([]byte, error) {
+	return f(se, existing, delta)
+}
+
+// MergeDelta is a delta recorded by Transaction.Merge.
+type MergeDelta struct {
+	Operator string
+	// Delta is the serialized delta.
+	Delta string
+}
+
+var (
+	mergeOperatorsMu sync.RWMutex
+	mergeOperators   = map[string]MergeOperator{
+		IncrementOperator: MergeFunc(increment),
+		IndexOperator:     MergeFunc(mergeIndex),
+	}
+)
+
+// RegisterMergeOperator registers op under name, replacing any operator
+// registered under the same name.
+func RegisterMergeOperator(name string, op MergeOperator) {
+	mergeOperatorsMu.Lock()
+	defer mergeOperatorsMu.Unlock()
+	mergeOperators[name] = op
+}
+
+// GetMergeOperator returns the operator registered under name.
+func GetMergeOperator(name string) (MergeOperator, bool) {
+	mergeOperatorsMu.RLock()
+	defer mergeOperatorsMu.RUnlock()
+	op, ok := mergeOperators[name]
+	return op, ok
+}
+
+// ApplyMerges applies deltas in order on top of the value of base.
+// A nil, deleted or expired base is treated as an absent record.
+func ApplyMerges(se serializer.Serializer, base DataItem, deltas []MergeDelta) (string, error) {
+	var value []byte
+	if isLive(base, config.Config.LeaseClock.Now()) {
+		value = []byte(base.Value())
+	}
+	for _, d := range deltas {
+		op, ok := GetMergeOperator(d.Operator)
+		if !ok {
+			return "", errors.Errorf("unknown merge operator: %s", d.Operator)
+		}
+		var err error
+		value, err = op.Merge(se, value, []byte(d.Delta))
+		if err != nil {
+			return "", err
+		}
+	}
+	return string(value), nil
+}
+
+func increment(se serializer.Serializer, existing []byte, delta []byte) ([]byte, error) {
+	var cur, d int64
+	if len(existing) != 0 {
+		if err := se.Deserialize(existing, &cur); err != nil {
+			return nil, err
+		}
+	}
+	if err := se.Deserialize(delta, &d); err != nil {
+		return nil, err
+	}
+	return se.Serialize(cur + d)
+}
@@ -0,0 +1,57 @@
+package txn
+
+import (
+	"testing"
+
+	"github.com/kkkzoz/oreo/pkg/serializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyMergesIncrement(t *testing.T) {
+	se := serializer.NewJSON2Serializer()
+	delta := func(d int64) MergeDelta {
+		bs, _ := se.Serialize(d)
+		return MergeDelta{Operator: IncrementOperator, Delta: string(bs)}
+	}
+
+	// absent record starts from zero
+	value, err := ApplyMerges(se, nil, []MergeDelta{delta(3), delta(-1)})
+	assert.NoError(t, err)
+	var res int64
+	assert.NoError(t, se.Deserialize([]byte(value), &res))
+	assert.Equal(t, int64(2), res)
+}
+
+func TestApplyMergesUnknownOperator(t *testing.T) {
+	se := serializer.NewJSON2Serializer()
+	_, err := ApplyMerges(se, nil, []MergeDelta{{Operator: "unknown", Delta: "1"}})
+	assert.Error(t, err)
+}
+
+func TestRegisterMergeOperator(t *testing.T) {
+	se := serializer.NewJSON2Serializer()
+	RegisterMergeOperator("append", MergeFunc(
+		func(se serializer.Serializer, existing []byte, delta []byte) ([]byte, error) {
+			var cur []string
+			if len(existing) != 0 {
+				if err := se.Deserialize(existing, &cur); err != nil {
+					return nil, err
+				}
+			}
+			var d string
+			if err := se.Deserialize(delta, &d); err != nil {
+				return nil, err
+			}
+			return se.Serialize(append(cur, d))
+		}))
+
+	bs, _ := se.Serialize("b")
+	value, err := ApplyMerges(se, nil, []MergeDelta{
+		{Operator: "append", Delta: `"a"`},
+		{Operator: "append", Delta: string(bs)},
+	})
+	assert.NoError(t, err)
+	var res []string
+	assert.NoError(t, se.Deserialize([]byte(value), &res))
+	assert.Equal(t, []string{"a", "b"}, res)
+}
@@ -0,0 +1,60 @@
+package txn_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrentIncrement(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	concurrency := 10
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx := newTestTransaction(conns)
+			assert.NoError(t, tx.Start())
+			assert.NoError(t, tx.Increment("mem", "count", 2))
+			assert.NoError(t, tx.Commit())
+		}()
+	}
+	wg.Wait()
+
+	tx := newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	var count int64
+	assert.NoError(t, tx.Read("mem", "count", &count))
+	assert.Equal(t, int64(2*concurrency), count)
+}
+
+func TestIncrementReadYourWrites(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	preTxn := newTestTransaction(conns)
+	assert.NoError(t, preTxn.Start())
+	assert.NoError(t, preTxn.Write("mem", "count", int64(10)))
+	assert.NoError(t, preTxn.Commit())
+
+	tx := newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Increment("mem", "count", 5))
+	assert.NoError(t, tx.Increment("mem", "count", 1))
+	var count int64
+	assert.NoError(t, tx.Read("mem", "count", &count))
+	assert.Equal(t, int64(16), count)
+
+	// a write overwrites the pending deltas
+	assert.NoError(t, tx.Write("mem", "count", int64(1)))
+	assert.NoError(t, tx.Increment("mem", "count", 1))
+	assert.NoError(t, tx.Read("mem", "count", &count))
+	assert.Equal(t, int64(2), count)
+	assert.NoError(t, tx.Commit())
+}
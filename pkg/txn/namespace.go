@@ -0,0 +1,82 @@
+package txn
+
+import (
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// NamespaceSeparator separates the namespace of a transaction
+// from the keys of its records and from its id.
+const NamespaceSeparator = "/"
+
+// Namespacer is implemented by the connectors that can keep the records
+// of a namespace apart from the others, e.g. in a collection per namespace.
+type Namespacer interface {
+	// WithNamespace returns a connector to the records of namespace ns.
+	WithNamespace(ns string) (Connector, error)
+}
+
+// NamespacedConnector returns conn routed to namespace ns if it is
+// a Namespacer, or conn itself otherwise.
+func NamespacedConnector(conn Connector, ns string) (Connector, error) {
+	if namespacer, ok := conn.(Namespacer); ok && ns != "" {
+		return namespacer.WithNamespace(ns)
+	}
+	return conn, nil
+}
+
+// SetNamespace puts the transaction in namespace ns, so the tenants
+// sharing the same datastores do not see each other's records.
+//
+// The keys of the records are prefixed with the namespace, and so is the
+// transaction id, hence the group keys "<ds>:<ns>/<txnId>". The connectors
+// that are Namespacers are routed to the namespace as well, and the executors
+// are told the namespace in the remote mode. It must be called before Start.
+func (t *Transaction) SetNamespace(ns string) {
+	t.namespace = ns
+}
+
+// Namespace returns the namespace of the transaction.
+func (t *Transaction) Namespace() string {
+	return t.namespace
+}
+
+// namespacedKey returns the key of the record key in the namespace.
+func (t *Transaction) namespacedKey(key string) string {
+	if t.namespace == "" {
+		return key
+	}
+	return t.namespace + NamespaceSeparator + key
+}
+
+// startNamespace routes the datastores and the remote client of the
+// transaction to its namespace. It is called by Start.
+func (t *Transaction) startNamespace() error {
+	if t.namespace == "" {
+		return nil
+	}
+	// the group keys are split by ':' and the group key lists by ',' and ' '
+	if strings.ContainsAny(t.namespace, ":, "+NamespaceSeparator) {
+		return errors.Errorf("invalid namespace %q", t.namespace)
+	}
+	t.TxnId = t.namespace + NamespaceSeparator + t.TxnId
+
+	if t.isRemote {
+		if client, ok := t.client.(interface {
+			WithNamespace(ns string) RemoteClient
+		}); ok {
+			t.client = client.WithNamespace(t.namespace)
+		}
+	}
+	// the group keys are created through the datastores in the remote mode too
+	for _, ds := range t.dataStoreMap {
+		if d, ok := ds.(*Datastore); ok {
+			if err := d.setNamespace(t.namespace); err != nil {
+				return err
+			}
+			t.groupKeyMaintainer.AddConnector(d)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package txn_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionNamespaces(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	for _, ns := range []string{"tenant-a", "tenant-b"} {
+		tx := newTestTransaction(conns)
+		tx.SetNamespace(ns)
+		assert.NoError(t, tx.Start())
+		assert.True(t, strings.HasPrefix(tx.TxnId, ns+txn.NamespaceSeparator))
+		assert.NoError(t, tx.Write("mem", "key", "value of "+ns))
+		assert.NoError(t, tx.Commit())
+	}
+
+	for _, ns := range []string{"tenant-a", "tenant-b"} {
+		tx := newTestTransaction(conns)
+		tx.SetNamespace(ns)
+		assert.NoError(t, tx.Start())
+		var value string
+		assert.NoError(t, tx.Read("mem", "key", &value))
+		assert.Equal(t, "value of "+ns, value)
+		assert.NoError(t, tx.Commit())
+
+		_, err := conns["mem"].GetItem(ns + txn.NamespaceSeparator + "key")
+		assert.NoError(t, err)
+	}
+
+	// the records of the namespaces are not visible without one
+	tx := newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	var value string
+	assert.Error(t, tx.Read("mem", "key", &value))
+	assert.NoError(t, tx.Commit())
+
+	tx = newTestTransaction(conns)
+	tx.SetNamespace("tenant:a")
+	assert.Error(t, tx.Start())
+}
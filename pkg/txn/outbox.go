@@ -0,0 +1,94 @@
+package txn
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+)
+
+// Change is a write buffered by a transaction.
+type Change struct {
+	Datastore string
+	Key       string
+	// Value is the serialized value of the record.
+	// It is empty for deletes and for records written by Merge.
+	Value     string
+	IsDeleted bool
+	// Deltas are the pending deltas of a record written by Merge.
+	// The resulting value is only known once the record is prepared,
+	// so the deltas are recorded instead.
+	Deltas []MergeDelta
+}
+
+// OutboxEntry is the record of the writes of a transaction
+// that is appended to the Outbox before the transaction is prepared.
+type OutboxEntry struct {
+	TxnId string
+	// StartTime is the TxnStartTime of the transaction,
+	// which is a lower bound of its commit time.
+	StartTime int64
+	// GroupKeyUrls are the group keys deciding the outcome of the transaction.
+	GroupKeyUrls []string
+	Changes      []Change
+	// CreatedAt is the time when the entry was appended,
+	// according to config.Config.LeaseClock.
+	CreatedAt time.Time
+}
+
+// Outbox durably stores the writes of the transactions being committed,
+// so they can be published once their group keys are committed,
+// even if the coordinator crashes in the meantime.
+type Outbox interface {
+	// Append stores the entry. It must be durable when it returns.
+	Append(entry OutboxEntry) error
+	// Pending returns the entries that have not been removed.
+	Pending() ([]OutboxEntry, error)
+	// Remove deletes the entry of txnId. This operation must be idempotent.
+	Remove(txnId string) error
+}
+
+// SetOutbox makes the transaction append its writes to outbox before
+// they are prepared. It has to be called before Commit.
+func (t *Transaction) SetOutbox(outbox Outbox) {
+	t.outbox = outbox
+}
+
+// appendToOutbox appends the writes buffered in the datastores to the outbox.
+func (t *Transaction) appendToOutbox() error {
+	entry := OutboxEntry{
+		TxnId:        t.TxnId,
+		StartTime:    t.TxnStartTime,
+		GroupKeyUrls: t.GroupKeyUrls,
+		CreatedAt:    config.Config.LeaseClock.Now(),
+	}
+	for dsName, ds := range t.dataStoreMap {
+		d, ok := ds.(*Datastore)
+		if !ok {
+			return errors.New("outbox is not supported by datastore: " + dsName)
+		}
+		entry.Changes = append(entry.Changes, d.changes()...)
+	}
+	return t.outbox.Append(entry)
+}
+
+// changes returns the writes buffered in the Datastore.
+func (r *Datastore) changes() []Change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changes := make([]Change, 0, len(r.writeCache))
+	for key, item := range r.writeCache {
+		change := Change{
+			Datastore: r.Name,
+			Key:       key,
+			IsDeleted: item.IsDeleted(),
+		}
+		if deltas, ok := r.merges[key]; ok {
+			change.Deltas = append([]MergeDelta(nil), deltas...)
+		} else if !item.IsDeleted() {
+			change.Value = item.Value()
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
@@ -0,0 +1,58 @@
+package txn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/cdc"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayPublishesCommittedWrites(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	outbox := cdc.NewMemoryOutbox()
+	sink := cdc.NewChanSink(10)
+	relay := cdc.NewRelay(outbox, sink, 0, bolt.NewBoltDatastore("mem", conns["mem"]))
+
+	txn1 := newTestTransaction(conns)
+	txn1.SetOutbox(outbox)
+	assert.NoError(t, txn1.Start())
+	assert.NoError(t, txn1.Write("mem", "key", "v1"))
+	assert.NoError(t, txn1.Commit())
+
+	// txn3 aborts due to the concurrent write of txn2
+	txn2 := newTestTransaction(conns)
+	txn2.SetOutbox(outbox)
+	txn3 := newTestTransaction(conns)
+	txn3.SetOutbox(outbox)
+	assert.NoError(t, txn2.Start())
+	assert.NoError(t, txn3.Start())
+	var value string
+	assert.NoError(t, txn2.Read("mem", "key", &value))
+	assert.NoError(t, txn3.Read("mem", "key", &value))
+	assert.NoError(t, txn2.Write("mem", "key", "v2"))
+	assert.NoError(t, txn3.Write("mem", "key", "v3"))
+	assert.NoError(t, txn2.Commit())
+	assert.Error(t, txn3.Commit())
+	time.Sleep(100 * time.Millisecond)
+
+	assert.NoError(t, relay.Flush())
+	assert.NoError(t, sink.Close())
+	var events []cdc.ChangeEvent
+	for event := range sink.Events() {
+		events = append(events, event)
+	}
+	assert.Len(t, events, 2)
+	assert.Equal(t, txn1.TxnId, events[0].TxnId)
+	assert.Equal(t, txn2.TxnId, events[1].TxnId)
+	assert.LessOrEqual(t, events[0].TCommit, events[1].TCommit)
+
+	entries, err := outbox.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
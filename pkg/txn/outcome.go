@@ -0,0 +1,193 @@
+package txn
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+)
+
+// TxnOutcome is the outcome of a transaction.
+type TxnOutcome struct {
+	TxnId string
+	// State is COMMITTED or ABORTED,
+	// or STARTED if the outcome is not decided yet.
+	State config.State
+	// TCommit is the commit time of a committed transaction.
+	// It is zero if the group keys do not carry one.
+	TCommit int64
+}
+
+// IsDecided reports whether the outcome is definitive.
+func (o TxnOutcome) IsDecided() bool {
+	return o.State == config.COMMITTED || o.State == config.ABORTED
+}
+
+// Status returns the outcome of the transaction, which is useful when
+// Commit fails without telling whether the commit point was passed,
+// e.g. after a timeout.
+//
+// The outcome is resolved from the group keys of the transaction with the
+// rules used by the readers. If it is not decided and the leases of the
+// prepared records have expired, the transaction is aborted by creating
+// its missing group keys in the ABORTED state.
+//
+// Status must not be called concurrently with Commit.
+func (t *Transaction) Status() (TxnOutcome, error) {
+	outcome := TxnOutcome{TxnId: t.TxnId}
+	switch {
+	case t.committed:
+		// the group keys may have been deleted since
+		outcome.State, outcome.TCommit = config.COMMITTED, t.TxnCommitTime
+		return outcome, nil
+	case t.GetState() == config.ABORTED:
+		outcome.State = config.ABORTED
+		return outcome, nil
+	case t.GetState() != config.COMMITTED:
+		outcome.State = config.STARTED
+		return outcome, nil
+	case len(t.GroupKeyUrls) == 0 || config.Debug.NativeMode:
+		// a read-only transaction, or a transaction without group keys,
+		// has returned from Commit with its outcome
+		outcome.State = config.COMMITTED
+		return outcome, nil
+	}
+	lease := func() (time.Time, error) {
+		if nanos := t.tLease.Load(); nanos != 0 {
+			return time.Unix(0, nanos), nil
+		}
+		return time.Time{}, nil
+	}
+	return resolveOutcome(&t.groupKeyMaintainer, outcome, t.GroupKeyUrls, lease)
+}
+
+// QueryOutcome returns the outcome of the transaction txnId from its group
+// keys "<ds>:<txnId>" in dss, using the same rules as Transaction.Status.
+// It is meant for the clients that lost the Transaction, e.g. after a crash.
+//
+// dss must be the datastores holding the group keys of the transaction, i.e.
+// the datastores in its GroupKeyUrls, and keys maps the name of each of them
+// to the keys written by the transaction, as stored in the datastore.
+// A datastore without a group key keeps the outcome undecided. The transaction
+// is then aborted once the leases of its records at keys that are still
+// prepared have expired. Without such records, it stays undecided.
+func QueryOutcome(txnId string, keys map[string][]string, dss ...Datastorer) (TxnOutcome, error) {
+	if len(dss) == 0 {
+		return TxnOutcome{}, errors.New("no datastores given")
+	}
+	groupKeys := NewGroupKeyMaintainer()
+	urls := make([]string, 0, len(dss))
+	for _, ds := range dss {
+		groupKeys.AddConnector(ds)
+		urls = append(urls, fmt.Sprintf("%s:%s", ds.GetName(), txnId))
+	}
+	lease := func() (time.Time, error) {
+		var tLease time.Time
+		for _, ds := range dss {
+			dsLease, _, err := PreparedLease(ds.GetConn(), txnId, keys[ds.GetName()])
+			if err != nil {
+				return time.Time{}, err
+			}
+			if dsLease.After(tLease) {
+				tLease = dsLease
+			}
+		}
+		return tLease, nil
+	}
+	return resolveOutcome(groupKeys, TxnOutcome{TxnId: txnId}, urls, lease)
+}
+
+// resolveOutcome reads the group keys at urls. If the outcome is not decided
+// and the lease of the prepared records, returned by lease, has passed, it
+// creates the missing group keys as ABORTED and reads them again, since the
+// coordinator may have passed the commit point in the meantime.
+func resolveOutcome(
+	g *GroupKeyMaintainer,
+	outcome TxnOutcome,
+	urls []string,
+	lease func() (time.Time, error),
+) (TxnOutcome, error) {
+	outcome, err := readOutcome(g, outcome, urls)
+	if err != nil || outcome.IsDecided() {
+		return outcome, err
+	}
+	tLease, err := lease()
+	if err != nil || !LeaseExpired(tLease) {
+		return outcome, err
+	}
+	g.CreateGroupKey(urls, config.ABORTED)
+	return readOutcome(g, outcome, urls)
+}
+
+func readOutcome(g *GroupKeyMaintainer, outcome TxnOutcome, urls []string) (TxnOutcome, error) {
+	groupKeys := make([]GroupKey, 0, len(urls))
+	for _, url := range urls {
+		groupKey, err := g.GetSingleGroupKey(url)
+		if err != nil {
+			if errors.Is(err, KeyNotFound) {
+				continue
+			}
+			return outcome, err
+		}
+		groupKeys = append(groupKeys, groupKey)
+	}
+
+	outcome.State, outcome.TCommit = config.STARTED, 0
+	switch {
+	case AtLeastOneAborted(groupKeys):
+		outcome.State = config.ABORTED
+	case len(groupKeys) == len(urls) && CommittedForAll(groupKeys):
+		outcome.State = config.COMMITTED
+		for _, gk := range groupKeys {
+			outcome.TCommit = max(outcome.TCommit, gk.TCommit)
+		}
+	}
+	return outcome, nil
+}
+
+// LeaseExpired reports whether tLease, the lease of the records prepared by
+// a transaction, has passed according to config.Config.LeaseClock.
+// A zero tLease, of a transaction without prepared records, never expires.
+func LeaseExpired(tLease time.Time) bool {
+	return !tLease.IsZero() && tLease.Before(config.Config.LeaseClock.Now())
+}
+
+// PreparedLease reads the records at keys in conn, and returns the latest
+// TLease of those prepared by the transaction txnId, or the zero time if
+// none of them is. found reports whether any of the records has been written
+// by the transaction, whether it is still prepared or not.
+func PreparedLease(conn Connector, txnId string, keys []string) (tLease time.Time, found bool, err error) {
+	for _, key := range keys {
+		item, err := conn.GetItem(key)
+		if err != nil {
+			if errors.Is(err, KeyNotFound) {
+				continue
+			}
+			return time.Time{}, false, err
+		}
+		if !writtenBy(item, txnId) {
+			continue
+		}
+		found = true
+		if item.TxnState() == config.PREPARED && item.TLease().After(tLease) {
+			tLease = item.TLease()
+		}
+	}
+	return tLease, found, nil
+}
+
+// writtenBy reports whether item is a version written by the transaction
+// txnId, i.e. one of its group keys "<ds>:<txnId>" belongs to the transaction.
+func writtenBy(item DataItem, txnId string) bool {
+	if item == nil || item.Empty() {
+		return false
+	}
+	for _, url := range strings.Split(item.GroupKeyList(), ",") {
+		if strings.HasSuffix(url, ":"+txnId) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package txn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionStatus(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conn := mock.NewMockMemoryConnection()
+	conns := map[string]*mock.MockMemoryConnection{"mem": conn}
+	txn1 := newTestTransaction(conns)
+	assert.NoError(t, txn1.Start())
+	assert.NoError(t, txn1.Write("mem", "x", "v1"))
+	assert.NoError(t, txn1.Commit())
+	outcome, err := txn1.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, config.COMMITTED, outcome.State)
+
+	ds := bolt.NewBoltDatastore("mem", conn)
+	outcome, err = txn.QueryOutcome(txn1.TxnId, nil, ds)
+	assert.NoError(t, err)
+	assert.Equal(t, config.COMMITTED, outcome.State)
+
+	// the group key of an in-doubt transaction is missing,
+	// and its record is still leased
+	txnId := "in-doubt"
+	record := bolt.NewBoltItem(txn.ItemOptions{
+		Key:          "y",
+		Value:        "v1",
+		GroupKeyList: "mem:" + txnId,
+		TxnState:     config.PREPARED,
+		TLease:       time.Now().Add(time.Hour),
+	})
+	_, err = conn.PutItem("y", record)
+	assert.NoError(t, err)
+	keys := map[string][]string{"mem": {"y"}}
+	outcome, err = txn.QueryOutcome(txnId, keys, ds)
+	assert.NoError(t, err)
+	assert.Equal(t, config.STARTED, outcome.State)
+	assert.False(t, outcome.IsDecided())
+
+	// it is aborted once the lease of its record has expired,
+	// which is only known from the keys of its records
+	record.BTLease = time.Now().Add(-time.Second)
+	_, err = conn.PutItem("y", record)
+	assert.NoError(t, err)
+	outcome, err = txn.QueryOutcome(txnId, nil, ds)
+	assert.NoError(t, err)
+	assert.Equal(t, config.STARTED, outcome.State)
+	outcome, err = txn.QueryOutcome(txnId, keys, ds)
+	assert.NoError(t, err)
+	assert.Equal(t, config.ABORTED, outcome.State)
+	groupKey, err := conn.Get("mem:" + txnId)
+	assert.NoError(t, err)
+	assert.Contains(t, groupKey, `"TxnState":4`)
+}
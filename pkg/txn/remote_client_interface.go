@@ -1,6 +1,8 @@
 package txn
 
 import (
+	"time"
+
 	"github.com/kkkzoz/oreo/pkg/config"
 )
 
@@ -22,6 +24,9 @@ const (
 	CassandraItem ItemType = "cassandra"
 	DynamoDBItem  ItemType = "dynamodb"
 	TiKVItem      ItemType = "tikv"
+	BoltItem      ItemType = "bolt"
+	PostgresItem  ItemType = "postgres"
+	EtcdItem      ItemType = "etcd"
 )
 
 type NetworkItem struct {
@@ -32,6 +37,9 @@ type NetworkItem struct {
 type CommitInfo struct {
 	Key     string
 	Version string
+	// TExpire is the expiry of the record written with a TTL,
+	// which the executor sets natively once it is committed.
+	TExpire time.Time
 }
 
 type RecordConfig struct {
@@ -40,6 +48,16 @@ type RecordConfig struct {
 	ReadStrategy                config.ReadStrategy
 	ConcurrentOptimizationLevel int
 	AblationLevel               int
+	// ClockUncertainty is the maximum clock offset in microseconds.
+	// Zero means timestamps come from the time oracle.
+	ClockUncertainty int64
+	IsolationLevel   config.IsolationLevel
+	// LeaseTime is the lease duration of the prepared records.
+	// Zero means the LeaseTime of the executor.
+	LeaseTime time.Duration
+	// OldestSnapshot is the oldest active snapshot of the coordinator,
+	// offered to the retention policy. Zero means it is not known.
+	OldestSnapshot int64
 }
 
 type RemoteClient interface {
@@ -51,7 +69,11 @@ type RemoteClient interface {
 	) (DataItem, RemoteDataStrategy, string, error)
 	Prepare(dsName string, itemList []DataItem,
 		startTime int64,
-		config RecordConfig, validationMap map[string]PredicateInfo) (map[string]string, int64, error)
+		config RecordConfig, validationMap map[string]PredicateInfo,
+		readSet map[string]ReadVersion,
+		conds map[string]WriteCondition,
+		merges map[string][]MergeDelta) (map[string]string, int64, error)
 	Commit(dsName string, infoList []CommitInfo, TCommit int64) error
+	Renew(dsName string, infoList []CommitInfo, tLease time.Time) (map[string]string, error)
 	Abort(dsName string, keyList []string, txnId string) error
 }
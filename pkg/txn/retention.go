@@ -0,0 +1,260 @@
+package txn
+
+import (
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/internal/util"
+	"github.com/kkkzoz/oreo/pkg/serializer"
+)
+
+// RetainedVersion is a version of a record
+// offered to a RetentionPolicy when a newer version is prepared.
+type RetainedVersion struct {
+	// Key is the key of the record, prefixed with its namespace if any.
+	Key string
+	// Depth is the position of the version in the linked list of the record,
+	// 1 for the version being prepared.
+	Depth int
+	// TValid is the commit timestamp of the version.
+	TValid int64
+	// NextTValid is the commit timestamp of the next newer version,
+	// so the version is visible to the snapshots started in (TValid, NextTValid].
+	NextTValid int64
+	// OldestSnapshot is the start time of the oldest active transaction
+	// known to the node preparing the version.
+	OldestSnapshot int64
+}
+
+// RetentionPolicy decides which versions of a record are kept in its
+// linked list, and hence how old the snapshots able to read it may be.
+//
+// The same policy has to be set on the clients and on the executors,
+// see SetRetentionPolicy, since the records are truncated by both.
+type RetentionPolicy interface {
+	// Keep reports whether version is kept. The versions are offered from
+	// the newest, and the first one not kept is dropped with the older ones.
+	// The version being prepared is always kept.
+	Keep(version RetainedVersion) bool
+	// MaxVersions returns the maximum number of versions of key kept,
+	// which is how deep the readers look. Zero means no limit.
+	MaxVersions(key string) int
+}
+
+// CountRetention keeps the newest Versions versions of the records.
+type CountRetention struct {
+	Versions int
+}
+
+func (p CountRetention) Keep(version RetainedVersion) bool {
+	return version.Depth <= p.MaxVersions(version.Key)
+}
+
+func (p CountRetention) MaxVersions(key string) int {
+	return max(p.Versions, 1)
+}
+
+// HorizonRetention keeps the versions visible to the snapshots started
+// up to Horizon before the oldest active snapshot, so the long snapshots
+// of hot records do not fail because their versions were truncated.
+//
+// The snapshots of the other nodes are not known, so Horizon has to cover
+// them. It is measured in the units of the timestamps of the time source,
+// e.g. microseconds for timesource.SimpleTimeSource.
+type HorizonRetention struct {
+	Horizon int64
+	// Max bounds the number of versions kept. Zero means no limit.
+	Max int
+}
+
+func (p HorizonRetention) Keep(version RetainedVersion) bool {
+	if p.Max > 0 && version.Depth > p.Max {
+		return false
+	}
+	return version.NextTValid >= version.OldestSnapshot-p.Horizon
+}
+
+func (p HorizonRetention) MaxVersions(key string) int {
+	return p.Max
+}
+
+// PrefixRule applies Policy to the records whose keys start with Prefix.
+type PrefixRule struct {
+	Prefix string
+	Policy RetentionPolicy
+}
+
+// PrefixRetention applies the rule with the longest prefix of the key
+// of a record, or Default if none matches.
+type PrefixRetention struct {
+	Rules   []PrefixRule
+	Default RetentionPolicy
+}
+
+func (p PrefixRetention) Keep(version RetainedVersion) bool {
+	return p.policyOf(version.Key).Keep(version)
+}
+
+func (p PrefixRetention) MaxVersions(key string) int {
+	return p.policyOf(key).MaxVersions(key)
+}
+
+func (p PrefixRetention) policyOf(key string) RetentionPolicy {
+	var policy RetentionPolicy
+	longest := -1
+	for _, rule := range p.Rules {
+		if len(rule.Prefix) > longest && strings.HasPrefix(key, rule.Prefix) {
+			policy, longest = rule.Policy, len(rule.Prefix)
+		}
+	}
+	if policy == nil {
+		policy = p.Default
+	}
+	if policy == nil {
+		policy = CountRetention{Versions: 1}
+	}
+	return policy
+}
+
+var (
+	retentionMu sync.RWMutex
+	retention   RetentionPolicy
+)
+
+// SetRetentionPolicy sets the retention policy of the records.
+// A nil policy restores the default one,
+// which keeps config.Config.MaxRecordLength versions.
+func SetRetentionPolicy(policy RetentionPolicy) {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+	retention = policy
+}
+
+// RetentionPolicyOr returns the retention policy set by SetRetentionPolicy,
+// or a CountRetention keeping maxLen versions if none is set.
+func RetentionPolicyOr(maxLen int) RetentionPolicy {
+	retentionMu.RLock()
+	defer retentionMu.RUnlock()
+	if retention == nil {
+		return CountRetention{Versions: maxLen}
+	}
+	return retention
+}
+
+// TruncateHistory returns oldItem, the current version of a record,
+// with its linked list truncated to the versions kept by policy once
+// a new version committing at commitTime is prepared on top of it.
+// It returns nil if none of the versions of oldItem is kept.
+//
+// getPrev returns the previous version of a version, and se serializes
+// the versions into the Prev fields. A zero oldestSnapshot means the
+// oldest active snapshot is not known, and commitTime is used instead.
+func TruncateHistory(
+	policy RetentionPolicy,
+	oldItem DataItem,
+	commitTime int64,
+	oldestSnapshot int64,
+	getPrev func(DataItem) (DataItem, error),
+	se serializer.Serializer,
+) (DataItem, error) {
+	if oldestSnapshot == 0 {
+		oldestSnapshot = commitTime
+	}
+	if commitTime == 0 {
+		// the commit time is not known before the prepare phase completes
+		commitTime = math.MaxInt64
+	}
+
+	stack := util.NewStack[DataItem]()
+	curItem, nextTValid := oldItem, commitTime
+	for depth := 2; ; depth++ {
+		keep := policy.Keep(RetainedVersion{
+			Key:            oldItem.Key(),
+			Depth:          depth,
+			TValid:         curItem.TValid(),
+			NextTValid:     nextTValid,
+			OldestSnapshot: oldestSnapshot,
+		})
+		if !keep {
+			break
+		}
+		stack.Push(curItem)
+		if curItem.Prev() == "" {
+			break
+		}
+		preItem, err := getPrev(curItem)
+		if err != nil {
+			return nil, errors.New("Unmarshal error: " + err.Error())
+		}
+		curItem, nextTValid = preItem, curItem.TValid()
+	}
+	if stack.IsEmpty() {
+		return nil, nil
+	}
+
+	tarItem, err := stack.Pop()
+	if err != nil {
+		return nil, errors.New("Pop error: " + err.Error())
+	}
+	tarItem.SetPrev("")
+	tarItem.SetLinkedLen(1)
+	for !stack.IsEmpty() {
+		item, err := stack.Pop()
+		if err != nil {
+			return nil, errors.New("Pop error: " + err.Error())
+		}
+		bs, err := se.Serialize(tarItem)
+		if err != nil {
+			return nil, errors.New("Serialize error: " + err.Error())
+		}
+		item.SetPrev(string(bs))
+		item.SetLinkedLen(tarItem.LinkedLen() + 1)
+		tarItem = item
+	}
+	return tarItem, nil
+}
+
+var activeSnapshots = struct {
+	sync.Mutex
+	starts map[int64]int
+}{starts: make(map[int64]int)}
+
+// OldestActiveSnapshot returns the start time of the oldest transaction
+// of this process that has started but not yet committed or aborted,
+// or zero if there is none.
+func OldestActiveSnapshot() int64 {
+	activeSnapshots.Lock()
+	defer activeSnapshots.Unlock()
+	var oldest int64
+	for start := range activeSnapshots.starts {
+		if oldest == 0 || start < oldest {
+			oldest = start
+		}
+	}
+	return oldest
+}
+
+// beginSnapshot records the snapshot of the transaction as active.
+// The start time is kept, since TxnStartTime moves when a read restarts.
+func (t *Transaction) beginSnapshot() {
+	activeSnapshots.Lock()
+	defer activeSnapshots.Unlock()
+	activeSnapshots.starts[t.TxnStartTime]++
+	t.snapshotStart = t.TxnStartTime
+}
+
+// endSnapshot records the end of the snapshot of the transaction.
+func (t *Transaction) endSnapshot() {
+	activeSnapshots.Lock()
+	defer activeSnapshots.Unlock()
+	start := t.snapshotStart
+	if start == 0 {
+		return
+	}
+	t.snapshotStart = 0
+	if activeSnapshots.starts[start]--; activeSnapshots.starts[start] <= 0 {
+		delete(activeSnapshots.starts, start)
+	}
+}
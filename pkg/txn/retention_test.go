@@ -0,0 +1,83 @@
+package txn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountRetention(t *testing.T) {
+	p := CountRetention{Versions: 3}
+	assert.True(t, p.Keep(RetainedVersion{Key: "k", Depth: 3}))
+	assert.False(t, p.Keep(RetainedVersion{Key: "k", Depth: 4}))
+	assert.Equal(t, 3, p.MaxVersions("k"))
+
+	// the version being prepared is always kept
+	assert.Equal(t, 1, CountRetention{}.MaxVersions("k"))
+}
+
+func TestHorizonRetention(t *testing.T) {
+	p := HorizonRetention{Horizon: 10, Max: 5}
+	version := func(depth int, nextTValid int64) RetainedVersion {
+		return RetainedVersion{Key: "k", Depth: depth, NextTValid: nextTValid, OldestSnapshot: 100}
+	}
+	// visible to the snapshots started after 90
+	assert.True(t, p.Keep(version(2, 95)))
+	assert.True(t, p.Keep(version(3, 90)))
+	// overwritten before any of them started
+	assert.False(t, p.Keep(version(3, 89)))
+	// bounded by Max
+	assert.False(t, p.Keep(version(6, 200)))
+	assert.Equal(t, 5, p.MaxVersions("k"))
+	assert.Equal(t, 0, HorizonRetention{Horizon: 10}.MaxVersions("k"))
+}
+
+func TestPrefixRetention(t *testing.T) {
+	p := PrefixRetention{
+		Rules: []PrefixRule{
+			{Prefix: "user:", Policy: CountRetention{Versions: 2}},
+			{Prefix: "user:hot:", Policy: CountRetention{Versions: 8}},
+		},
+		Default: CountRetention{Versions: 4},
+	}
+	assert.Equal(t, 2, p.MaxVersions("user:1"))
+	assert.Equal(t, 8, p.MaxVersions("user:hot:1"))
+	assert.Equal(t, 4, p.MaxVersions("order:1"))
+	assert.True(t, p.Keep(RetainedVersion{Key: "user:hot:1", Depth: 8}))
+	assert.False(t, p.Keep(RetainedVersion{Key: "user:1", Depth: 3}))
+
+	assert.Equal(t, 1, PrefixRetention{}.MaxVersions("order:1"))
+}
+
+func TestRetentionPolicyOr(t *testing.T) {
+	assert.Equal(t, CountRetention{Versions: 2}, RetentionPolicyOr(2))
+
+	SetRetentionPolicy(HorizonRetention{Horizon: 10})
+	defer SetRetentionPolicy(nil)
+	assert.Equal(t, HorizonRetention{Horizon: 10}, RetentionPolicyOr(2))
+}
+
+func TestReadRestartEndsItsSnapshot(t *testing.T) {
+	config.Config.MaxClockUncertainty = time.Millisecond
+	defer func() { config.Config.MaxClockUncertainty = 0 }()
+
+	tx := NewTransaction()
+	start, err := tx.getTime("start")
+	assert.NoError(t, err)
+	tx.TxnStartTime = start
+	tx.beginSnapshot()
+	assert.NoError(t, tx.restartRead())
+	assert.Greater(t, tx.TxnStartTime, start)
+
+	activeSnapshots.Lock()
+	assert.NotContains(t, activeSnapshots.starts, start)
+	assert.Equal(t, 1, activeSnapshots.starts[tx.TxnStartTime])
+	activeSnapshots.Unlock()
+
+	tx.endSnapshot()
+	activeSnapshots.Lock()
+	defer activeSnapshots.Unlock()
+	assert.NotContains(t, activeSnapshots.starts, tx.TxnStartTime)
+}
@@ -0,0 +1,76 @@
+package txn_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/serializer"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionRetentionPolicy(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	txn.SetRetentionPolicy(txn.PrefixRetention{
+		Rules:   []txn.PrefixRule{{Prefix: "hot:", Policy: txn.CountRetention{Versions: 4}}},
+		Default: txn.CountRetention{Versions: 1},
+	})
+	defer txn.SetRetentionPolicy(nil)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	for i := 0; i < 6; i++ {
+		tx := newTestTransaction(conns)
+		assert.NoError(t, tx.Start())
+		assert.NotZero(t, txn.OldestActiveSnapshot())
+		assert.NoError(t, tx.Write("mem", "hot:k", fmt.Sprintf("v%d", i)))
+		assert.NoError(t, tx.Write("mem", "cold:k", fmt.Sprintf("v%d", i)))
+		assert.NoError(t, tx.Commit())
+	}
+
+	for key, want := range map[string]int{"hot:k": 4, "cold:k": 1} {
+		item, err := conns["mem"].GetItem(key)
+		assert.NoError(t, err)
+		assert.Equal(t, want, item.LinkedLen(), key)
+	}
+}
+
+func TestTruncateHistoryByHorizon(t *testing.T) {
+	se := serializer.NewJSON2Serializer()
+	getPrev := func(item txn.DataItem) (txn.DataItem, error) {
+		var prev bolt.BoltItem
+		err := se.Deserialize([]byte(item.Prev()), &prev)
+		return &prev, err
+	}
+	// the versions committed at 10, 20, 30 and 40
+	var item txn.DataItem
+	for i := 1; i <= 4; i++ {
+		next := bolt.NewBoltItem(txn.ItemOptions{Key: "k", TValid: int64(i * 10), LinkedLen: 1})
+		if item != nil {
+			bs, _ := se.Serialize(item)
+			next.SetPrev(string(bs))
+			next.SetLinkedLen(item.LinkedLen() + 1)
+		}
+		item = next
+	}
+
+	// a snapshot started at 25 still reads the version of 20
+	policy := txn.HorizonRetention{Horizon: 5}
+	truncated, err := txn.TruncateHistory(policy, item, 50, 30, getPrev, se)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, truncated.LinkedLen())
+	prev, err := getPrev(truncated)
+	assert.NoError(t, err)
+	prev, err = getPrev(prev)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), prev.TValid())
+	assert.Equal(t, "", prev.Prev())
+
+	// no snapshot reads the current version any more
+	truncated, err = txn.TruncateHistory(policy, item, 50, 60, getPrev, se)
+	assert.NoError(t, err)
+	assert.Nil(t, truncated)
+}
@@ -0,0 +1,161 @@
+package txn
+
+import (
+	"maps"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+)
+
+// savepoint is a named snapshot of the state buffered by a transaction.
+type savepoint struct {
+	name       string
+	isReadOnly bool
+	writeCount int
+	states     map[string]*datastoreState
+}
+
+// datastoreState is a copy of the state buffered in a Datastore.
+type datastoreState struct {
+	readCache     map[string]DataItem
+	writeCache    map[string]DataItem
+	invisibleSet  map[string]bool
+	validationSet map[string]PredicateInfo
+	absentSet     map[string]bool
+	writeConds    map[string]WriteCondition
+	merges        map[string][]MergeDelta
+}
+
+// Savepoint marks the current state of the transaction with name,
+// so it can be restored by RollbackTo.
+// If name is already used, the new savepoint hides the older one
+// until it is released.
+func (t *Transaction) Savepoint(name string) error {
+	err := t.CheckState(config.STARTED)
+	if err != nil {
+		return err
+	}
+	sp := savepoint{
+		name:       name,
+		isReadOnly: t.isReadOnly,
+		writeCount: t.writeCount,
+		states:     make(map[string]*datastoreState, len(t.dataStoreMap)),
+	}
+	for dsName, ds := range t.dataStoreMap {
+		d, ok := ds.(*Datastore)
+		if !ok {
+			return errors.New("savepoints are not supported by datastore: " + dsName)
+		}
+		sp.states[dsName] = d.saveState()
+	}
+	t.savepoints = append(t.savepoints, sp)
+	return nil
+}
+
+// RollbackTo discards the reads and writes performed after the savepoint name.
+// The savepoint is kept, while the savepoints created after it are released.
+func (t *Transaction) RollbackTo(name string) error {
+	err := t.CheckState(config.STARTED)
+	if err != nil {
+		return err
+	}
+	i, err := t.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+	sp := t.savepoints[i]
+	for dsName, state := range sp.states {
+		t.dataStoreMap[dsName].(*Datastore).restoreState(state)
+	}
+	t.isReadOnly = sp.isReadOnly
+	t.writeCount = sp.writeCount
+	t.savepoints = t.savepoints[:i+1]
+	return nil
+}
+
+// Release removes the savepoint name and the savepoints created after it.
+// The reads and writes performed after the savepoint are kept.
+func (t *Transaction) Release(name string) error {
+	err := t.CheckState(config.STARTED)
+	if err != nil {
+		return err
+	}
+	i, err := t.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+	t.savepoints = t.savepoints[:i]
+	return nil
+}
+
+// findSavepoint returns the index of the latest savepoint with name.
+func (t *Transaction) findSavepoint(name string) (int, error) {
+	for i := len(t.savepoints) - 1; i >= 0; i-- {
+		if t.savepoints[i].name == name {
+			return i, nil
+		}
+	}
+	return -1, errors.New("savepoint not found: " + name)
+}
+
+// saveState returns a copy of the state buffered in the Datastore.
+func (r *Datastore) saveState() *datastoreState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &datastoreState{
+		readCache:     r.copyItems(r.readCache),
+		writeCache:    r.copyItems(r.writeCache),
+		invisibleSet:  maps.Clone(r.invisibleSet),
+		validationSet: maps.Clone(r.validationSet),
+		absentSet:     maps.Clone(r.absentSet),
+		writeConds:    maps.Clone(r.writeConds),
+		merges:        copyMerges(r.merges),
+	}
+}
+
+// restoreState restores the state saved by saveState.
+// The state is copied again, so it can be restored more than once.
+func (r *Datastore) restoreState(state *datastoreState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readCache = r.copyItems(state.readCache)
+	r.writeCache = r.copyItems(state.writeCache)
+	r.invisibleSet = maps.Clone(state.invisibleSet)
+	r.validationSet = maps.Clone(state.validationSet)
+	r.absentSet = maps.Clone(state.absentSet)
+	r.writeConds = maps.Clone(state.writeConds)
+	r.merges = copyMerges(state.merges)
+}
+
+// copyItems deep copies the items, since cached items are modified in place.
+// The expiry of the items is kept, as it is not part of ItemOptions.
+func (r *Datastore) copyItems(items map[string]DataItem) map[string]DataItem {
+	res := make(map[string]DataItem, len(items))
+	for key, item := range items {
+		copied := r.itemFactory.NewDataItem(ItemOptions{
+			Key:          item.Key(),
+			Value:        item.Value(),
+			GroupKeyList: item.GroupKeyList(),
+			TxnState:     item.TxnState(),
+			TValid:       item.TValid(),
+			TLease:       item.TLease(),
+			Prev:         item.Prev(),
+			LinkedLen:    item.LinkedLen(),
+			IsDeleted:    item.IsDeleted(),
+			Version:      item.Version(),
+		})
+		if it, ok := copied.(ExpiringItem); ok {
+			it.SetTExpire(expiryOf(item))
+		}
+		res[key] = copied
+	}
+	return res
+}
+
+func copyMerges(merges map[string][]MergeDelta) map[string][]MergeDelta {
+	res := make(map[string][]MergeDelta, len(merges))
+	for key, deltas := range merges {
+		res[key] = append([]MergeDelta(nil), deltas...)
+	}
+	return res
+}
@@ -0,0 +1,41 @@
+package txn_test
+
+import (
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavepointRollbackTo(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	tx := newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Write("mem", "key1", "v1"))
+	assert.NoError(t, tx.Savepoint("sp"))
+	assert.NoError(t, tx.Write("mem", "key1", "v2"))
+	assert.NoError(t, tx.Write("mem", "key2", "v2"))
+
+	assert.NoError(t, tx.RollbackTo("sp"))
+	var value string
+	assert.NoError(t, tx.Read("mem", "key1", &value))
+	assert.Equal(t, "v1", value)
+	assert.Error(t, tx.Read("mem", "key2", &value))
+
+	// the savepoint is kept after RollbackTo and removed by Release
+	assert.NoError(t, tx.Write("mem", "key1", "v3"))
+	assert.NoError(t, tx.RollbackTo("sp"))
+	assert.NoError(t, tx.Release("sp"))
+	assert.Error(t, tx.RollbackTo("sp"))
+	assert.NoError(t, tx.Commit())
+
+	postTxn := newTestTransaction(conns)
+	assert.NoError(t, postTxn.Start())
+	assert.NoError(t, postTxn.Read("mem", "key1", &value))
+	assert.Equal(t, "v1", value)
+	assert.Error(t, postTxn.Read("mem", "key2", &value))
+	assert.NoError(t, postTxn.Commit())
+}
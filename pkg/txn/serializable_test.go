@@ -0,0 +1,100 @@
+package txn_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// barrierConnection holds the prepared writes until all the transactions
+// sharing the barrier have reached their prepare phase.
+type barrierConnection struct {
+	*mock.MockMemoryConnection
+	barrier *sync.WaitGroup
+}
+
+func (c *barrierConnection) ConditionalUpdate(key string, value txn.DataItem, doCreate bool) (string, error) {
+	if value.TxnState() == config.PREPARED {
+		c.barrier.Done()
+		c.barrier.Wait()
+	}
+	return c.MockMemoryConnection.ConditionalUpdate(key, value, doCreate)
+}
+
+func TestSerializableRejectsWriteSkewAcrossDatastores(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	run := func(level config.IsolationLevel) (error, error) {
+		config.Config.IsolationLevel = level
+		defer func() { config.Config.IsolationLevel = config.SnapshotIsolation }()
+
+		conns := map[string]*mock.MockMemoryConnection{
+			"mem1": mock.NewMockMemoryConnection(),
+			"mem2": mock.NewMockMemoryConnection(),
+		}
+		init := newTestTransaction(conns)
+		assert.NoError(t, init.Start())
+		assert.NoError(t, init.Write("mem1", "x", "x0"))
+		assert.NoError(t, init.Write("mem2", "y", "y0"))
+		assert.NoError(t, init.Commit())
+
+		var barrier sync.WaitGroup
+		barrier.Add(2)
+		newTxn := func() *txn.Transaction {
+			tx := txn.NewTransaction()
+			for name, conn := range conns {
+				tx.AddDatastore(bolt.NewBoltDatastore(name,
+					&barrierConnection{MockMemoryConnection: conn, barrier: &barrier}))
+			}
+			return tx
+		}
+
+		// each transaction reads the record the other one writes,
+		// in the other datastore
+		txn1 := newTxn()
+		txn2 := newTxn()
+		assert.NoError(t, txn1.Start())
+		assert.NoError(t, txn2.Start())
+		var value string
+		assert.NoError(t, txn1.Read("mem1", "x", &value))
+		assert.NoError(t, txn2.Read("mem2", "y", &value))
+		assert.NoError(t, txn1.Write("mem2", "y", "y1"))
+		assert.NoError(t, txn2.Write("mem1", "x", "x2"))
+
+		var err1, err2 error
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			err1 = txn1.Commit()
+		}()
+		go func() {
+			defer wg.Done()
+			err2 = txn2.Commit()
+		}()
+		wg.Wait()
+		return err1, err2
+	}
+
+	t.Run("snapshot isolation", func(t *testing.T) {
+		err1, err2 := run(config.SnapshotIsolation)
+		assert.NoError(t, err1)
+		assert.NoError(t, err2)
+	})
+
+	t.Run("serializable", func(t *testing.T) {
+		err1, err2 := run(config.Serializable)
+		assert.False(t, err1 == nil && err2 == nil, "both transactions committed")
+		for _, err := range []error{err1, err2} {
+			if err != nil {
+				assert.ErrorContains(t, err, txn.ReadConflict.Error())
+			}
+		}
+	})
+}
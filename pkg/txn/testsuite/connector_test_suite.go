@@ -519,6 +519,70 @@ func testConnectionConditionalCommit(t *testing.T, conn txn.Connector, h Helper)
 	assert.True(t, dbItem.Equal(item))
 }
 
+func testConnectionBatch(t *testing.T, conn txn.Connector, h Helper) {
+	batchConn, ok := conn.(txn.BatchConnector)
+	if !ok {
+		t.Skip("the connector does not support batches")
+	}
+	for _, key := range []string{"batch-create", "batch-update", "batch-stale", "batch-exists"} {
+		_ = conn.Delete(key)
+	}
+	newItem := func(key string, version string) txn.DataItem {
+		return h.MakeItem(txn.ItemOptions{
+			Key:          key,
+			Value:        testutil.NewTestItem(key),
+			GroupKeyList: "1",
+			TxnState:     config.PREPARED,
+			TValid:       -1,
+			TLease:       time.Now().Add(1 * time.Second),
+			Version:      version,
+		})
+	}
+	updateVer, err := conn.PutItem("batch-update", newItem("batch-update", ""))
+	assert.NoError(t, err)
+	existsVer, err := conn.PutItem("batch-exists", newItem("batch-exists", ""))
+	assert.NoError(t, err)
+
+	updated := newItem("batch-update", updateVer)
+	updated.SetValue(util.ToJSONString(testutil.NewTestItem("batch-update-new")))
+	results := batchConn.ConditionalUpdateBatch([]txn.UpdateOp{
+		{Key: "batch-create", Value: newItem("batch-create", ""), DoCreate: true},
+		{Key: "batch-update", Value: updated},
+		{Key: "batch-stale", Value: newItem("batch-stale", generateMismatchedVersion(updateVer))},
+		{Key: "batch-exists", Value: newItem("batch-exists", existsVer), DoCreate: true},
+	})
+	assert.Len(t, results, 4)
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.EqualError(t, results[2].Err, txn.VersionMismatch.Error())
+	assert.EqualError(t, results[3].Err, txn.VersionMismatch.Error())
+
+	item, err := conn.GetItem("batch-create")
+	assert.NoError(t, err)
+	assert.Equal(t, results[0].Version, item.Version())
+	item, err = conn.GetItem("batch-update")
+	assert.NoError(t, err)
+	assert.Equal(t, results[1].Version, item.Version())
+	assert.Equal(t, updated.Value(), item.Value())
+
+	results = batchConn.ConditionalCommitBatch([]txn.CommitInfo{
+		{Key: "batch-create", Version: results[0].Version},
+		{Key: "batch-update", Version: generateMismatchedVersion(results[1].Version)},
+	}, 100)
+	assert.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.EqualError(t, results[1].Err, txn.VersionMismatch.Error())
+
+	item, err = conn.GetItem("batch-create")
+	assert.NoError(t, err)
+	assert.Equal(t, results[0].Version, item.Version())
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+	assert.Equal(t, int64(100), item.TValid())
+	item, err = conn.GetItem("batch-update")
+	assert.NoError(t, err)
+	assert.Equal(t, config.PREPARED, item.TxnState())
+}
+
 // ---------------------------------------------------------------------------
 // unified entry – reuse a single connection and helper across all tests
 // ---------------------------------------------------------------------------
@@ -549,6 +613,7 @@ func TestConnectorSuite(t *testing.T, conn txn.Connector, h Helper) {
 		{"ConnectionDeleteTwice", testConnectionDeleteTwice},
 		{"ConnectionConditionalUpdateDoCreate", testConnectionConditionalUpdateDoCreate},
 		{"ConnectionConditionalCommit", testConnectionConditionalCommit},
+		{"ConnectionBatch", testConnectionBatch},
 	}
 
 	for _, tc := range tests {
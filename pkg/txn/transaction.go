@@ -3,8 +3,11 @@ package txn
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-errors/errors"
@@ -12,6 +15,7 @@ import (
 	"github.com/kkkzoz/oreo/pkg/config"
 	"github.com/kkkzoz/oreo/pkg/logger"
 	"github.com/kkkzoz/oreo/pkg/timesource"
+	"golang.org/x/sync/errgroup"
 )
 
 type SourceType string
@@ -29,6 +33,8 @@ var (
 	VersionMismatch  = errors.Errorf("version mismatch")
 	KeyExists        = errors.Errorf("key exists")
 	ReadFailed       = errors.Errorf("read failed due to unknown txn status")
+	UncertainRead    = errors.Errorf("read restart due to clock uncertainty")
+	ReadConflict     = errors.Errorf("read validation failed due to a newer version")
 )
 
 const (
@@ -64,12 +70,47 @@ type Transaction struct {
 	// writeCount is the number of write operations performed by the transaction.
 	writeCount int
 
+	// readCount is the number of read operations performed by the transaction.
+	readCount int
+
 	// client is the network client used by the transaction.
 	client RemoteClient
 
 	// isRemote indicates whether the transaction is remote.
 	isRemote bool
 
+	// savepoints is the stack of savepoints created by the transaction.
+	savepoints []savepoint
+
+	// outbox records the writes of the transaction before it commits, if set.
+	outbox Outbox
+
+	// leaseTime overrides config.Config.LeaseTime for the records
+	// prepared by the transaction if it is not zero.
+	leaseTime time.Duration
+
+	// tLease is the latest lease stamped on the records prepared by the
+	// transaction, in Unix nanoseconds. It is updated by the heartbeat.
+	tLease atomic.Int64
+
+	// committed is set once the transaction has passed its commit point.
+	committed bool
+
+	// namespace prefixes the keys of the records and the id of the transaction.
+	namespace string
+
+	// snapshotStart is the start time counted by OldestActiveSnapshot
+	// for the snapshot of the transaction, or zero if none is.
+	snapshotStart int64
+
+	// oldestSnapshot is the oldest active snapshot when the transaction
+	// started to commit, offered to the retention policy.
+	oldestSnapshot int64
+
+	// snapshotTime is the time of the snapshot on the lease clock,
+	// to which the expiries of the records are compared.
+	snapshotTime time.Time
+
 	*StateMachine
 
 	debugStart time.Time
@@ -161,6 +202,7 @@ func (t *Transaction) Start() error {
 			return errors.New("failed to get time")
 		}
 	}
+	t.snapshotTime = config.Config.LeaseClock.Now()
 
 	for _, ds := range t.dataStoreMap {
 		err := ds.Start()
@@ -180,6 +222,12 @@ func (t *Transaction) Start() error {
 		}
 	}
 
+	if err := t.startNamespace(); err != nil {
+		return err
+	}
+	if !config.Debug.NativeMode {
+		t.beginSnapshot()
+	}
 	return nil
 }
 
@@ -213,6 +261,11 @@ func (t *Transaction) SetGlobalDatastore(ds Datastorer) {
 
 // Read reads the value associated with the given key from the specified datastore.
 // It returns an error if the transaction is not in the STARTED state or if the datastore is not found.
+//
+// A version in the clock uncertainty window is only restarted transparently on
+// the first read of a read-only transaction. Otherwise, Read returns an error
+// for which IsUncertainRead is true, and the transaction has to be aborted and
+// run again as a new transaction.
 func (t *Transaction) Read(dsName string, key string, value any) error {
 	err := t.CheckState(config.STARTED)
 	if err != nil {
@@ -221,12 +274,49 @@ func (t *Transaction) Read(dsName string, key string, value any) error {
 
 	t.debug(testutil.DRead, "read in %v: [Key: %v]", dsName, key)
 	if ds, ok := t.dataStoreMap[dsName]; ok {
-		return ds.Read(key, value)
+		key := t.namespacedKey(key)
+		err := ds.Read(key, value)
+		// The first read of a transaction can be restarted transparently
+		// since nothing has been observed at the old start time yet.
+		if IsUncertainRead(err) && t.readCount == 0 && t.isReadOnly {
+			logger.Debugw("restarting read due to clock uncertainty", "txnId", t.TxnId, "key", key)
+			if rerr := t.restartRead(); rerr != nil {
+				return rerr
+			}
+			err = ds.Read(key, value)
+		}
+		t.readCount++
+		return err
 	}
 	return errors.New("datastore not found: " + dsName)
 }
 
+// ReadVersionOf returns the version of key observed by the reads of the
+// transaction in the datastore dsName. It returns false if the key has not
+// been read from the datastore, e.g. if the reads are served by the writes
+// of the transaction.
+func (t *Transaction) ReadVersionOf(dsName string, key string) (ReadVersion, bool) {
+	ds, ok := t.dataStoreMap[dsName].(*Datastore)
+	if !ok {
+		return ReadVersion{}, false
+	}
+	key = t.namespacedKey(key)
+	if item, ok := ds.readCache[key]; ok {
+		return ReadVersion{
+			GroupKeyList: item.GroupKeyList(),
+			IsAbsent:     item.IsDeleted() || ds.isExpired(item),
+		}, true
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if ds.absentSet[key] {
+		return ReadVersion{IsAbsent: true}, true
+	}
+	return ReadVersion{}, false
+}
+
 // Write writes the given key-value pair to the specified datastore in the transaction.
+// The entries of the indexes of the datastore are written along with it.
 // It returns an error if the transaction is not in the STARTED state or if the datastore is not found.
 func (t *Transaction) Write(dsName string, key string, value any) error {
 	err := t.CheckState(config.STARTED)
@@ -236,12 +326,86 @@ func (t *Transaction) Write(dsName string, key string, value any) error {
 	t.isReadOnly = false
 	t.writeCount++
 	if ds, ok := t.dataStoreMap[dsName]; ok {
-		return ds.Write(key, value)
+		if err := ds.Write(t.namespacedKey(key), value); err != nil {
+			return err
+		}
+		return t.updateIndexes(dsName, key, value, false)
+	}
+	return errors.New("datastore not found: " + dsName)
+}
+
+// Insert writes the given key-value pair to the specified datastore in the transaction.
+// The commit fails with KeyAlreadyExists if a committed value of the key exists.
+func (t *Transaction) Insert(dsName string, key string, value any) error {
+	err := t.writeWith(dsName, func(ds Datastorer) error {
+		return ds.Insert(t.namespacedKey(key), value)
+	})
+	if err != nil {
+		return err
+	}
+	return t.updateIndexes(dsName, key, value, false)
+}
+
+// Update writes the given key-value pair to the specified datastore in the transaction.
+// The commit fails with KeyNotExists if the key has no committed value.
+func (t *Transaction) Update(dsName string, key string, value any) error {
+	err := t.writeWith(dsName, func(ds Datastorer) error {
+		return ds.Update(t.namespacedKey(key), value)
+	})
+	if err != nil {
+		return err
+	}
+	return t.updateIndexes(dsName, key, value, false)
+}
+
+// CompareAndSet writes the given key-value pair to the specified datastore in the transaction.
+// The commit fails with UnexpectedValue if the committed value of the key is not expected.
+func (t *Transaction) CompareAndSet(dsName string, key string, expected any, value any) error {
+	err := t.writeWith(dsName, func(ds Datastorer) error {
+		return ds.CompareAndSet(t.namespacedKey(key), expected, value)
+	})
+	if err != nil {
+		return err
+	}
+	return t.updateIndexes(dsName, key, value, false)
+}
+
+// Increment adds delta to the int64 value of key in the specified datastore.
+// Unlike a read-modify-write, concurrent increments of the same key commute
+// instead of conflicting with each other.
+func (t *Transaction) Increment(dsName string, key string, delta int64) error {
+	return t.Merge(dsName, key, IncrementOperator, delta)
+}
+
+// Merge records a delta of the given merge operator for key in the specified datastore.
+// The delta is applied on top of the latest committed value of key during
+// Prepare, so concurrent merges of the same key do not conflict.
+func (t *Transaction) Merge(dsName string, key string, operator string, delta any) error {
+	// the merged values are unknown until Prepare, so they cannot be indexed
+	if len(indexesOf(dsName)) != 0 {
+		return errors.New("merges are not supported in the indexed datastore " + dsName)
+	}
+	return t.writeWith(dsName, func(ds Datastorer) error {
+		return ds.Merge(t.namespacedKey(key), operator, delta)
+	})
+}
+
+// writeWith performs a write operation on the specified datastore.
+func (t *Transaction) writeWith(dsName string, writeFunc func(Datastorer) error) error {
+	err := t.CheckState(config.STARTED)
+	if err != nil {
+		return err
+	}
+	t.isReadOnly = false
+	t.writeCount++
+	if ds, ok := t.dataStoreMap[dsName]; ok {
+		return writeFunc(ds)
 	}
 	return errors.New("datastore not found: " + dsName)
 }
 
-// Delete deletes a key from the specified datastore in the transaction.
+// Delete deletes a key from the specified datastore in the transaction,
+// removing it from the entries of the indexes of the datastore.
 // It returns an error if the transaction is not in the STARTED state or if the datastore is not found.
 func (t *Transaction) Delete(dsName string, key string) error {
 	err := t.CheckState(config.STARTED)
@@ -252,7 +416,10 @@ func (t *Transaction) Delete(dsName string, key string) error {
 	msgStr := fmt.Sprintf("delete in %v: [Key: %v]", dsName, key)
 	logger.Debugw(msgStr, "txnId", t.TxnId, "topic", testutil.DDelete)
 	if ds, ok := t.dataStoreMap[dsName]; ok {
-		return ds.Delete(key)
+		if err := ds.Delete(t.namespacedKey(key)); err != nil {
+			return err
+		}
+		return t.updateIndexes(dsName, key, nil, true)
 	}
 	return errors.New("datastore not found: " + dsName)
 }
@@ -264,6 +431,7 @@ func (t *Transaction) Delete(dsName string, key string) error {
 // Finally, it deletes the transaction state record.
 // Returns an error if any operation fails.
 func (t *Transaction) Commit() error {
+	defer t.endSnapshot()
 	defer func() {
 		logger.Debugw(
 			"txn.Commit() ends",
@@ -283,6 +451,16 @@ func (t *Transaction) Commit() error {
 		"Topic",
 		"CheckPoint",
 	)
+	if t.isReadOnly {
+		// a read-only transaction is serialized at commit time,
+		// so its reads have to be validated as well
+		if err := t.validateReadSets(); err != nil {
+			abortErr := t.Abort()
+			logger.CheckAndLogError("Abort failed", abortErr)
+			return errors.New("validation phase failed: " + err.Error())
+		}
+	}
+
 	err := t.SetState(config.COMMITTED)
 	if err != nil {
 		return err
@@ -292,10 +470,13 @@ func (t *Transaction) Commit() error {
 		logger.Infow("transaction is read-only, Commit() complete", "txnId", t.TxnId)
 		return nil
 	}
+	t.oldestSnapshot = OldestActiveSnapshot()
 
-	// generate groupKeyUrls
+	// generate groupKeyUrls, in the order of the datastore names
+	// so the single group key always lands on the same datastore
 	i := 0
-	for _, ds := range t.dataStoreMap {
+	for _, name := range slices.Sorted(maps.Keys(t.dataStoreMap)) {
+		ds := t.dataStoreMap[name]
 		if ds.GetWriteCacheSize() == 0 {
 			continue
 		}
@@ -314,6 +495,16 @@ func (t *Transaction) Commit() error {
 		return t.commitInNative()
 	}
 
+	// the writes are recorded before the commit point,
+	// so they can be published even if the coordinator crashes afterwards
+	if t.outbox != nil {
+		if err := t.appendToOutbox(); err != nil {
+			abortErr := t.Abort()
+			logger.CheckAndLogError("Abort failed", abortErr)
+			return errors.New("failed to append to the outbox: " + err.Error())
+		}
+	}
+
 	if config.Debug.CherryGarciaMode {
 		return t.commitInCherryGarcia()
 	} else {
@@ -333,6 +524,9 @@ func (t *Transaction) commitInNative() error {
 }
 
 func (t *Transaction) commitInCherryGarcia() error {
+	stopHeartbeat := t.startHeartbeat()
+	defer stopHeartbeat()
+
 	var err error
 	t.TxnCommitTime, err = t.getTime("commit")
 	if err != nil {
@@ -381,11 +575,19 @@ func (t *Transaction) commitInCherryGarcia() error {
 	for _, ds := range t.dataStoreMap {
 		prepareDatastoreFunc(ds)
 	}
+	t.extendLease(config.Config.LeaseClock.Now().Add(t.getLeaseTime()))
 
 	if !success {
+		stopHeartbeat()
 		err = t.Abort()
 		logger.CheckAndLogError("Abort failed", err)
-		return errors.New("prepare phase failed: " + cause.Error())
+		return errors.Errorf("prepare phase failed: %w", cause)
+	}
+	if err := t.validateReadSets(); err != nil {
+		stopHeartbeat()
+		abortErr := t.Abort()
+		logger.CheckAndLogError("Abort failed", abortErr)
+		return errors.New("validation phase failed: " + err.Error())
 	}
 
 	logger.Infow(
@@ -399,6 +601,8 @@ func (t *Transaction) commitInCherryGarcia() error {
 	)
 
 	successNum := t.CreateGroupKeyFromUrls(t.GroupKeyUrls, config.COMMITTED)
+	// the outcome is decided, so the leases no longer matter
+	stopHeartbeat()
 	if successNum != len(t.GroupKeyUrls) {
 		err = t.Abort()
 		logger.CheckAndLogError("Abort failed", err)
@@ -409,6 +613,7 @@ func (t *Transaction) commitInCherryGarcia() error {
 		)
 	}
 	logger.Debugw("GroupKey created", "Latency", time.Since(t.debugStart), "Topic", "CheckPoint")
+	t.committed = true
 
 	wg := sync.WaitGroup{}
 	for _, ds := range t.dataStoreMap {
@@ -427,6 +632,9 @@ func (t *Transaction) commitInCherryGarcia() error {
 }
 
 func (t *Transaction) commitInOreo() error {
+	stopHeartbeat := t.startHeartbeat()
+	defer stopHeartbeat()
+
 	tCommitMax := int64(0)
 	tCommitMin := int64(1 << 62)
 	success := true
@@ -482,13 +690,23 @@ func (t *Transaction) commitInOreo() error {
 		}(ds)
 	}
 	wg.Wait()
+	t.extendLease(config.Config.LeaseClock.Now().Add(t.getLeaseTime()))
 
 	if !success {
+		stopHeartbeat()
 		go func() {
 			err := t.Abort()
 			logger.CheckAndLogError("Abort failed", err)
 		}()
-		return errors.New("prepare phase failed: " + cause.Error())
+		return errors.Errorf("prepare phase failed: %w", cause)
+	}
+	if err := t.validateReadSets(); err != nil {
+		stopHeartbeat()
+		go func() {
+			err := t.Abort()
+			logger.CheckAndLogError("Abort failed", err)
+		}()
+		return errors.New("validation phase failed: " + err.Error())
 	}
 
 	logger.Infow(
@@ -518,13 +736,17 @@ func (t *Transaction) commitInOreo() error {
 		}
 	}
 
-	if config.Config.AblationLevel <= 3 {
+	// in serializable mode, the executors leave the group keys to the
+	// coordinator, which creates them once the reads are validated
+	if config.Config.AblationLevel <= 3 || config.Config.IsolationLevel == config.Serializable {
 		// Simulate the latency of the request
 		// I don't want to change the messy logic in the test
 		if config.Debug.DebugMode {
 			time.Sleep(config.GetMaxDebugLatency())
 		}
 		successNum := t.CreateGroupKeyFromUrls(t.GroupKeyUrls, config.COMMITTED)
+		// the outcome is decided, so the leases no longer matter
+		stopHeartbeat()
 		if successNum != len(t.GroupKeyUrls) {
 			err := t.Abort()
 			logger.CheckAndLogError("Abort failed", err)
@@ -534,6 +756,7 @@ func (t *Transaction) commitInOreo() error {
 				len(t.GroupKeyUrls),
 			)
 		}
+		t.committed = true
 		logger.Infow("Starting to call ds.Commit()", "txnId", t.TxnId)
 		wg := sync.WaitGroup{}
 		for _, ds := range t.dataStoreMap {
@@ -544,9 +767,13 @@ func (t *Transaction) commitInOreo() error {
 			}(ds)
 		}
 		wg.Wait()
+		t.commitWait()
 		return nil
 	}
 
+	// the group keys have been created along with the records
+	stopHeartbeat()
+	t.committed = true
 	go func() {
 		logger.Infow("Starting to call ds.Commit()", "txnId", t.TxnId)
 		wg := sync.WaitGroup{}
@@ -560,9 +787,85 @@ func (t *Transaction) commitInOreo() error {
 		wg.Wait()
 		// t.DeleteGroupKeyFromUrls(t.GroupKeyUrls)
 	}()
+	t.commitWait()
+	return nil
+}
+
+// validateReadSets validates the read sets of all the datastores in
+// serializable mode. It is called once every datastore has prepared its
+// writes and before any group key is created, so of two transactions
+// reading what the other one writes, in the same datastore or not,
+// at least one fails the validation.
+func (t *Transaction) validateReadSets() error {
+	if config.Config.IsolationLevel != config.Serializable {
+		return nil
+	}
+	var eg errgroup.Group
+	for _, ds := range t.dataStoreMap {
+		if d, ok := ds.(*Datastore); ok {
+			eg.Go(d.validateReadSet)
+		}
+	}
+	return eg.Wait()
+}
+
+// commitWait blocks until the local clock has passed TxnCommitTime by the
+// configured clock uncertainty, so a transaction that starts after Commit()
+// returns never gets a start time below TxnCommitTime by more than the bound.
+// The remaining window is covered by read-restart on the reader side.
+//
+// It is a no-op when timestamps come from the time oracle.
+func (t *Transaction) commitWait() {
+	uncertainty := config.Config.MaxClockUncertainty.Microseconds()
+	if uncertainty == 0 || t.TxnCommitTime == 0 {
+		return
+	}
+	wait := time.Duration(t.TxnCommitTime+uncertainty-time.Now().UnixMicro()) * time.Microsecond
+	if wait > 0 {
+		logger.Debugw("commit wait", "txnId", t.TxnId, "wait", wait, "Topic", "CheckPoint")
+		time.Sleep(wait)
+	}
+}
+
+// inUncertaintyWindow reports whether a version whose TValid is not below
+// TxnStartTime may still have committed before the transaction started,
+// given the configured clock uncertainty.
+func (t *Transaction) inUncertaintyWindow(tValid int64) bool {
+	uncertainty := config.Config.MaxClockUncertainty.Microseconds()
+	return uncertainty != 0 && tValid >= t.TxnStartTime && tValid < t.TxnStartTime+uncertainty
+}
+
+// restartRead moves TxnStartTime past the uncertainty window of the current
+// start time and drops the state gathered at the old one.
+// It is only safe before the transaction has observed any record.
+func (t *Transaction) restartRead() error {
+	deadline := t.TxnStartTime + config.Config.MaxClockUncertainty.Microseconds()
+	if wait := time.Duration(deadline-time.Now().UnixMicro()) * time.Microsecond; wait > 0 {
+		time.Sleep(wait)
+	}
+	startTime, err := t.getTime("start")
+	if err != nil {
+		return err
+	}
+	t.TxnStartTime = startTime
+	for _, ds := range t.dataStoreMap {
+		if d, ok := ds.(*Datastore); ok {
+			d.clear()
+		}
+	}
+	if t.snapshotStart != 0 {
+		t.endSnapshot()
+		t.beginSnapshot()
+	}
 	return nil
 }
 
+// IsUncertainRead reports whether err is caused by a version in the clock
+// uncertainty window, either locally or on a remote executor.
+func IsUncertainRead(err error) bool {
+	return errors.Is(err, UncertainRead)
+}
+
 func (t *Transaction) OnePhaseCommit() error {
 	for _, ds := range t.dataStoreMap {
 		err := ds.OnePhaseCommit()
@@ -596,6 +899,7 @@ func (t *Transaction) Abort() error {
 	if err != nil {
 		return err
 	}
+	t.endSnapshot()
 
 	hasCommitted := lastState == config.COMMITTED
 	logger.Infow("aborting transaction", "txnId", t.TxnId, "hasCommitted", hasCommitted)
@@ -721,6 +1025,7 @@ func (t *Transaction) RemoteRead(
 			MaxRecordLen:                config.Config.MaxRecordLength,
 			ReadStrategy:                config.Config.ReadStrategy,
 			ConcurrentOptimizationLevel: config.Config.ConcurrentOptimizationLevel,
+			ClockUncertainty:            config.Config.MaxClockUncertainty.Microseconds(),
 		})
 		resultChan <- result{data, strategy, groupKey, err}
 	}()
@@ -738,6 +1043,9 @@ func (t *Transaction) RemotePrepare(
 	dsName string,
 	itemList []DataItem,
 	validationMap map[string]PredicateInfo,
+	readSet map[string]ReadVersion,
+	conds map[string]WriteCondition,
+	merges map[string][]MergeDelta,
 ) (map[string]string, int64, error) {
 	if !t.isRemote {
 		return nil, 0, errors.New("not a remote transaction")
@@ -754,9 +1062,13 @@ func (t *Transaction) RemotePrepare(
 		ReadStrategy:                config.Config.ReadStrategy,
 		ConcurrentOptimizationLevel: config.Config.ConcurrentOptimizationLevel,
 		AblationLevel:               config.Config.AblationLevel,
+		ClockUncertainty:            config.Config.MaxClockUncertainty.Microseconds(),
+		IsolationLevel:              config.Config.IsolationLevel,
+		LeaseTime:                   t.leaseTime,
+		OldestSnapshot:              t.oldestSnapshot,
 	}
 	return t.client.Prepare(dsName, itemList, t.TxnStartTime,
-		cfg, validationMap)
+		cfg, validationMap, readSet, conds, merges)
 }
 
 func (t *Transaction) RemoteCommit(dsName string, infoList []CommitInfo) error {
@@ -767,6 +1079,17 @@ func (t *Transaction) RemoteCommit(dsName string, infoList []CommitInfo) error {
 	return t.client.Commit(dsName, infoList, t.TxnCommitTime)
 }
 
+func (t *Transaction) RemoteRenew(
+	dsName string,
+	infoList []CommitInfo,
+	tLease time.Time,
+) (map[string]string, error) {
+	if !t.isRemote {
+		return nil, errors.New("not a remote transaction")
+	}
+	return t.client.Renew(dsName, infoList, tLease)
+}
+
 func (t *Transaction) RemoteAbort(dsName string, keyList []string) error {
 	if !t.isRemote {
 		return errors.New("not a remote transaction")
@@ -0,0 +1,78 @@
+package txn_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestTransaction returns a local transaction over the in-memory
+// datastores conns, named by their keys.
+func newTestTransaction(conns map[string]*mock.MockMemoryConnection) *txn.Transaction {
+	tx := txn.NewTransaction()
+	for name, conn := range conns {
+		tx.AddDatastore(bolt.NewBoltDatastore(name, conn))
+	}
+	return tx
+}
+
+func TestIsUncertainRead(t *testing.T) {
+	assert.True(t, txn.IsUncertainRead(txn.UncertainRead))
+	// as returned by a remote read
+	assert.True(t, txn.IsUncertainRead(errors.Join(errors.New("Remote read failed"), txn.UncertainRead)))
+	assert.False(t, txn.IsUncertainRead(txn.KeyNotFound))
+	assert.False(t, txn.IsUncertainRead(nil))
+}
+
+func TestReadInClockUncertaintyWindow(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+	config.Config.MaxClockUncertainty = 50 * time.Millisecond
+	defer func() { config.Config.MaxClockUncertainty = 0 }()
+
+	putCommitted := func(conn *mock.MockMemoryConnection, key string, tValid int64) {
+		_, err := conn.PutItem(key, bolt.NewBoltItem(txn.ItemOptions{
+			Key:          key,
+			Value:        `"` + key + `"`,
+			GroupKeyList: "mem:writer",
+			TxnState:     config.COMMITTED,
+			TValid:       tValid,
+			LinkedLen:    1,
+			Version:      "1",
+		}))
+		assert.NoError(t, err)
+	}
+
+	t.Run("first read restarts", func(t *testing.T) {
+		conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+		tValid := time.Now().Add(20 * time.Millisecond).UnixMicro()
+		putCommitted(conns["mem"], "item1", tValid)
+
+		tx := newTestTransaction(conns)
+		assert.NoError(t, tx.Start())
+		var value string
+		assert.NoError(t, tx.Read("mem", "item1", &value))
+		assert.Equal(t, "item1", value)
+		assert.Greater(t, tx.TxnStartTime, tValid)
+	})
+
+	t.Run("later read fails", func(t *testing.T) {
+		conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+		putCommitted(conns["mem"], "item1", time.Now().Add(-10*time.Second).UnixMicro())
+
+		tx := newTestTransaction(conns)
+		assert.NoError(t, tx.Start())
+		var value string
+		assert.NoError(t, tx.Read("mem", "item1", &value))
+
+		putCommitted(conns["mem"], "item2", tx.TxnStartTime+10)
+		err := tx.Read("mem", "item2", &value)
+		assert.True(t, txn.IsUncertainRead(err))
+	})
+}
@@ -0,0 +1,158 @@
+package txn
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+)
+
+// ExpiringItem is implemented by the DataItems that carry an expiry,
+// which is required by Transaction.WriteWithTTL.
+type ExpiringItem interface {
+	// TExpire returns when the version expires, or the zero time if it never does.
+	TExpire() time.Time
+	SetTExpire(time.Time)
+}
+
+// Expirer is implemented by the connectors that can expire records natively.
+//
+// The native expiry is set once the record is committed, and it must be
+// cleared by the next ConditionalUpdate of the record, so the records
+// prepared over an expiring one do not expire with it.
+type Expirer interface {
+	// ExpireAt makes the record key expire at expireAt,
+	// unless it is no longer at version.
+	ExpireAt(key string, version string, expireAt time.Time) error
+}
+
+// KeyScanner is implemented by the connectors that can list
+// the keys of their records, so CollectExpired can go through them.
+type KeyScanner interface {
+	// ScanKeys calls fn with the key of every record, stopping at the first error.
+	// The keys that are not records, such as the group keys, are not listed,
+	// and fn is never called concurrently.
+	ScanKeys(fn func(key string) error) error
+}
+
+// expiryOf returns the expiry of item, or the zero time if it has none.
+func expiryOf(item DataItem) time.Time {
+	if it, ok := item.(ExpiringItem); ok {
+		return it.TExpire()
+	}
+	return time.Time{}
+}
+
+// isExpired reports whether item has expired at the given time.
+func isExpired(item DataItem, at time.Time) bool {
+	expiry := expiryOf(item)
+	return !expiry.IsZero() && !expiry.After(at)
+}
+
+// isLive reports whether item is a record that exists at the given time,
+// neither deleted nor expired. A nil item means the record is not found.
+func isLive(item DataItem, at time.Time) bool {
+	return item != nil && !item.Empty() && !item.IsDeleted() && !isExpired(item, at)
+}
+
+// WriteWithTTL writes the given key-value pair like Write, but the record
+// expires ttl after the write: the snapshots started after its expiry do not
+// find it, whatever the time they read it, and the older ones still do.
+//
+// Once committed, the record is deleted natively by the datastores that are
+// Expirers, after config.Config.ExpiryGracePeriod covering the snapshots
+// started before the expiry. The others are cleaned up by CollectExpired.
+// The items of the datastore have to be ExpiringItems.
+func (t *Transaction) WriteWithTTL(dsName string, key string, value any, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.Errorf("invalid ttl %v", ttl)
+	}
+	ds, ok := t.dataStoreMap[dsName]
+	if !ok {
+		return errors.New("datastore not found: " + dsName)
+	}
+	d, ok := ds.(*Datastore)
+	if !ok || !d.supportsExpiry() {
+		return errors.New("TTL is not supported by datastore: " + dsName)
+	}
+	if err := t.Write(dsName, key, value); err != nil {
+		return err
+	}
+	d.writeCache[t.namespacedKey(key)].(ExpiringItem).SetTExpire(
+		config.Config.LeaseClock.Now().Add(ttl))
+	return nil
+}
+
+// supportsExpiry reports whether the items of the datastore carry an expiry.
+func (r *Datastore) supportsExpiry() bool {
+	_, ok := r.itemFactory.NewDataItem(ItemOptions{Value: ""}).(ExpiringItem)
+	return ok
+}
+
+// isExpired reports whether item has expired as of the snapshot of the transaction.
+func (r *Datastore) isExpired(item DataItem) bool {
+	return isExpired(item, r.Txn.snapshotTime)
+}
+
+// ExpireNatively makes the committed record key, at version, expire natively
+// if its expiry is set and conn is an Expirer. The grace period is added
+// to the expiry, so the snapshots started before the expiry still read it.
+func ExpireNatively(conn Connector, key string, version string, expiry time.Time) error {
+	expirer, ok := conn.(Expirer)
+	if !ok || expiry.IsZero() {
+		return nil
+	}
+	err := expirer.ExpireAt(key, version, expiry.Add(config.Config.ExpiryGracePeriod))
+	if err != nil && errors.Is(err, VersionMismatch) {
+		// the record has been updated in the meantime
+		return nil
+	}
+	return err
+}
+
+// CollectExpired replaces the committed records of conn that have expired
+// before now, minus config.Config.ExpiryGracePeriod, with tombstones
+// without history, and returns how many of them it collected.
+//
+// A record is only collected if it has not been updated in the meantime,
+// so it is safe to run CollectExpired while transactions are running.
+func CollectExpired(conn Connector, now time.Time) (int, error) {
+	scanner, ok := conn.(KeyScanner)
+	if !ok {
+		return 0, errors.New("the connector cannot list its keys")
+	}
+	before := now.Add(-config.Config.ExpiryGracePeriod)
+	collected := 0
+	err := scanner.ScanKeys(func(key string) error {
+		item, err := conn.GetItem(key)
+		if err != nil {
+			// the record has been deleted in the meantime
+			if errors.Is(err, KeyNotFound) {
+				return nil
+			}
+			return err
+		}
+		if item == nil || item.Empty() {
+			return nil
+		}
+		if item.TxnState() != config.COMMITTED || item.IsDeleted() || !isExpired(item, before) {
+			return nil
+		}
+		item.SetValue("")
+		item.SetIsDeleted(true)
+		item.SetPrev("")
+		item.SetLinkedLen(1)
+		item.(ExpiringItem).SetTExpire(time.Time{})
+		_, err = conn.ConditionalUpdate(key, item, false)
+		if err != nil {
+			if strings.Contains(err.Error(), VersionMismatch.Error()) {
+				return nil
+			}
+			return err
+		}
+		collected++
+		return nil
+	})
+	return collected, err
+}
@@ -0,0 +1,129 @@
+package txn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/config"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionWriteWithTTL(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	tx := newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.Error(t, tx.WriteWithTTL("mem", "session", "s1", 0))
+	assert.NoError(t, tx.WriteWithTTL("mem", "session", "s1", 200*time.Millisecond))
+	assert.NoError(t, tx.WriteWithTTL("mem", "renewed", "r1", 200*time.Millisecond))
+	assert.NoError(t, tx.Write("mem", "renewed", "r2"))
+	assert.NoError(t, tx.Commit())
+
+	// a snapshot started before the expiry still reads the record
+	before := newTestTransaction(conns)
+	assert.NoError(t, before.Start())
+	var value string
+	assert.NoError(t, before.Read("mem", "session", &value))
+	assert.Equal(t, "s1", value)
+
+	time.Sleep(300 * time.Millisecond)
+	assert.NoError(t, before.Read("mem", "session", &value))
+	assert.NoError(t, before.Commit())
+
+	after := newTestTransaction(conns)
+	assert.NoError(t, after.Start())
+	err := after.Read("mem", "session", &value)
+	assert.ErrorContains(t, err, txn.KeyNotFound.Error())
+	assert.NoError(t, after.Read("mem", "renewed", &value))
+	assert.Equal(t, "r2", value)
+	assert.NoError(t, after.Commit())
+
+	// the expired records are collected once the grace period is over
+	grace := config.Config.ExpiryGracePeriod
+	config.Config.ExpiryGracePeriod = 0
+	defer func() { config.Config.ExpiryGracePeriod = grace }()
+	collected, err := txn.CollectExpired(conns["mem"], time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, collected)
+	item, err := conns["mem"].GetItem("session")
+	assert.NoError(t, err)
+	assert.True(t, item.IsDeleted())
+	assert.Equal(t, 1, item.LinkedLen())
+
+	// a new write of the key does not expire
+	tx = newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Write("mem", "session", "s2"))
+	assert.NoError(t, tx.Commit())
+	tx = newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Read("mem", "session", &value))
+	assert.Equal(t, "s2", value)
+	assert.NoError(t, tx.Commit())
+}
+
+func TestWriteConditionsOnExpiredRecord(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	tx := newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.WriteWithTTL("mem", "session", "s1", 100*time.Millisecond))
+	assert.NoError(t, tx.Commit())
+
+	// the record is read before it expires, and is replaced after
+	before := newTestTransaction(conns)
+	assert.NoError(t, before.Start())
+	var value string
+	assert.NoError(t, before.Read("mem", "session", &value))
+	assert.NoError(t, before.Update("mem", "session", "s2"))
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, txn.IsConditionFailed(before.Commit()))
+
+	// the expired record is absent to the conditions
+	tx = newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.CompareAndSet("mem", "session", "s1", "s3"))
+	assert.True(t, txn.IsConditionFailed(tx.Commit()))
+	tx = newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Insert("mem", "session", "s4"))
+	assert.NoError(t, tx.Commit())
+
+	tx = newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Read("mem", "session", &value))
+	assert.Equal(t, "s4", value)
+	assert.NoError(t, tx.Commit())
+}
+
+func TestWriteWithTTLAcrossSavepoint(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conns := map[string]*mock.MockMemoryConnection{"mem": mock.NewMockMemoryConnection()}
+	tx := newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.WriteWithTTL("mem", "session", "s1", 100*time.Millisecond))
+	assert.NoError(t, tx.Savepoint("sp"))
+	assert.NoError(t, tx.Write("mem", "other", "o1"))
+	assert.NoError(t, tx.RollbackTo("sp"))
+	assert.NoError(t, tx.Commit())
+	item, err := conns["mem"].GetItem("session")
+	assert.NoError(t, err)
+	assert.False(t, item.(txn.ExpiringItem).TExpire().IsZero())
+
+	// the expired record in the read cache stays absent
+	time.Sleep(150 * time.Millisecond)
+	tx = newTestTransaction(conns)
+	assert.NoError(t, tx.Start())
+	var value string
+	assert.ErrorContains(t, tx.Read("mem", "session", &value), txn.KeyNotFound.Error())
+	assert.NoError(t, tx.Savepoint("sp"))
+	assert.NoError(t, tx.RollbackTo("sp"))
+	assert.ErrorContains(t, tx.Read("mem", "session", &value), txn.KeyNotFound.Error())
+	assert.NoError(t, tx.Commit())
+}
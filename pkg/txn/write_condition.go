@@ -0,0 +1,76 @@
+package txn
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/kkkzoz/oreo/pkg/config"
+)
+
+var (
+	KeyAlreadyExists = errors.Errorf("insert failed because the key already exists")
+	KeyNotExists     = errors.Errorf("update failed because the key does not exist")
+	UnexpectedValue  = errors.Errorf("compare-and-set failed due to unexpected value")
+)
+
+// WriteConditionKind is the kind of condition attached to a write.
+type WriteConditionKind string
+
+const (
+	// ConditionInsert requires the record to be absent.
+	ConditionInsert WriteConditionKind = "insert"
+	// ConditionUpdate requires the record to exist.
+	ConditionUpdate WriteConditionKind = "update"
+	// ConditionCompareAndSet requires the record to hold the expected value.
+	ConditionCompareAndSet WriteConditionKind = "cas"
+)
+
+// WriteCondition is a condition on the committed record that a write
+// replaces. It is checked during Prepare.
+type WriteCondition struct {
+	Kind WriteConditionKind
+	// Expected is the serialized value expected by ConditionCompareAndSet.
+	Expected string
+}
+
+// Check checks the condition against item, the record the write replaces.
+// A nil item means the record is not found, and an expired one is absent
+// as well, as in ReadVersion.Matches.
+func (c WriteCondition) Check(key string, item DataItem) error {
+	exists := isLive(item, config.Config.LeaseClock.Now())
+	switch c.Kind {
+	case ConditionInsert:
+		if exists {
+			return errors.Errorf("%w, key: %s", KeyAlreadyExists, key)
+		}
+	case ConditionUpdate:
+		if !exists {
+			return errors.Errorf("%w, key: %s", KeyNotExists, key)
+		}
+	case ConditionCompareAndSet:
+		if !exists || item.Value() != c.Expected {
+			return errors.Errorf("%w, key: %s", UnexpectedValue, key)
+		}
+	default:
+		return errors.Errorf("unknown write condition: %v", c.Kind)
+	}
+	return nil
+}
+
+// Replaced maps err, the failure of the conditional update that prepares the
+// write to key, to the error of the condition. Only an insert fails for good,
+// since the record found absent has been created concurrently. An update or a
+// compare-and-set of a record replaced concurrently keeps the VersionMismatch,
+// as it may succeed once retried against the new version.
+func (c WriteCondition) Replaced(key string, err error) error {
+	if c.Kind != ConditionInsert || !errors.Is(err, KeyExists) && !errors.Is(err, VersionMismatch) {
+		return err
+	}
+	return errors.Errorf("%w, key: %s", KeyAlreadyExists, key)
+}
+
+// IsConditionFailed reports whether err is caused by a failed write condition,
+// either locally or on a remote executor.
+func IsConditionFailed(err error) bool {
+	return errors.Is(err, KeyAlreadyExists) ||
+		errors.Is(err, KeyNotExists) ||
+		errors.Is(err, UnexpectedValue)
+}
@@ -0,0 +1,69 @@
+package txn_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kkkzoz/oreo/internal/mock"
+	"github.com/kkkzoz/oreo/internal/testutil"
+	"github.com/kkkzoz/oreo/pkg/datastore/bolt"
+	"github.com/kkkzoz/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// commitConcurrently runs two transactions performing write on conn, which
+// both read the records before either prepares them, and returns the error
+// of the one that commits first and of the other one.
+func commitConcurrently(t *testing.T, conn *mock.MockMemoryConnection, write func(tx *txn.Transaction) error) (error, error) {
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx := txn.NewTransaction()
+			tx.AddDatastore(bolt.NewBoltDatastore("mem",
+				&barrierConnection{MockMemoryConnection: conn, barrier: &barrier}))
+			assert.NoError(t, tx.Start())
+			assert.NoError(t, write(tx))
+			errs[i] = tx.Commit()
+		}()
+	}
+	wg.Wait()
+	if errs[0] != nil {
+		return errs[1], errs[0]
+	}
+	return errs[0], errs[1]
+}
+
+func TestConcurrentInsertFailsCondition(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conn := mock.NewMockMemoryConnection()
+	first, second := commitConcurrently(t, conn, func(tx *txn.Transaction) error {
+		return tx.Insert("mem", "x", "v")
+	})
+	assert.NoError(t, first)
+	assert.True(t, txn.IsConditionFailed(second), "%v", second)
+	assert.ErrorIs(t, second, txn.KeyAlreadyExists)
+}
+
+func TestConcurrentUpdateIsRetryable(t *testing.T) {
+	testutil.UseCoordinatorGroupKeys(t)
+
+	conn := mock.NewMockMemoryConnection()
+	tx := newTestTransaction(map[string]*mock.MockMemoryConnection{"mem": conn})
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Write("mem", "x", "v0"))
+	assert.NoError(t, tx.Commit())
+
+	// the record still exists, so the condition holds on a retry
+	first, second := commitConcurrently(t, conn, func(tx *txn.Transaction) error {
+		return tx.Update("mem", "x", "v1")
+	})
+	assert.NoError(t, first)
+	assert.False(t, txn.IsConditionFailed(second), "%v", second)
+	assert.ErrorIs(t, second, txn.VersionMismatch)
+}
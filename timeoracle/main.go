@@ -38,6 +38,14 @@ func (t TimeOracleServer) handleTimestamp(w http.ResponseWriter, r *http.Request
 	logger.CheckAndLogError("Failed to write timestamp response", err)
 }
 
+// handleClock returns the wall clock of the oracle in microseconds,
+// which is followed by the timesource.OracleClock of the executors.
+func (t TimeOracleServer) handleClock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	_, err := fmt.Fprintf(w, "%d", time.Now().UnixMicro())
+	logger.CheckAndLogError("Failed to write clock response", err)
+}
+
 func main() {
 	flag.IntVar(&port, "p", 8010, "HTTP server port number")
 	flag.StringVar(&oracleType, "type", "hybrid", "Time Oracle Implementaion Type")
@@ -62,6 +70,7 @@ func main() {
 
 	// 设置 HTTP handler，使用 server.handleTimestamp
 	http.HandleFunc("/timestamp/", server.handleTimestamp)
+	http.HandleFunc("/clock", server.handleClock)
 
 	// 启动 HTTP server
 	serverAddress := fmt.Sprintf(":%d", server.port)